@@ -1,9 +1,11 @@
 package api
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"net/url"
+	"sync"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -13,6 +15,11 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/swaggo/gin-swagger/swaggerFiles"
 	_ "github.com/victornm/es-backend/docs"
+	"github.com/victornm/es-backend/pkg/event"
+	"github.com/victornm/es-backend/pkg/eventlog"
+	"github.com/victornm/es-backend/pkg/job"
+	"github.com/victornm/es-backend/pkg/stream"
+	"github.com/victornm/es-backend/pkg/upload"
 )
 
 // Server is an interface for HTTP Server
@@ -24,6 +31,7 @@ import (
 type Server interface {
 	Init()
 	ServeHTTP(w http.ResponseWriter, r *http.Request)
+	Close()
 
 	// Only http handler method will be extract to the interface
 	createSignInHandler() gin.HandlerFunc
@@ -33,6 +41,41 @@ type Server interface {
 	createAuthMiddleware() gin.HandlerFunc
 	createPingHandler() gin.HandlerFunc
 	createGetProfileHandler() gin.HandlerFunc
+	createGetNotificationsHandler() gin.HandlerFunc
+	createCountUnreadNotificationsHandler() gin.HandlerFunc
+	createMarkNotificationAsReadHandler() gin.HandlerFunc
+	createMarkAllNotificationsAsReadHandler() gin.HandlerFunc
+	createGetNotificationPreferencesHandler() gin.HandlerFunc
+	createUpdateNotificationPreferenceHandler() gin.HandlerFunc
+	createStreamAuthMiddleware() gin.HandlerFunc
+	createStreamHandler() gin.HandlerFunc
+	createCreateUploadHandler() gin.HandlerFunc
+	createWriteUploadChunkHandler() gin.HandlerFunc
+	createGetUploadHandler() gin.HandlerFunc
+	createDeleteUploadHandler() gin.HandlerFunc
+	createSignUploadURLHandler() gin.HandlerFunc
+	createDownloadFileHandler() gin.HandlerFunc
+	createReportHandler() gin.HandlerFunc
+	createGetModerationQueueHandler() gin.HandlerFunc
+	createAssignReportHandler() gin.HandlerFunc
+	createResolveReportHandler() gin.HandlerFunc
+	createGetModerationAuditHandler() gin.HandlerFunc
+	createGetPublicProfileHandler() gin.HandlerFunc
+	createFollowHandler() gin.HandlerFunc
+	createUnfollowHandler() gin.HandlerFunc
+	createGetFollowersHandler() gin.HandlerFunc
+	createGetFollowingHandler() gin.HandlerFunc
+	createCreateWebhookHandler() gin.HandlerFunc
+	createGetWebhooksHandler() gin.HandlerFunc
+	createUpdateWebhookHandler() gin.HandlerFunc
+	createDeleteWebhookHandler() gin.HandlerFunc
+	createGetWebhookDeliveriesHandler() gin.HandlerFunc
+	createSendTestWebhookHandler() gin.HandlerFunc
+	createAdminMiddleware() gin.HandlerFunc
+	createGetDeadLettersHandler() gin.HandlerFunc
+	createGetDeadLetterHandler() gin.HandlerFunc
+	createRequeueDeadLetterHandler() gin.HandlerFunc
+	createDiscardDeadLetterHandler() gin.HandlerFunc
 }
 
 // routeMap create single source of truth when testing API
@@ -65,12 +108,141 @@ func routeMap(s Server) map[string]map[string][]gin.HandlerFunc {
 		"/users/profile": {
 			http.MethodGet: []gin.HandlerFunc{s.createAuthMiddleware(), s.createGetProfileHandler()},
 		},
+
+		// notification handler
+		"/notifications": {
+			http.MethodGet: []gin.HandlerFunc{s.createAuthMiddleware(), s.createGetNotificationsHandler()},
+		},
+
+		"/notifications/unread-count": {
+			http.MethodGet: []gin.HandlerFunc{s.createAuthMiddleware(), s.createCountUnreadNotificationsHandler()},
+		},
+
+		"/notifications/:id/read": {
+			http.MethodPost: []gin.HandlerFunc{s.createAuthMiddleware(), s.createMarkNotificationAsReadHandler()},
+		},
+
+		"/notifications/read-all": {
+			http.MethodPost: []gin.HandlerFunc{s.createAuthMiddleware(), s.createMarkAllNotificationsAsReadHandler()},
+		},
+
+		"/notifications/preferences": {
+			http.MethodGet: []gin.HandlerFunc{s.createAuthMiddleware(), s.createGetNotificationPreferencesHandler()},
+			http.MethodPut: []gin.HandlerFunc{s.createAuthMiddleware(), s.createUpdateNotificationPreferenceHandler()},
+		},
+
+		// real-time stream handler
+		"/stream": {
+			http.MethodGet: []gin.HandlerFunc{s.createStreamAuthMiddleware(), s.createStreamHandler()},
+		},
+
+		// upload handler
+		"/uploads": {
+			http.MethodPost: []gin.HandlerFunc{s.createAuthMiddleware(), s.createCreateUploadHandler()},
+		},
+
+		"/uploads/:id": {
+			http.MethodGet:    []gin.HandlerFunc{s.createAuthMiddleware(), s.createGetUploadHandler()},
+			http.MethodPatch:  []gin.HandlerFunc{s.createAuthMiddleware(), s.createWriteUploadChunkHandler()},
+			http.MethodDelete: []gin.HandlerFunc{s.createAuthMiddleware(), s.createDeleteUploadHandler()},
+		},
+
+		"/uploads/:id/url": {
+			http.MethodGet: []gin.HandlerFunc{s.createAuthMiddleware(), s.createSignUploadURLHandler()},
+		},
+
+		// signed download, no authentication
+		"/files/:id": {
+			http.MethodGet: []gin.HandlerFunc{s.createDownloadFileHandler()},
+		},
+
+		// moderation handler
+		"/reports": {
+			http.MethodPost: []gin.HandlerFunc{s.createAuthMiddleware(), s.createReportHandler()},
+		},
+
+		"/moderation/reports": {
+			http.MethodGet: []gin.HandlerFunc{s.createAuthMiddleware(), s.createGetModerationQueueHandler()},
+		},
+
+		"/moderation/reports/:id/assign": {
+			http.MethodPost: []gin.HandlerFunc{s.createAuthMiddleware(), s.createAssignReportHandler()},
+		},
+
+		"/moderation/reports/:id/resolve": {
+			http.MethodPost: []gin.HandlerFunc{s.createAuthMiddleware(), s.createResolveReportHandler()},
+		},
+
+		"/moderation/audit": {
+			http.MethodGet: []gin.HandlerFunc{s.createAuthMiddleware(), s.createGetModerationAuditHandler()},
+		},
+
+		// follow handler
+		"/profiles/:id": {
+			http.MethodGet: []gin.HandlerFunc{s.createAuthMiddleware(), s.createGetPublicProfileHandler()},
+		},
+
+		"/profiles/:id/follow": {
+			http.MethodPost:   []gin.HandlerFunc{s.createAuthMiddleware(), s.createFollowHandler()},
+			http.MethodDelete: []gin.HandlerFunc{s.createAuthMiddleware(), s.createUnfollowHandler()},
+		},
+
+		"/profiles/:id/followers": {
+			http.MethodGet: []gin.HandlerFunc{s.createAuthMiddleware(), s.createGetFollowersHandler()},
+		},
+
+		"/profiles/:id/following": {
+			http.MethodGet: []gin.HandlerFunc{s.createAuthMiddleware(), s.createGetFollowingHandler()},
+		},
+
+		// webhook handler
+		"/webhooks": {
+			http.MethodPost: []gin.HandlerFunc{s.createAuthMiddleware(), s.createCreateWebhookHandler()},
+			http.MethodGet:  []gin.HandlerFunc{s.createAuthMiddleware(), s.createGetWebhooksHandler()},
+		},
+
+		"/webhooks/:id": {
+			http.MethodPut:    []gin.HandlerFunc{s.createAuthMiddleware(), s.createUpdateWebhookHandler()},
+			http.MethodDelete: []gin.HandlerFunc{s.createAuthMiddleware(), s.createDeleteWebhookHandler()},
+		},
+
+		"/webhooks/:id/deliveries": {
+			http.MethodGet: []gin.HandlerFunc{s.createAuthMiddleware(), s.createGetWebhookDeliveriesHandler()},
+		},
+
+		"/webhooks/:id/test": {
+			http.MethodPost: []gin.HandlerFunc{s.createAuthMiddleware(), s.createSendTestWebhookHandler()},
+		},
+
+		// dead letter handler, admins only
+		"/dead-letters": {
+			http.MethodGet: []gin.HandlerFunc{s.createAuthMiddleware(), s.createAdminMiddleware(), s.createGetDeadLettersHandler()},
+		},
+
+		"/dead-letters/:id": {
+			http.MethodGet:    []gin.HandlerFunc{s.createAuthMiddleware(), s.createAdminMiddleware(), s.createGetDeadLetterHandler()},
+			http.MethodDelete: []gin.HandlerFunc{s.createAuthMiddleware(), s.createAdminMiddleware(), s.createDiscardDeadLetterHandler()},
+		},
+
+		"/dead-letters/:id/requeue": {
+			http.MethodPost: []gin.HandlerFunc{s.createAuthMiddleware(), s.createAdminMiddleware(), s.createRequeueDeadLetterHandler()},
+		},
 	}
 }
 
 type realServer struct {
 	router *gin.Engine
 	db     *sqlx.DB
+	bus    *event.Bus
+	hub    *stream.Hub
+	blobs  upload.BlobStore
+
+	eventLog eventlog.Repository
+
+	jobs job.Service
+
+	// stopBackground stops the jobs and the delivery loops, and waits for them
+	stopBackground func()
 
 	config *ServerConfig
 }
@@ -81,6 +253,7 @@ func NewServer(config *ServerConfig) *realServer {
 
 // @title ES API
 // @version 1.0
+// @description Backend of E-Sharing
 
 // @contact.name VictorNM
 // @contact.url https://github.com/VictorNM/
@@ -93,15 +266,69 @@ func NewServer(config *ServerConfig) *realServer {
 // @name Authorization
 func (s *realServer) Init() {
 	s.connectDB()
+	s.bus = event.NewBus()
+	s.initDeadLetters()
+	s.initEventLog()
+	s.initStream()
+	s.initAuth()
+	s.initFollow()
+	s.initWebhooks()
+	s.initBlobStore()
+	s.initJobs()
 
-	s.router = gin.Default()
+	// gin.Default, with tokens of stream queries kept out of the access log
+	s.router = gin.New()
+	s.router.Use(gin.LoggerWithFormatter(accessLogFormatter), gin.Recovery())
 	s.initRouter()
+
+	s.startBackground()
+}
+
+// startBackground runs the jobs, if this replica is the leader, and the loops of every replica until stopBackground
+func (s *realServer) startBackground() {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+
+	run := func(f func(ctx context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(ctx)
+		}()
+	}
+
+	run(func(ctx context.Context) { s.jobs.Run(ctx, jobPollInterval) })
+	run(func(ctx context.Context) { s.runNotificationDigest(ctx, notificationDigestInterval) })
+	run(func(ctx context.Context) { s.runUploadGarbageCollector(ctx, uploadGCInterval) })
+	run(func(ctx context.Context) { s.runOutboxRelay(ctx, outboxRelayInterval, outboxCleanupInterval) })
+	run(func(ctx context.Context) { s.runWebhookDelivery(ctx, webhookDeliveryInterval) })
+	run(func(ctx context.Context) { s.runDeadLetterRedelivery(ctx, deadLetterRedeliveryInterval) })
+
+	s.stopBackground = func() {
+		cancel()
+		wg.Wait()
+	}
 }
 
 func (s *realServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
 }
 
+// CloseStreams ends long-running connections such as event streams,
+// it should be registered with http.Server.RegisterOnShutdown so Shutdown does not wait for them
+func (s *realServer) CloseStreams() {
+	s.hub.Close()
+}
+
+// Close stops the jobs and event deliveries.
+// It must be called after http.Server.Shutdown returns, so events published by in-flight requests are handled.
+// The background loops stop first, they need the handlers of the bus.
+func (s *realServer) Close() {
+	s.stopBackground()
+	s.bus.Close()
+	s.closeEventLog()
+}
+
 func (s *realServer) initRouter() {
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowHeaders = append(corsConfig.AllowHeaders, "Authorization")
@@ -141,6 +368,11 @@ type ServerConfig struct {
 	FrontendBaseURL string // the domain where the frontend live
 	APIBaseURL      string
 	SqlConnString   string
+	UploadDir       string // where the local blob store keeps uploaded files
+	UploadSecret    string // key for signing download URLs, kept apart from the JWT secret
+	EventLogPath    string // JSON Lines file for the event log, the database is used if empty
+	AsyncEmail      bool   // queue emails for the worker command instead of sending them from the server
+	AsyncJobs       bool   // queue due jobs for the worker command instead of running them on the leader
 
 	*AuthConfig
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"log"
 	"net/http"
 	"os"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/victornm/es-backend/pkg/auth"
 	"github.com/victornm/es-backend/pkg/auth/mock"
+	"github.com/victornm/es-backend/pkg/event"
 	"github.com/victornm/es-backend/pkg/store/memory"
 )
 
@@ -138,7 +140,7 @@ func (s *realServer) createOauth2SignInHandler() gin.HandlerFunc {
 }
 
 func (s *realServer) createAuthMiddleware() gin.HandlerFunc {
-	tokenParser := s.createJWTService()
+	authenticate := s.createAuthenticator()
 
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -147,7 +149,7 @@ func (s *realServer) createAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		userAuth, err := tokenParser.ParseToken(authHeader[7:])
+		userAuth, err := authenticate(authHeader[7:])
 		if err != nil {
 			abort(c, http.StatusUnauthorized, err)
 			return
@@ -157,6 +159,44 @@ func (s *realServer) createAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// createAuthenticator returns the user of a token.
+// Tokens outlive suspensions, so the user is checked to still be active on every request.
+func (s *realServer) createAuthenticator() func(token string) (*auth.UserAuthDTO, error) {
+	tokenParser := s.createJWTService()
+	finder := createUserFinder(s)
+
+	return func(token string) (*auth.UserAuthDTO, error) {
+		userAuth, err := tokenParser.ParseToken(token)
+		if err != nil {
+			return nil, err
+		}
+
+		u, err := finder.FindUserByID(userAuth.UserID)
+		if err != nil {
+			return nil, auth.ErrNotAuthenticated
+		}
+
+		if !u.IsActive {
+			return nil, auth.ErrNotActivated
+		}
+
+		return userAuth, nil
+	}
+}
+
+// createAdminMiddleware only lets super admins through, it must come after the auth middleware
+func (s *realServer) createAdminMiddleware() gin.HandlerFunc {
+	finder := createUserFinder(s)
+
+	return func(c *gin.Context) {
+		u, err := finder.FindUserByID(getUser(c).UserID)
+		if err != nil || !u.IsSuperAdmin {
+			abort(c, http.StatusForbidden, auth.ErrNotAdmin)
+			return
+		}
+	}
+}
+
 func (s *realServer) createJWTService() auth.JWTService {
 	return auth.NewJWTService(s.config.JWTSecret, s.config.JWTExpiredHours)
 }
@@ -164,9 +204,8 @@ func (s *realServer) createJWTService() auth.JWTService {
 func (s *realServer) createAuthService() auth.Service {
 	return auth.New(&auth.Config{
 		UserRepository: createAuthUserRepository(s),
-		Mailer:         createMailer(s),
 		JWTService:     s.createJWTService(),
-		ActivateURL:    s.config.FrontendBaseURL + "/activate",
+		Bus:            s.bus,
 	})
 }
 
@@ -180,15 +219,38 @@ func (s *realServer) createAuthOAuth2Service() auth.OAuth2Service {
 				s.config.OAuth2GoogleClientSecret,
 			),
 		},
+		Bus: s.bus,
 	})
 }
 
+// initAuth sends activation emails to newly registered users
+func (s *realServer) initAuth() {
+	_, err := auth.SubscribeActivationEmail(s.bus, &auth.ActivationConfig{
+		UserRepository: createAuthUserRepository(s),
+		Mailer:         createMailer(s),
+		ActivateURL:    s.config.FrontendBaseURL + "/activate",
+		Options:        &event.Options{Name: "auth.send_activation_email", Retry: handlerRetry},
+	})
+	if err != nil {
+		log.Fatalf("subscribe to auth events failed: %v", err)
+	}
+}
+
 // TODO: Change to real repository
 var createAuthUserRepository = func(s *realServer) auth.UserRepository {
 	return mock.NewRepository(memory.GlobalUserStore)
 }
 
-var createMailer = func(s *realServer) *mailer.Mailer {
+// createMailer queues the emails for the worker command with AsyncEmail, or sends them from the server
+var createMailer = func(s *realServer) mailer.Sender {
+	if s.config.AsyncEmail {
+		return mailer.NewQueue(createTaskRepository(s))
+	}
+
+	return createSMTPMailer()
+}
+
+var createSMTPMailer = func() mailer.Sender {
 	account := os.Getenv("MAIL_ACCOUNT")
 	password := os.Getenv("MAIL_PASSWORD")
 	return mailer.New("smtp.gmail.com", 587, account, password, account)
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victornm/es-backend/pkg/deadletter"
+	"github.com/victornm/es-backend/pkg/event"
+	"github.com/victornm/es-backend/pkg/store/postgres"
+)
+
+const deadLetterRedeliveryInterval = 10 * time.Second
+
+// handlerRetry is the retry policy of event handlers, events failing for longer are dead letters
+var handlerRetry = &event.RetryPolicy{MaxAttempts: 5, Backoff: time.Second, MaxBackoff: time.Minute}
+
+// @Summary Get dead letters
+// @Description Get events which event handlers failed on, oldest first. Admins only
+// @Tags dead letter
+// @Produce json
+// @Param status query string false "dead, requeued or redelivering, default dead"
+// @Param offset query int false "default 0"
+// @Param limit query int false "default 20"
+// @Success 200 {object} api.BaseResponse{data=[]deadletter.DeadLetterDTO} "Get dead letters successfully"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not an admin"
+// @Router /dead-letters [get]
+func (s *realServer) createGetDeadLettersHandler() gin.HandlerFunc {
+	service := s.createDeadLetterService()
+
+	return func(c *gin.Context) {
+		offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if err != nil {
+			reject(c, http.StatusBadRequest, deadletter.ErrInvalidInput)
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil {
+			reject(c, http.StatusBadRequest, deadletter.ErrInvalidInput)
+			return
+		}
+
+		letters, err := service.GetDeadLetters(c.Query("status"), offset, limit)
+		if err != nil {
+			reject(c, deadLetterErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, letters)
+	}
+}
+
+// @Summary Get a dead letter
+// @Description Admins only
+// @Tags dead letter
+// @Produce json
+// @Param id path int true "dead letter ID"
+// @Success 200 {object} api.BaseResponse{data=deadletter.DeadLetterDTO} "Get dead letter successfully"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not an admin"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Dead letter not found"
+// @Router /dead-letters/{id} [get]
+func (s *realServer) createGetDeadLetterHandler() gin.HandlerFunc {
+	service := s.createDeadLetterService()
+
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, deadletter.ErrDeadLetterNotFound)
+			return
+		}
+
+		l, err := service.GetDeadLetter(id)
+		if err != nil {
+			reject(c, deadLetterErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, l)
+	}
+}
+
+// @Summary Requeue a dead letter
+// @Description Send the event again to the handler which failed on it, within a few seconds. Admins only
+// @Tags dead letter
+// @Produce json
+// @Param id path int true "dead letter ID"
+// @Success 200 {object} api.BaseResponse "Requeue successfully"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not an admin"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Dead letter not found"
+// @Router /dead-letters/{id}/requeue [post]
+func (s *realServer) createRequeueDeadLetterHandler() gin.HandlerFunc {
+	service := s.createDeadLetterService()
+
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, deadletter.ErrDeadLetterNotFound)
+			return
+		}
+
+		if err := service.Requeue(id); err != nil {
+			reject(c, deadLetterErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, nil)
+	}
+}
+
+// @Summary Discard a dead letter
+// @Description Admins only
+// @Tags dead letter
+// @Produce json
+// @Param id path int true "dead letter ID"
+// @Success 200 {object} api.BaseResponse "Discard successfully"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not an admin"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Dead letter not found"
+// @Router /dead-letters/{id} [delete]
+func (s *realServer) createDiscardDeadLetterHandler() gin.HandlerFunc {
+	service := s.createDeadLetterService()
+
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, deadletter.ErrDeadLetterNotFound)
+			return
+		}
+
+		if err := service.Discard(id); err != nil {
+			reject(c, deadLetterErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, nil)
+	}
+}
+
+func deadLetterErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, deadletter.ErrDeadLetterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, deadletter.ErrUnknown):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// initDeadLetters stores the events event handlers failed on
+func (s *realServer) initDeadLetters() {
+	service := s.createDeadLetterService()
+
+	s.bus.OnDeadLetter(func(l *event.DeadLetter) {
+		if err := service.Record(l); err != nil {
+			log.Printf("store dead letter of %s failed: %v", l.Handler, err)
+		}
+	})
+}
+
+// runDeadLetterRedelivery sends requeued dead letters to their handler every interval, until ctx is done.
+// Dead letters may be requeued by the CLI in another process. It runs on every replica, each one claims the letters it sends.
+// It must stop before the bus is closed, requeued letters would be marked dead again without their handler.
+func (s *realServer) runDeadLetterRedelivery(ctx context.Context, interval time.Duration) {
+	service := s.createDeadLetterService()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			if _, err := service.Redeliver(s.bus); err != nil {
+				log.Printf("redeliver dead letters failed: %v", err)
+			}
+		}
+	}
+}
+
+// DeadLetterService returns the dead letters of the database, for the CLI
+func (s *realServer) DeadLetterService() deadletter.Service {
+	s.connectDB()
+
+	return s.createDeadLetterService()
+}
+
+func (s *realServer) createDeadLetterService() deadletter.Service {
+	return deadletter.New(&deadletter.Config{
+		Repository: createDeadLetterRepository(s),
+		Events:     domainEvents,
+	})
+}
+
+// dead letters are kept in the database, so they survive restarts and can be managed by the CLI
+var createDeadLetterRepository = func(s *realServer) deadletter.Repository {
+	return postgres.NewDeadLetterGateway(s.db)
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/auth"
+	"github.com/victornm/es-backend/pkg/event"
+	"github.com/victornm/es-backend/pkg/eventlog"
+	"github.com/victornm/es-backend/pkg/follow"
+	"github.com/victornm/es-backend/pkg/notification"
+	"github.com/victornm/es-backend/pkg/store/postgres"
+)
+
+// domainEvents are the event types which can be decoded from the outbox and the event log
+var domainEvents = []interface{}{
+	auth.UserRegistered{},
+	auth.UserSignedIn{},
+	follow.Followed{},
+	notification.NotificationCreated{},
+	notification.NotificationsRead{},
+}
+
+// newEventRegistry returns the registry of the domain events.
+// When the payload of an event changes, its old version is registered here with the upcaster to the new one,
+// so the event log can still be replayed.
+func newEventRegistry() *event.Registry {
+	return event.NewRegistry(domainEvents...)
+}
+
+// projection is state derived from events, kept up to date from the bus and rebuilt by replaying the event log
+type projection interface {
+	// Handle applies an event, events of other types are ignored
+	Handle(ctx context.Context, e interface{}) error
+
+	// Reset removes the state, before the events are replayed from the start
+	Reset() error
+}
+
+// projections receive old events again on every replay, so handlers with side effects like emails must not be added
+var projections = []struct {
+	name   string
+	create func(s *realServer) projection
+}{
+	{"notification.unread_counts", func(s *realServer) projection {
+		return notification.NewUnreadCounts(postgres.NewNotificationGateway(s.db))
+	}},
+}
+
+// initEventLog records every event published on the bus, and keeps the projections up to date
+func (s *realServer) initEventLog() {
+	s.openEventLog()
+
+	_, err := s.bus.Handle(s.createEventLogService().Record, &event.Options{
+		BufferSize: 1024,
+		Timeout:    5 * time.Second,
+		Name:       "eventlog.record",
+		Retry:      handlerRetry,
+	})
+	if err != nil {
+		log.Fatalf("subscribe to all events failed: %v", err)
+	}
+
+	for _, p := range projections {
+		_, err := s.bus.Handle(p.create(s).Handle, &event.Options{
+			BufferSize: 1024,
+			Timeout:    5 * time.Second,
+			Name:       p.name,
+			Retry:      handlerRetry,
+		})
+		if err != nil {
+			log.Fatalf("subscribe projection %s failed: %v", p.name, err)
+		}
+	}
+}
+
+// Replay passes logged events to the projections, one by one.
+// A replay from the start resets the projections first, a replay from an offset or a time
+// applies the events on top of their state, to catch up after a failed replay for example.
+// With out set, it is a dry run: the envelopes of the events are written to out as JSON Lines instead of being handled.
+func (s *realServer) Replay(input *eventlog.ReplayInput, out io.Writer) (*eventlog.ReplayResult, error) {
+	s.connectDB()
+	s.openEventLog()
+	defer s.closeEventLog()
+
+	if out != nil {
+		return s.createEventLogService().Replay(input, func(env *event.Envelope) error {
+			line, err := json.Marshal(env)
+			if err != nil {
+				return err
+			}
+
+			_, err = fmt.Fprintf(out, "%s\n", line)
+			return err
+		})
+	}
+
+	created := make([]projection, len(projections))
+	for i, p := range projections {
+		created[i] = p.create(s)
+	}
+
+	if input.FromOffset == 0 && input.Since.IsZero() {
+		for i, p := range created {
+			if err := p.Reset(); err != nil {
+				return nil, fmt.Errorf("reset projection %s: %w", projections[i].name, err)
+			}
+		}
+	}
+
+	return s.createEventLogService().Replay(input, func(env *event.Envelope) error {
+		ctx := event.WithEnvelope(context.Background(), env)
+		for i, p := range created {
+			if err := p.Handle(ctx, env.Payload); err != nil {
+				return fmt.Errorf("projection %s: %w", projections[i].name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *realServer) createEventLogService() eventlog.Service {
+	return eventlog.New(&eventlog.Config{
+		Repository: s.eventLog,
+		Registry:   newEventRegistry(),
+	})
+}
+
+// openEventLog uses the JSON Lines file of the config if set, the database otherwise
+func (s *realServer) openEventLog() {
+	if s.config.EventLogPath == "" {
+		s.eventLog = postgres.NewEventGateway(s.db)
+		return
+	}
+
+	l, err := eventlog.NewFileLog(s.config.EventLogPath)
+	if err != nil {
+		log.Fatalf("open event log failed: %v", err)
+	}
+
+	s.eventLog = l
+}
+
+func (s *realServer) closeEventLog() {
+	if c, ok := s.eventLog.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Printf("close event log failed: %v", err)
+		}
+	}
+}
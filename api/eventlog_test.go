@@ -0,0 +1,11 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/victornm/es-backend/pkg/event/eventtest"
+)
+
+func TestDomainEvents_RoundTrip(t *testing.T) {
+	eventtest.AssertRoundTrip(t, newEventRegistry())
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victornm/es-backend/pkg/event"
+	"github.com/victornm/es-backend/pkg/follow"
+	"github.com/victornm/es-backend/pkg/notification"
+	"github.com/victornm/es-backend/pkg/outbox"
+	"github.com/victornm/es-backend/pkg/store/memory"
+	"github.com/victornm/es-backend/pkg/user"
+)
+
+const (
+	newFollowerNotificationType = "new_follower"
+
+	// followDedupeSize is the number of recent follow events remembered to drop repeated deliveries
+	followDedupeSize = 1000
+)
+
+// @Summary Get a user's public profile
+// @Description Get the public profile of a user, with followers and following counts
+// @Tags follow
+// @Produce json
+// @Param id path int true "user ID"
+// @Success 200 {object} api.BaseResponse{data=user.PublicProfileDTO} "Get profile successfully"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "User not found"
+// @Router /profiles/{id} [get]
+func (s *realServer) createGetPublicProfileHandler() gin.HandlerFunc {
+	query := s.createUserGetPublicProfileQuery()
+
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, user.ErrNotFound)
+			return
+		}
+
+		p, err := query.GetPublicProfile(id)
+		if err != nil {
+			if errors.Is(err, user.ErrNotFound) {
+				reject(c, http.StatusNotFound, err)
+				return
+			}
+
+			reject(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		response(c, http.StatusOK, p)
+	}
+}
+
+// @Summary Follow a user
+// @Description Follow a user, the user is notified
+// @Tags follow
+// @Produce json
+// @Param id path int true "user ID"
+// @Success 201 {object} api.BaseResponse "Follow successfully"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "User not found"
+// @Failure 409 {object} api.BaseResponse{errors=[]api.Error} "Already following"
+// @Router /profiles/{id}/follow [post]
+func (s *realServer) createFollowHandler() gin.HandlerFunc {
+	service := s.createFollowService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, follow.ErrUserNotFound)
+			return
+		}
+
+		if err := service.Follow(userAuth.UserID, id); err != nil {
+			reject(c, followErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusCreated, nil)
+	}
+}
+
+// @Summary Unfollow a user
+// @Tags follow
+// @Produce json
+// @Param id path int true "user ID"
+// @Success 200 {object} api.BaseResponse "Unfollow successfully"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Not following"
+// @Router /profiles/{id}/follow [delete]
+func (s *realServer) createUnfollowHandler() gin.HandlerFunc {
+	service := s.createFollowService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, follow.ErrNotFollowing)
+			return
+		}
+
+		if err := service.Unfollow(userAuth.UserID, id); err != nil {
+			reject(c, followErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, nil)
+	}
+}
+
+// @Summary Get a user's followers
+// @Description Get users following a user, newest first
+// @Tags follow
+// @Produce json
+// @Param id path int true "user ID"
+// @Param offset query int false "default 0"
+// @Param limit query int false "default 20"
+// @Success 200 {object} api.BaseResponse{data=[]follow.FollowDTO} "Get followers successfully"
+// @Router /profiles/{id}/followers [get]
+func (s *realServer) createGetFollowersHandler() gin.HandlerFunc {
+	service := s.createFollowService()
+
+	return s.createFollowListHandler(service.GetFollowers)
+}
+
+// @Summary Get users a user follows
+// @Description Get users followed by a user, newest first
+// @Tags follow
+// @Produce json
+// @Param id path int true "user ID"
+// @Param offset query int false "default 0"
+// @Param limit query int false "default 20"
+// @Success 200 {object} api.BaseResponse{data=[]follow.FollowDTO} "Get following successfully"
+// @Router /profiles/{id}/following [get]
+func (s *realServer) createGetFollowingHandler() gin.HandlerFunc {
+	service := s.createFollowService()
+
+	return s.createFollowListHandler(service.GetFollowing)
+}
+
+func (s *realServer) createFollowListHandler(list func(userID, offset, limit int) ([]*follow.FollowDTO, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, follow.ErrUserNotFound)
+			return
+		}
+
+		offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if err != nil {
+			reject(c, http.StatusBadRequest, follow.ErrInvalidInput)
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil {
+			reject(c, http.StatusBadRequest, follow.ErrInvalidInput)
+			return
+		}
+
+		users, err := list(id, offset, limit)
+		if err != nil {
+			reject(c, followErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, users)
+	}
+}
+
+func followErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, follow.ErrUserNotFound), errors.Is(err, follow.ErrNotFollowing):
+		return http.StatusNotFound
+	case errors.Is(err, follow.ErrAlreadyFollowing):
+		return http.StatusConflict
+	case errors.Is(err, follow.ErrUnknown):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// initFollow notifies users about their new followers
+func (s *realServer) initFollow() {
+	finder := createFollowUserFinder(s)
+	notifier := s.createNotificationService()
+
+	// follows reach the bus through the outbox, which relays them at least once
+	deduper := outbox.NewDeduper(followDedupeSize)
+
+	_, err := s.bus.Handle(func(ctx context.Context, followed follow.Followed) error {
+		if deduper.Seen(followed.IdempotencyKey) {
+			return nil
+		}
+
+		err := notifyNewFollower(ctx, finder, notifier, followed)
+		if err != nil {
+			// retried with the same key
+			deduper.Forget(followed.IdempotencyKey)
+		}
+
+		return err
+	}, &event.Options{Name: "follow.notify_new_follower", Retry: handlerRetry})
+	if err != nil {
+		log.Fatalf("subscribe to follow events failed: %v", err)
+	}
+}
+
+func notifyNewFollower(ctx context.Context, finder follow.UserFinder, notifier notification.Service, followed follow.Followed) error {
+	follower, err := finder.FindUserByID(followed.FollowerID)
+	if err != nil {
+		return err
+	}
+
+	return notifier.Notify(ctx, &notification.NotifyInput{
+		UserID: followed.FolloweeID,
+		Type:   newFollowerNotificationType,
+		Title:  fmt.Sprintf("%s started following you", follower.Username),
+		Link:   fmt.Sprintf("/profiles/%d", follower.ID),
+	})
+}
+
+func (s *realServer) createFollowService() follow.Service {
+	return follow.New(&follow.Config{
+		Repository:  createFollowRepository(s),
+		UserFinder:  createFollowUserFinder(s),
+		Transaction: createFollowTransaction(s),
+	})
+}
+
+func (s *realServer) createUserGetPublicProfileQuery() user.GetPublicProfileQuery {
+	return user.NewPublicQueryService(createUserFinder(s), createFollowRepository(s))
+}
+
+// TODO: Change to real repository
+var createFollowRepository = func(s *realServer) follow.Repository {
+	return memory.GlobalFollowStore
+}
+
+// createFollowTransaction stores follows and then their events in the outbox.
+// It is not atomic: the memory stores have no transactions, so a follow stays stored if storing its event fails.
+// TODO: Change to postgres.Transaction with gateways bound to tx once users and follows are stored in the database:
+//
+//	return postgres.Transaction(s.db, func(tx postgres.DB) error {
+//		return f(postgres.NewFollowGateway(tx), postgres.NewOutboxGateway(tx))
+//	})
+var createFollowTransaction = func(s *realServer) follow.Transaction {
+	return func(f func(r follow.Repository, w outbox.Writer) error) error {
+		return f(createFollowRepository(s), createOutboxRepository(s))
+	}
+}
+
+var createFollowUserFinder = func(s *realServer) follow.UserFinder {
+	return memory.GlobalUserStore
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"time"
+
+	"github.com/victornm/es-backend/pkg/job"
+	"github.com/victornm/es-backend/pkg/store/postgres"
+)
+
+const (
+	jobPollInterval = time.Second
+
+	// jobLockKey is the key of the advisory lock held by the replica running the jobs
+	jobLockKey int64 = 0x6a6f6273
+)
+
+// initJobs registers the jobs, the leader runs them or queues them for the worker command with AsyncJobs
+func (s *realServer) initJobs() {
+	s.jobs = s.createJobService()
+	s.registerJobs(s.jobs)
+}
+
+// registerJobs registers the jobs and the functions running them, on the servers and the workers.
+// Jobs run on the leader or a worker, so they must only use the database: work on the memory or the disk of a replica,
+// such as notification digests and upload garbage collection, runs in a loop on every replica instead.
+// No job is registered yet.
+func (s *realServer) registerJobs(jobs job.Service) {}
+
+// JobService returns the jobs of the database, for the CLI
+func (s *realServer) JobService() job.Service {
+	s.connectDB()
+
+	return s.createJobService()
+}
+
+func (s *realServer) createJobService() job.Service {
+	config := &job.Config{
+		Repository: createJobRepository(s),
+		Lock:       postgres.NewAdvisoryLock(s.db, jobLockKey),
+	}
+
+	if s.config.AsyncJobs {
+		config.Queue = createTaskRepository(s)
+	}
+
+	return job.New(config)
+}
+
+// jobs are kept in the database, so replicas share them and the CLI can trigger them
+var createJobRepository = func(s *realServer) job.Repository {
+	return postgres.NewJobGateway(s.db)
+}
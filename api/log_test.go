@@ -0,0 +1,25 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactQuery(t *testing.T) {
+	tests := map[string]struct {
+		path   string
+		wanted string
+	}{
+		"no query":       {"/api/stream", "/api/stream"},
+		"no credentials": {"/api/notifications?limit=20", "/api/notifications?limit=20"},
+		"access token":   {"/api/stream?last_event_id=3&access_token=secret", "/api/stream?access_token=REDACTED&last_event_id=3"},
+		"invalid query":  {"/api/stream?access_token=%zz", "/api/stream"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.wanted, redactQuery(test.path))
+		})
+	}
+}
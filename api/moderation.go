@@ -0,0 +1,228 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victornm/es-backend/pkg/moderation"
+	"github.com/victornm/es-backend/pkg/store/memory"
+)
+
+const moderationAutoHideThreshold = 5
+
+type assignInput struct {
+	AssigneeID int `json:"assignee_id"`
+}
+
+// @Summary Report abusive content or user
+// @Description Report an item for moderators to review. Supported target types: user
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param report body moderation.ReportInput true "Report"
+// @Success 201 {object} api.BaseResponse{data=moderation.ReportDTO} "Report successfully"
+// @Failure 400 {object} api.BaseResponse{errors=[]api.Error} "Bad request"
+// @Router /reports [post]
+func (s *realServer) createReportHandler() gin.HandlerFunc {
+	service := s.createModerationService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		var input *moderation.ReportInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			reject(c, http.StatusBadRequest, moderation.ErrInvalidInput)
+			return
+		}
+
+		r, err := service.Report(userAuth.UserID, input)
+		if err != nil {
+			reject(c, moderationErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusCreated, r)
+	}
+}
+
+// @Summary Get the moderation queue
+// @Description Get reports waiting for moderators, oldest first. Moderators only
+// @Tags moderation
+// @Produce json
+// @Param status query string false "open, assigned or resolved, default both open and assigned"
+// @Param offset query int false "default 0"
+// @Param limit query int false "default 20"
+// @Success 200 {object} api.BaseResponse{data=[]moderation.ReportDTO} "Get queue successfully"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not a moderator"
+// @Router /moderation/reports [get]
+func (s *realServer) createGetModerationQueueHandler() gin.HandlerFunc {
+	service := s.createModerationService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if err != nil {
+			reject(c, http.StatusBadRequest, moderation.ErrInvalidInput)
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil {
+			reject(c, http.StatusBadRequest, moderation.ErrInvalidInput)
+			return
+		}
+
+		reports, err := service.GetQueue(userAuth.UserID, c.Query("status"), offset, limit)
+		if err != nil {
+			reject(c, moderationErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, reports)
+	}
+}
+
+// @Summary Assign a report
+// @Description Assign a report to a moderator. Moderators only
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param id path int true "report ID"
+// @Param assignee body assignInput true "Assignee"
+// @Success 200 {object} api.BaseResponse "Assign successfully"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not a moderator"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Report not found"
+// @Router /moderation/reports/{id}/assign [post]
+func (s *realServer) createAssignReportHandler() gin.HandlerFunc {
+	service := s.createModerationService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, moderation.ErrReportNotFound)
+			return
+		}
+
+		var input assignInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			reject(c, http.StatusBadRequest, moderation.ErrInvalidInput)
+			return
+		}
+
+		if err := service.Assign(userAuth.UserID, id, input.AssigneeID); err != nil {
+			reject(c, moderationErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, nil)
+	}
+}
+
+// @Summary Resolve a report
+// @Description Apply a decision to the reported item: hide, warn, suspend or dismiss.
+// @Description Every unresolved report of the same item is resolved. Moderators only
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param id path int true "report ID"
+// @Param decision body moderation.ResolveInput true "Decision"
+// @Success 200 {object} api.BaseResponse "Resolve successfully"
+// @Failure 400 {object} api.BaseResponse{errors=[]api.Error} "Bad request"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not a moderator"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Report not found"
+// @Router /moderation/reports/{id}/resolve [post]
+func (s *realServer) createResolveReportHandler() gin.HandlerFunc {
+	service := s.createModerationService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, moderation.ErrReportNotFound)
+			return
+		}
+
+		var input *moderation.ResolveInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			reject(c, http.StatusBadRequest, moderation.ErrInvalidInput)
+			return
+		}
+
+		if err := service.Resolve(userAuth.UserID, id, input); err != nil {
+			reject(c, moderationErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, nil)
+	}
+}
+
+// @Summary Get moderation audit trail
+// @Description Get every moderator decision about an item. Moderators only
+// @Tags moderation
+// @Produce json
+// @Param target_type query string true "target type"
+// @Param target_id query int true "target ID"
+// @Success 200 {object} api.BaseResponse{data=[]moderation.ActionDTO} "Get audit trail successfully"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not a moderator"
+// @Router /moderation/audit [get]
+func (s *realServer) createGetModerationAuditHandler() gin.HandlerFunc {
+	service := s.createModerationService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		targetID, err := strconv.Atoi(c.Query("target_id"))
+		if err != nil {
+			reject(c, http.StatusBadRequest, moderation.ErrInvalidInput)
+			return
+		}
+
+		actions, err := service.GetAuditTrail(userAuth.UserID, c.Query("target_type"), targetID)
+		if err != nil {
+			reject(c, moderationErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, actions)
+	}
+}
+
+func moderationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, moderation.ErrNotModerator):
+		return http.StatusForbidden
+	case errors.Is(err, moderation.ErrReportNotFound), errors.Is(err, moderation.ErrTargetNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, moderation.ErrAlreadyReported), errors.Is(err, moderation.ErrReportAlreadyResolved):
+		return http.StatusConflict
+	case errors.Is(err, moderation.ErrUnknown):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (s *realServer) createModerationService() moderation.Service {
+	return moderation.New(&moderation.Config{
+		Repository:        createModerationRepository(s),
+		UserRepository:    createModerationUserRepository(s),
+		Notifier:          s.createNotificationService(),
+		AutoHideThreshold: moderationAutoHideThreshold,
+	})
+}
+
+// TODO: Change to real repository
+var createModerationRepository = func(s *realServer) moderation.Repository {
+	return memory.GlobalModerationStore
+}
+
+var createModerationUserRepository = func(s *realServer) moderation.UserRepository {
+	return memory.GlobalUserStore
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victornm/es-backend/pkg/notification"
+	"github.com/victornm/es-backend/pkg/store/memory"
+)
+
+const notificationDigestInterval = time.Hour
+
+type notificationList struct {
+	Notifications []*notification.NotificationDTO `json:"notifications"`
+	UnreadCount   int                             `json:"unread_count"`
+}
+
+type unreadCount struct {
+	UnreadCount int `json:"unread_count"`
+}
+
+// @Summary Get notifications of current user
+// @Description Get notifications of current user, newest first
+// @Tags notification
+// @Produce json
+// @Param offset query int false "default 0"
+// @Param limit query int false "default 20"
+// @Success 200 {object} api.BaseResponse{data=notificationList} "Get notifications successfully"
+// @Failure 400 {object} api.BaseResponse{errors=[]api.Error} "Bad request"
+// @Router /notifications [get]
+func (s *realServer) createGetNotificationsHandler() gin.HandlerFunc {
+	service := s.createNotificationService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if err != nil {
+			reject(c, http.StatusBadRequest, notification.ErrInvalidInput)
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil {
+			reject(c, http.StatusBadRequest, notification.ErrInvalidInput)
+			return
+		}
+
+		notifications, err := service.GetNotifications(userAuth.UserID, offset, limit)
+		if err != nil {
+			reject(c, http.StatusBadRequest, err)
+			return
+		}
+
+		count, err := service.CountUnread(userAuth.UserID)
+		if err != nil {
+			reject(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		response(c, http.StatusOK, notificationList{Notifications: notifications, UnreadCount: count})
+	}
+}
+
+// @Summary Count unread notifications of current user
+// @Description Count unread notifications of current user
+// @Tags notification
+// @Produce json
+// @Success 200 {object} api.BaseResponse{data=unreadCount} "Count successfully"
+// @Router /notifications/unread-count [get]
+func (s *realServer) createCountUnreadNotificationsHandler() gin.HandlerFunc {
+	service := s.createNotificationService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		count, err := service.CountUnread(userAuth.UserID)
+		if err != nil {
+			reject(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		response(c, http.StatusOK, unreadCount{UnreadCount: count})
+	}
+}
+
+// @Summary Mark a notification as read
+// @Description Mark a notification of current user as read
+// @Tags notification
+// @Produce json
+// @Param id path int true "notification ID"
+// @Success 200 {object} api.BaseResponse "Mark successfully"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Notification not found"
+// @Router /notifications/{id}/read [post]
+func (s *realServer) createMarkNotificationAsReadHandler() gin.HandlerFunc {
+	service := s.createNotificationService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, notification.ErrNotFound)
+			return
+		}
+
+		if err := service.MarkAsRead(userAuth.UserID, id); err != nil {
+			reject(c, http.StatusNotFound, err)
+			return
+		}
+
+		response(c, http.StatusOK, nil)
+	}
+}
+
+// @Summary Mark all notifications as read
+// @Description Mark all notifications of current user as read
+// @Tags notification
+// @Produce json
+// @Success 200 {object} api.BaseResponse "Mark successfully"
+// @Router /notifications/read-all [post]
+func (s *realServer) createMarkAllNotificationsAsReadHandler() gin.HandlerFunc {
+	service := s.createNotificationService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		if err := service.MarkAllAsRead(userAuth.UserID); err != nil {
+			reject(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		response(c, http.StatusOK, nil)
+	}
+}
+
+// @Summary Get notification preferences of current user
+// @Description Get delivery channel of each notification type, types not listed use the default channel
+// @Tags notification
+// @Produce json
+// @Success 200 {object} api.BaseResponse{data=[]notification.PreferenceDTO} "Get preferences successfully"
+// @Router /notifications/preferences [get]
+func (s *realServer) createGetNotificationPreferencesHandler() gin.HandlerFunc {
+	service := s.createNotificationService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		prefs, err := service.GetPreferences(userAuth.UserID)
+		if err != nil {
+			reject(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		response(c, http.StatusOK, prefs)
+	}
+}
+
+// @Summary Update a notification preference of current user
+// @Description Choose the delivery channel of a notification type: in_app, email or digest
+// @Tags notification
+// @Produce json
+// @Param preference body notification.PreferenceInput true "Notification preference"
+// @Success 200 {object} api.BaseResponse "Update successfully"
+// @Failure 400 {object} api.BaseResponse{errors=[]api.Error} "Bad request"
+// @Router /notifications/preferences [put]
+func (s *realServer) createUpdateNotificationPreferenceHandler() gin.HandlerFunc {
+	service := s.createNotificationService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		var input *notification.PreferenceInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			reject(c, http.StatusBadRequest, notification.ErrInvalidInput)
+			return
+		}
+
+		if err := service.UpdatePreference(userAuth.UserID, input); err != nil {
+			if errors.Is(err, notification.ErrInvalidInput) {
+				reject(c, http.StatusBadRequest, err)
+				return
+			}
+			reject(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		response(c, http.StatusOK, nil)
+	}
+}
+
+func (s *realServer) createNotificationService() notification.Service {
+	return notification.New(&notification.Config{
+		Repository: createNotificationRepository(s),
+		UserFinder: createUserFinder(s),
+		Mailer:     createMailer(s),
+		Bus:        s.bus,
+	})
+}
+
+// runNotificationDigest sends the pending digest emails every interval until ctx is done.
+// It runs on every replica, as notifications are kept in the memory of each one.
+// TODO: Change to a job once notifications are stored in the database
+func (s *realServer) runNotificationDigest(ctx context.Context, interval time.Duration) {
+	service := s.createNotificationService()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			if err := service.SendDigests(); err != nil {
+				log.Printf("send notification digests failed: %v", err)
+			}
+		}
+	}
+}
+
+// TODO: Change to real repository
+var createNotificationRepository = func(s *realServer) notification.Repository {
+	return memory.GlobalNotificationStore
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/outbox"
+	"github.com/victornm/es-backend/pkg/store/memory"
+)
+
+const (
+	outboxRelayInterval   = time.Second
+	outboxCleanupInterval = time.Hour
+	outboxBatchSize       = 100
+	outboxRetention       = 7 * 24 * time.Hour
+)
+
+func (s *realServer) createOutboxService() outbox.Service {
+	return outbox.New(&outbox.Config{
+		Repository: createOutboxRepository(s),
+		Bus:        s.bus,
+		Events:     domainEvents,
+		BatchSize:  outboxBatchSize,
+		Retention:  outboxRetention,
+	})
+}
+
+// runOutboxRelay publishes stored events every relayInterval
+// and deletes delivered events every cleanupInterval, until ctx is done
+func (s *realServer) runOutboxRelay(ctx context.Context, relayInterval, cleanupInterval time.Duration) {
+	service := s.createOutboxService()
+
+	relay := time.NewTicker(relayInterval)
+	defer relay.Stop()
+
+	cleanup := time.NewTicker(cleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-relay.C:
+			// keep relaying while there is a backlog
+			for {
+				published, err := service.Relay()
+				if err != nil {
+					log.Printf("relay outbox failed: %v", err)
+				}
+
+				if err != nil || published < outboxBatchSize {
+					break
+				}
+			}
+
+		case <-cleanup.C:
+			deleted, err := service.Cleanup()
+			if err != nil {
+				log.Printf("clean up outbox failed: %v", err)
+				continue
+			}
+
+			if deleted > 0 {
+				log.Printf("deleted %d delivered outbox entries", deleted)
+			}
+		}
+	}
+}
+
+// TODO: Change to real repository
+var createOutboxRepository = func(s *realServer) outbox.Repository {
+	return memory.GlobalOutboxStore
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victornm/es-backend/pkg/notification"
+	"github.com/victornm/es-backend/pkg/stream"
+)
+
+const streamHeartbeat = 25 * time.Second
+
+// @Summary Stream events of current user
+// @Description Push notifications of current user using Server-Sent Events,
+// @Description or WebSocket if the request asks for an upgrade.
+// @Description Browsers can't set the Authorization header for these, so the token is also accepted in the access_token query.
+// @Description Send Last-Event-ID header (or last_event_id query) to resume after reconnecting.
+// @Tags stream
+// @Produce text/event-stream
+// @Param access_token query string false "JWT token, if Authorization header is not set"
+// @Param last_event_id query int false "resume after this event"
+// @Success 200 {string} string "Event stream"
+// @Failure 401 {object} api.BaseResponse{errors=[]api.Error} "Not authenticated"
+// @Router /stream [get]
+func (s *realServer) createStreamHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		sub, err := s.hub.Subscribe(userAuth.UserID, stream.LastEventID(c.Request))
+		if err != nil {
+			reject(c, http.StatusServiceUnavailable, err)
+			return
+		}
+		defer sub.Close()
+
+		if stream.IsWebSocket(c.Request) {
+			err = stream.ServeWebSocket(c.Writer, c.Request, sub, streamHeartbeat)
+		} else {
+			err = stream.ServeSSE(c.Writer, c.Request, sub, streamHeartbeat)
+		}
+
+		if err != nil {
+			log.Printf("stream to user %d ended: %v", userAuth.UserID, err)
+		}
+	}
+}
+
+// createStreamAuthMiddleware is the same as createAuthMiddleware
+// but falls back to the access_token query for EventSource and WebSocket clients
+func (s *realServer) createStreamAuthMiddleware() gin.HandlerFunc {
+	authenticate := s.createAuthenticator()
+	authMiddleware := s.createAuthMiddleware()
+
+	return func(c *gin.Context) {
+		token := c.Query("access_token")
+		if c.GetHeader("Authorization") != "" || token == "" {
+			authMiddleware(c)
+			return
+		}
+
+		userAuth, err := authenticate(token)
+		if err != nil {
+			abort(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		c.Set("user", userAuth)
+	}
+}
+
+func (s *realServer) initStream() {
+	s.hub = stream.NewHub(&stream.Config{})
+
+	stream.Forward(s.bus, s.hub, notification.NotificationCreated{}, "notification", func(e interface{}) (int, interface{}) {
+		created := e.(notification.NotificationCreated)
+		return created.UserID, created.Notification
+	})
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victornm/es-backend/pkg/store/memory"
+	"github.com/victornm/es-backend/pkg/store/postgres"
+	"github.com/victornm/es-backend/pkg/upload"
+)
+
+const (
+	uploadMaxFileSize    = 200 << 20 // 200MB, enough for short videos
+	uploadUserQuota      = 1 << 30   // 1GB
+	uploadURLExpiry      = 15 * time.Minute
+	uploadPendingTTL     = 24 * time.Hour
+	uploadGCInterval     = time.Hour
+	uploadOffsetHeader   = "Upload-Offset"
+	uploadChunkMediaType = "application/offset+octet-stream"
+)
+
+type signedURL struct {
+	URL string `json:"url"`
+}
+
+// @Summary Create an upload
+// @Description Start a resumable upload, the content is sent later in chunks using PATCH /uploads/{id}
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Param upload body upload.CreateInput true "File size and hex encoded SHA-256 checksum"
+// @Success 201 {object} api.BaseResponse{data=upload.UploadDTO} "Create successfully"
+// @Failure 400 {object} api.BaseResponse{errors=[]api.Error} "Bad request"
+// @Failure 413 {object} api.BaseResponse{errors=[]api.Error} "File too large or quota exceeded"
+// @Router /uploads [post]
+func (s *realServer) createCreateUploadHandler() gin.HandlerFunc {
+	service := s.createUploadService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		var input *upload.CreateInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			reject(c, http.StatusBadRequest, upload.ErrInvalidInput)
+			return
+		}
+
+		u, err := service.Create(userAuth.UserID, input)
+		if err != nil {
+			reject(c, uploadErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusCreated, u)
+	}
+}
+
+// @Summary Send a chunk of an upload
+// @Description Append the request body to the upload. Upload-Offset must be the current offset of the upload.
+// @Description The upload is verified and completed when the last chunk is received.
+// @Tags upload
+// @Accept application/offset+octet-stream
+// @Produce json
+// @Param id path string true "upload ID"
+// @Param Upload-Offset header int true "offset of this chunk"
+// @Success 200 {object} api.BaseResponse{data=upload.UploadDTO} "Chunk received"
+// @Failure 400 {object} api.BaseResponse{errors=[]api.Error} "Bad request or file rejected"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Upload not found"
+// @Failure 409 {object} api.BaseResponse{errors=[]api.Error} "Offset mismatch"
+// @Router /uploads/{id} [patch]
+func (s *realServer) createWriteUploadChunkHandler() gin.HandlerFunc {
+	service := s.createUploadService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		if mediaType, _, _ := mime.ParseMediaType(c.ContentType()); mediaType != uploadChunkMediaType {
+			reject(c, http.StatusUnsupportedMediaType, upload.ErrInvalidInput)
+			return
+		}
+
+		offset, err := strconv.ParseInt(c.GetHeader(uploadOffsetHeader), 10, 64)
+		if err != nil {
+			reject(c, http.StatusBadRequest, upload.ErrInvalidInput)
+			return
+		}
+
+		u, err := service.WriteChunk(userAuth.UserID, c.Param("id"), offset, c.Request.Body)
+		if err != nil {
+			reject(c, uploadErrorStatus(err), err)
+			return
+		}
+
+		c.Header(uploadOffsetHeader, strconv.FormatInt(u.Offset, 10))
+		response(c, http.StatusOK, u)
+	}
+}
+
+// @Summary Get an upload
+// @Description Get status and current offset of an upload, used for resuming
+// @Tags upload
+// @Produce json
+// @Param id path string true "upload ID"
+// @Success 200 {object} api.BaseResponse{data=upload.UploadDTO} "Get successfully"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Upload not found"
+// @Router /uploads/{id} [get]
+func (s *realServer) createGetUploadHandler() gin.HandlerFunc {
+	service := s.createUploadService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		u, err := service.Get(userAuth.UserID, c.Param("id"))
+		if err != nil {
+			reject(c, uploadErrorStatus(err), err)
+			return
+		}
+
+		c.Header(uploadOffsetHeader, strconv.FormatInt(u.Offset, 10))
+		response(c, http.StatusOK, u)
+	}
+}
+
+// @Summary Delete an upload
+// @Description Delete an upload and its content, its size no longer counts toward the quota
+// @Tags upload
+// @Produce json
+// @Param id path string true "upload ID"
+// @Success 200 {object} api.BaseResponse "Delete successfully"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Upload not found"
+// @Router /uploads/{id} [delete]
+func (s *realServer) createDeleteUploadHandler() gin.HandlerFunc {
+	service := s.createUploadService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		if err := service.Delete(userAuth.UserID, c.Param("id")); err != nil {
+			reject(c, uploadErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, nil)
+	}
+}
+
+// @Summary Get a download URL of an upload
+// @Description Get a signed URL to download a completed upload without authentication, the URL expires after a while
+// @Tags upload
+// @Produce json
+// @Param id path string true "upload ID"
+// @Success 200 {object} api.BaseResponse{data=signedURL} "Sign successfully"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Upload not found"
+// @Router /uploads/{id}/url [get]
+func (s *realServer) createSignUploadURLHandler() gin.HandlerFunc {
+	service := s.createUploadService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		u, err := service.SignURL(userAuth.UserID, c.Param("id"))
+		if err != nil {
+			reject(c, uploadErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, signedURL{URL: u})
+	}
+}
+
+// @Summary Download a file
+// @Description Download a completed upload using a signed URL from /uploads/{id}/url
+// @Tags upload
+// @Param id path string true "upload ID"
+// @Param expires query int true "expiry of the URL"
+// @Param signature query string true "signature of the URL"
+// @Success 200 {file} file "File content"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Invalid or expired signature"
+// @Router /files/{id} [get]
+func (s *realServer) createDownloadFileHandler() gin.HandlerFunc {
+	service := s.createUploadService()
+
+	return func(c *gin.Context) {
+		expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+		if err != nil {
+			reject(c, http.StatusForbidden, upload.ErrInvalidSignature)
+			return
+		}
+
+		f, err := service.Open(c.Param("id"), expires, c.Query("signature"))
+		if err != nil {
+			reject(c, uploadErrorStatus(err), err)
+			return
+		}
+		defer f.Close()
+
+		c.Header("Content-Type", f.ContentType)
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": f.Filename}))
+		http.ServeContent(c.Writer, c.Request, f.Filename, f.ModTime, f)
+	}
+}
+
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, upload.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, upload.ErrOffsetMismatch):
+		return http.StatusConflict
+	case errors.Is(err, upload.ErrTooLarge), errors.Is(err, upload.ErrQuotaExceeded):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, upload.ErrInvalidSignature):
+		return http.StatusForbidden
+	case errors.Is(err, upload.ErrUnknown):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (s *realServer) createUploadService() upload.Service {
+	repository := createUploadRepository(s)
+
+	// replicas may share the upload directory, the blobs of a replica are only known to the others with the database
+	_, shared := repository.(*postgres.UploadGateway)
+
+	return upload.New(&upload.Config{
+		Repository:  repository,
+		BlobStore:   s.blobs,
+		MaxFileSize: uploadMaxFileSize,
+		UserQuota:   uploadUserQuota,
+		Secret:      s.config.UploadSecret,
+		DownloadURL: s.config.APIBaseURL + "/api/files",
+		URLExpiry:   uploadURLExpiry,
+		PendingTTL:  uploadPendingTTL,
+
+		RemoveOrphanBlobs: shared,
+	})
+}
+
+// runUploadGarbageCollector removes abandoned and rejected uploads every interval until ctx is done.
+// It runs on every replica, as the uploads are kept in the memory of each one.
+func (s *realServer) runUploadGarbageCollector(ctx context.Context, interval time.Duration) {
+	service := s.createUploadService()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			removed, err := service.CollectGarbage()
+			if err != nil {
+				log.Printf("collect upload garbage failed: %v", err)
+			}
+
+			if removed > 0 {
+				log.Printf("removed %d orphaned uploads", removed)
+			}
+		}
+	}
+}
+
+func (s *realServer) initBlobStore() {
+	blobs, err := upload.NewLocalStore(s.config.UploadDir)
+	if err != nil {
+		log.Fatalf("open upload directory failed: %v", err)
+	}
+
+	s.blobs = blobs
+}
+
+// TODO: Change to real repository
+var createUploadRepository = func(s *realServer) upload.Repository {
+	return memory.GlobalUploadStore
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victornm/es-backend/pkg/auth"
+	"github.com/victornm/es-backend/pkg/event"
+	"github.com/victornm/es-backend/pkg/follow"
+	"github.com/victornm/es-backend/pkg/store/memory"
+	"github.com/victornm/es-backend/pkg/webhook"
+)
+
+const webhookDeliveryInterval = time.Second
+
+// webhookTopics are the events webhooks can subscribe to, by the event type sent to receivers.
+// Sign-ins are not offered, the activity of users is not shared with partners.
+var webhookTopics = map[string]*webhook.Topic{
+	"user.registered": {
+		Event: auth.UserRegistered{},
+		Payload: func(e interface{}) interface{} {
+			registered := e.(auth.UserRegistered)
+			return userRegisteredPayload{UserID: registered.UserID, Provider: registered.Provider}
+		},
+	},
+	"user.followed": {
+		Event: follow.Followed{},
+		Payload: func(e interface{}) interface{} {
+			followed := e.(follow.Followed)
+			return userFollowedPayload{FollowerID: followed.FollowerID, FolloweeID: followed.FolloweeID}
+		},
+	},
+}
+
+// userRegisteredPayload is the data of user.registered deliveries, the email address is left out
+type userRegisteredPayload struct {
+	UserID   int    `json:"user_id"`
+	Provider string `json:"provider"`
+}
+
+// userFollowedPayload is the data of user.followed deliveries
+type userFollowedPayload struct {
+	FollowerID int `json:"follower_id"`
+	FolloweeID int `json:"followee_id"`
+}
+
+// @Summary Create a webhook
+// @Description Register a URL receiving events. The secret used to sign deliveries is only returned here. Admins only
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param webhook body webhook.WebhookInput true "Webhook"
+// @Success 201 {object} api.BaseResponse{data=webhook.WebhookDTO} "Create successfully"
+// @Failure 400 {object} api.BaseResponse{errors=[]api.Error} "Bad request"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not an admin"
+// @Router /webhooks [post]
+func (s *realServer) createCreateWebhookHandler() gin.HandlerFunc {
+	service := s.createWebhookService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		var input *webhook.WebhookInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			reject(c, http.StatusBadRequest, webhook.ErrInvalidInput)
+			return
+		}
+
+		w, err := service.Create(userAuth.UserID, input)
+		if err != nil {
+			reject(c, webhookErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusCreated, w)
+	}
+}
+
+// @Summary Get webhooks
+// @Description Admins only
+// @Tags webhook
+// @Produce json
+// @Success 200 {object} api.BaseResponse{data=[]webhook.WebhookDTO} "Get webhooks successfully"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not an admin"
+// @Router /webhooks [get]
+func (s *realServer) createGetWebhooksHandler() gin.HandlerFunc {
+	service := s.createWebhookService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		webhooks, err := service.GetWebhooks(userAuth.UserID)
+		if err != nil {
+			reject(c, webhookErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, webhooks)
+	}
+}
+
+// @Summary Update a webhook
+// @Description Change the URL, the subscribed events or disable the webhook. Admins only
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "webhook ID"
+// @Param webhook body webhook.WebhookInput true "Webhook"
+// @Success 200 {object} api.BaseResponse "Update successfully"
+// @Failure 400 {object} api.BaseResponse{errors=[]api.Error} "Bad request"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not an admin"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Webhook not found"
+// @Router /webhooks/{id} [put]
+func (s *realServer) createUpdateWebhookHandler() gin.HandlerFunc {
+	service := s.createWebhookService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, webhook.ErrWebhookNotFound)
+			return
+		}
+
+		var input *webhook.WebhookInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			reject(c, http.StatusBadRequest, webhook.ErrInvalidInput)
+			return
+		}
+
+		if err := service.Update(userAuth.UserID, id, input); err != nil {
+			reject(c, webhookErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, nil)
+	}
+}
+
+// @Summary Delete a webhook
+// @Description Admins only
+// @Tags webhook
+// @Produce json
+// @Param id path int true "webhook ID"
+// @Success 200 {object} api.BaseResponse "Delete successfully"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not an admin"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Webhook not found"
+// @Router /webhooks/{id} [delete]
+func (s *realServer) createDeleteWebhookHandler() gin.HandlerFunc {
+	service := s.createWebhookService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, webhook.ErrWebhookNotFound)
+			return
+		}
+
+		if err := service.Delete(userAuth.UserID, id); err != nil {
+			reject(c, webhookErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, nil)
+	}
+}
+
+// @Summary Get webhook deliveries
+// @Description Get the delivery log of a webhook, newest first. Admins only
+// @Tags webhook
+// @Produce json
+// @Param id path int true "webhook ID"
+// @Param offset query int false "default 0"
+// @Param limit query int false "default 20"
+// @Success 200 {object} api.BaseResponse{data=[]webhook.DeliveryDTO} "Get deliveries successfully"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not an admin"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Webhook not found"
+// @Router /webhooks/{id}/deliveries [get]
+func (s *realServer) createGetWebhookDeliveriesHandler() gin.HandlerFunc {
+	service := s.createWebhookService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, webhook.ErrWebhookNotFound)
+			return
+		}
+
+		offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if err != nil {
+			reject(c, http.StatusBadRequest, webhook.ErrInvalidInput)
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil {
+			reject(c, http.StatusBadRequest, webhook.ErrInvalidInput)
+			return
+		}
+
+		deliveries, err := service.GetDeliveries(userAuth.UserID, id, offset, limit)
+		if err != nil {
+			reject(c, webhookErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, deliveries)
+	}
+}
+
+// @Summary Send a test event
+// @Description Deliver a webhook.test event right away and return the result, failed test events are not retried. Admins only
+// @Tags webhook
+// @Produce json
+// @Param id path int true "webhook ID"
+// @Success 200 {object} api.BaseResponse{data=webhook.DeliveryDTO} "Test event sent"
+// @Failure 403 {object} api.BaseResponse{errors=[]api.Error} "Not an admin"
+// @Failure 404 {object} api.BaseResponse{errors=[]api.Error} "Webhook not found"
+// @Router /webhooks/{id}/test [post]
+func (s *realServer) createSendTestWebhookHandler() gin.HandlerFunc {
+	service := s.createWebhookService()
+
+	return func(c *gin.Context) {
+		userAuth := getUser(c)
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			reject(c, http.StatusNotFound, webhook.ErrWebhookNotFound)
+			return
+		}
+
+		d, err := service.SendTest(userAuth.UserID, id)
+		if err != nil {
+			reject(c, webhookErrorStatus(err), err)
+			return
+		}
+
+		response(c, http.StatusOK, d)
+	}
+}
+
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, webhook.ErrNotAdmin):
+		return http.StatusForbidden
+	case errors.Is(err, webhook.ErrWebhookNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, webhook.ErrUnknown):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// initWebhooks queues deliveries for every event webhooks can subscribe to
+func (s *realServer) initWebhooks() {
+	_, err := s.bus.Handle(s.createWebhookService().HandleEvent, &event.Options{
+		BufferSize: 1024,
+		Timeout:    5 * time.Second,
+		Name:       "webhook.queue_deliveries",
+		Retry:      handlerRetry,
+	})
+	if err != nil {
+		log.Fatalf("subscribe to webhook events failed: %v", err)
+	}
+}
+
+// runWebhookDelivery sends due webhook deliveries every interval, until ctx is done
+func (s *realServer) runWebhookDelivery(ctx context.Context, interval time.Duration) {
+	service := s.createWebhookService()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			if _, err := service.Deliver(); err != nil {
+				log.Printf("deliver webhooks failed: %v", err)
+			}
+		}
+	}
+}
+
+func (s *realServer) createWebhookService() webhook.Service {
+	return webhook.New(&webhook.Config{
+		Repository: createWebhookRepository(s),
+		UserFinder: createWebhookUserFinder(s),
+		Topics:     webhookTopics,
+	})
+}
+
+// TODO: Change to real repository
+var createWebhookRepository = func(s *realServer) webhook.Repository {
+	return memory.GlobalWebhookStore
+}
+
+var createWebhookUserFinder = func(s *realServer) webhook.UserFinder {
+	return memory.GlobalUserStore
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"context"
+
+	"github.com/victornm/es-backend/pkg/job"
+	"github.com/victornm/es-backend/pkg/mailer"
+	"github.com/victornm/es-backend/pkg/queue"
+	"github.com/victornm/es-backend/pkg/store/postgres"
+)
+
+// Work runs the queued tasks until ctx is done, for the worker command.
+// config.Repository is set to the task queue of the database.
+func (s *realServer) Work(ctx context.Context, config *queue.Config) {
+	s.connectDB()
+
+	jobs := s.createJobService()
+	s.registerJobs(jobs)
+
+	config.Repository = createTaskRepository(s)
+	w := queue.New(config)
+	w.Handle(mailer.SendTask, mailer.HandleSendTask(createSMTPMailer()))
+	w.Handle(job.RunTask, jobs.HandleRunTask)
+
+	w.Run(ctx)
+}
+
+// tasks are kept in the database, so they are shared by every worker process
+var createTaskRepository = func(s *realServer) queue.Repository {
+	return postgres.NewTaskGateway(s.db)
+}
@@ -0,0 +1,32 @@
+CREATE TABLE jobs
+(
+    id         int generated always as identity,
+    name       varchar(255) not null,
+    schedule   varchar(255) not null default '',
+    payload    jsonb        not null default 'null',
+    run_at     timestamp    not null,
+    status     varchar(16)  not null,
+    created_at timestamp,
+    updated_at timestamp,
+
+    primary key (id)
+);
+
+-- a periodic job exists once, however many replicas register it
+CREATE UNIQUE INDEX jobs_periodic_name_idx ON jobs (name) WHERE schedule <> '';
+CREATE INDEX jobs_due_idx ON jobs (run_at) WHERE status = 'scheduled';
+
+CREATE TABLE job_runs
+(
+    id          int generated always as identity,
+    job_id      integer      not null references jobs (id) on delete cascade,
+    name        varchar(255) not null,
+    status      varchar(16)  not null,
+    error       text         not null default '',
+    started_at  timestamp    not null,
+    finished_at timestamp,
+
+    primary key (id)
+);
+
+CREATE INDEX job_runs_name_idx ON job_runs (name, id);
@@ -0,0 +1,20 @@
+CREATE TABLE tasks
+(
+    id           int generated always as identity,
+    kind         varchar(255) not null,
+    unique_key   varchar(255) not null default '',
+    payload      jsonb        not null default 'null',
+    status       varchar(16)  not null,
+    attempts     integer      not null default 0,
+    last_error   text         not null default '',
+    run_at       timestamp    not null,
+    locked_until timestamp,
+    created_at   timestamp,
+    updated_at   timestamp,
+
+    primary key (id)
+);
+
+CREATE INDEX tasks_ready_idx ON tasks (kind, run_at) WHERE status = 'queued';
+
+CREATE UNIQUE INDEX tasks_unique_key_idx ON tasks (unique_key) WHERE status = 'queued' AND unique_key <> '';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notification_unread_counts;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
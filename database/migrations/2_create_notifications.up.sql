@@ -0,0 +1,35 @@
+CREATE TABLE notifications
+(
+    id         int generated always as identity,
+    user_id    integer      not null references users (id) on delete cascade,
+    type       varchar(64)  not null,
+    title      varchar(255) not null,
+    body       text         not null default '',
+    link       varchar(255) not null default '',
+    is_read    boolean      not null default false,
+    in_digest  boolean      not null default false,
+    created_at timestamp,
+    read_at    timestamp,
+
+    primary key (id)
+);
+
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, id);
+
+CREATE TABLE notification_preferences
+(
+    user_id integer     not null references users (id) on delete cascade,
+    type    varchar(64) not null,
+    channel varchar(16) not null,
+
+    primary key (user_id, type)
+);
+
+-- The unread counts are a projection rebuilt from the event log, which keeps the events of deleted users
+CREATE TABLE notification_unread_counts
+(
+    user_id integer not null,
+    unread  integer not null,
+
+    primary key (user_id)
+);
@@ -0,0 +1,18 @@
+CREATE TABLE uploads
+(
+    id           uuid,
+    user_id      integer      not null references users (id) on delete cascade,
+    filename     varchar(255) not null,
+    content_type varchar(255),
+    size         bigint       not null,
+    "offset"     bigint       not null default 0,
+    checksum     varchar(64)  not null,
+    status       varchar(16)  not null,
+    created_at   timestamp,
+    updated_at   timestamp,
+
+    primary key (id)
+);
+
+CREATE INDEX uploads_user_id_idx ON uploads (user_id);
+CREATE INDEX uploads_status_updated_at_idx ON uploads (status, updated_at);
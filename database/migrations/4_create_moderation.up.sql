@@ -0,0 +1,38 @@
+CREATE TABLE reports
+(
+    id          int generated always as identity,
+    reporter_id integer     not null references users (id) on delete cascade,
+    target_type varchar(32) not null,
+    target_id   integer     not null,
+    reason      varchar(32) not null,
+    comment     text,
+    status      varchar(16) not null,
+    assignee_id integer references users (id) on delete set null,
+    resolution  varchar(32),
+    created_at  timestamp,
+    updated_at  timestamp,
+
+    primary key (id)
+);
+
+CREATE INDEX reports_status_idx ON reports (status, id);
+CREATE INDEX reports_target_idx ON reports (target_type, target_id);
+-- a user has one unresolved report of a target
+CREATE UNIQUE INDEX reports_reporter_target_idx ON reports (reporter_id, target_type, target_id) WHERE status <> 'resolved';
+
+-- append-only audit trail of moderator decisions
+CREATE TABLE moderation_actions
+(
+    id           int generated always as identity,
+    moderator_id integer references users (id) on delete set null,
+    report_id    integer references reports (id) on delete set null,
+    target_type  varchar(32) not null,
+    target_id    integer     not null,
+    action       varchar(32) not null,
+    note         text,
+    created_at   timestamp,
+
+    primary key (id)
+);
+
+CREATE INDEX moderation_actions_target_idx ON moderation_actions (target_type, target_id);
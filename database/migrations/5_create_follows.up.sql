@@ -0,0 +1,11 @@
+CREATE TABLE follows
+(
+    follower_id integer not null references users (id) on delete cascade,
+    followee_id integer not null references users (id) on delete cascade,
+    created_at  timestamp,
+
+    primary key (follower_id, followee_id),
+    check (follower_id <> followee_id)
+);
+
+CREATE INDEX follows_followee_id_idx ON follows (followee_id, created_at);
@@ -0,0 +1,22 @@
+CREATE TABLE outbox
+(
+    id              bigserial,
+    event_id        varchar(36)  not null,
+    event_type      varchar(255) not null,
+    version         integer      not null,
+    payload         jsonb        not null,
+    actor_id        integer      not null default 0,
+    correlation_id  varchar(36)  not null,
+    occurred_at     timestamp    not null,
+    idempotency_key uuid         not null unique,
+    attempts        integer      not null default 0,
+    last_error      text         not null default '',
+    locked_until    timestamp,
+    created_at      timestamp,
+    delivered_at    timestamp,
+
+    primary key (id)
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (id) WHERE delivered_at IS NULL;
+CREATE INDEX outbox_delivered_at_idx ON outbox (delivered_at);
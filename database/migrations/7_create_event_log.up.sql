@@ -0,0 +1,16 @@
+CREATE TABLE event_log
+(
+    "offset"       bigserial,
+    event_id       varchar(36)  not null,
+    event_type     varchar(255) not null,
+    version        integer      not null,
+    payload        jsonb        not null,
+    actor_id       integer      not null default 0,
+    correlation_id varchar(36)  not null,
+    occurred_at    timestamp    not null,
+    created_at     timestamp    not null,
+
+    primary key ("offset")
+);
+
+CREATE INDEX event_log_created_at_idx ON event_log (created_at);
@@ -0,0 +1,33 @@
+CREATE TABLE webhooks
+(
+    id          int generated always as identity,
+    user_id     integer       not null references users (id) on delete cascade,
+    url         varchar(2048) not null,
+    secret      varchar(255)  not null,
+    event_types text[]        not null,
+    is_active   boolean       not null default true,
+    created_at  timestamp,
+    updated_at  timestamp,
+
+    primary key (id)
+);
+
+CREATE TABLE webhook_deliveries
+(
+    id              int generated always as identity,
+    webhook_id      integer      not null references webhooks (id) on delete cascade,
+    event_type      varchar(255) not null,
+    payload         jsonb        not null,
+    status          varchar(16)  not null,
+    attempts        integer      not null default 0,
+    response_code   integer      not null default 0,
+    error           text         not null default '',
+    next_attempt_at timestamp    not null,
+    created_at      timestamp,
+    updated_at      timestamp,
+
+    primary key (id)
+);
+
+CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id);
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,21 @@
+CREATE TABLE dead_letters
+(
+    id             int generated always as identity,
+    handler        varchar(255) not null,
+    event_id       varchar(36)  not null,
+    event_type     varchar(255) not null,
+    version        integer      not null,
+    payload        jsonb        not null,
+    actor_id       integer      not null default 0,
+    correlation_id varchar(36)  not null,
+    occurred_at    timestamp    not null,
+    error          text         not null default '',
+    attempts       integer      not null default 0,
+    status         varchar(16)  not null,
+    created_at     timestamp,
+    updated_at     timestamp,
+
+    primary key (id)
+);
+
+CREATE INDEX dead_letters_status_idx ON dead_letters (status, id);
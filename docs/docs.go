@@ -1,68 +1,1291 @@
 // GENERATED BY THE COMMAND ABOVE; DO NOT EDIT
-// This file was generated by swaggo/swag
+// This file was generated by swaggo/swag at
+// 2026-10-19 12:12:12.508245636 +0000 UTC m=+0.094206295
 
 package docs
 
 import (
 	"bytes"
-	"encoding/json"
-	"strings"
 
 	"github.com/alecthomas/template"
 	"github.com/swaggo/swag"
 )
 
 var doc = `{
-    "schemes": {{ marshal .Schemes }},
     "swagger": "2.0",
     "info": {
-        "description": "{{.Description}}",
-        "title": "{{.Title}}",
-        "contact": {},
+        "description": "Backend of E-Sharing",
+        "title": "ES API",
+        "contact": {
+            "name": "VictorNM",
+            "url": "https://github.com/VictorNM/"
+        },
         "license": {},
-        "version": "{{.Version}}"
+        "version": "1.0"
     },
-    "host": "{{.Host}}",
-    "basePath": "{{.BasePath}}",
+    "host": "localhost:8080",
+    "basePath": "/api",
     "paths": {
         "/api/ping": {
             "get": {
-                "description": "For testing",
+                "description": "For testing",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ping"
+                ],
+                "summary": "PING PONG",
+                "responses": {
+                    "200": {
+                        "description": "PING PONG",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dead-letters": {
+            "get": {
+                "description": "Get events which event handlers failed on, oldest first. Admins only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dead letter"
+                ],
+                "summary": "Get dead letters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "dead, requeued or redelivering, default dead",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "default 0",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "default 20",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Get dead letters successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dead-letters/{id}": {
+            "get": {
+                "description": "Admins only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dead letter"
+                ],
+                "summary": "Get a dead letter",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "dead letter ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Get dead letter successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Dead letter not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Admins only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dead letter"
+                ],
+                "summary": "Discard a dead letter",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "dead letter ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Discard successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Dead letter not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dead-letters/{id}/requeue": {
+            "post": {
+                "description": "Send the event again to the handler which failed on it, within a few seconds. Admins only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dead letter"
+                ],
+                "summary": "Requeue a dead letter",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "dead letter ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Requeue successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Dead letter not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}": {
+            "get": {
+                "description": "Download a completed upload using a signed URL from /uploads/{id}/url",
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Download a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "expiry of the URL",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature of the URL",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid or expired signature",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/audit": {
+            "get": {
+                "description": "Get every moderator decision about an item. Moderators only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "moderation"
+                ],
+                "summary": "Get moderation audit trail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "target type",
+                        "name": "target_type",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "target ID",
+                        "name": "target_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Get audit trail successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not a moderator",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/reports": {
+            "get": {
+                "description": "Get reports waiting for moderators, oldest first. Moderators only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "moderation"
+                ],
+                "summary": "Get the moderation queue",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "open, assigned or resolved, default both open and assigned",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "default 0",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "default 20",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Get queue successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not a moderator",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/reports/{id}/assign": {
+            "post": {
+                "description": "Assign a report to a moderator. Moderators only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "moderation"
+                ],
+                "summary": "Assign a report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "report ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Assignee",
+                        "name": "assignee",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/assignInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Assign successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not a moderator",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/reports/{id}/resolve": {
+            "post": {
+                "description": "Apply a decision to the reported item: hide, warn, suspend or dismiss.\nEvery unresolved report of the same item is resolved. Moderators only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "moderation"
+                ],
+                "summary": "Resolve a report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "report ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Decision",
+                        "name": "decision",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/moderation.ResolveInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Resolve successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not a moderator",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications": {
+            "get": {
+                "description": "Get notifications of current user, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notification"
+                ],
+                "summary": "Get notifications of current user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "default 0",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "default 20",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Get notifications successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/preferences": {
+            "get": {
+                "description": "Get delivery channel of each notification type, types not listed use the default channel",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notification"
+                ],
+                "summary": "Get notification preferences of current user",
+                "responses": {
+                    "200": {
+                        "description": "Get preferences successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Choose the delivery channel of a notification type: in_app, email or digest",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notification"
+                ],
+                "summary": "Update a notification preference of current user",
+                "parameters": [
+                    {
+                        "description": "Notification preference",
+                        "name": "preference",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/notification.PreferenceInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Update successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/read-all": {
+            "post": {
+                "description": "Mark all notifications of current user as read",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notification"
+                ],
+                "summary": "Mark all notifications as read",
+                "responses": {
+                    "200": {
+                        "description": "Mark successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/unread-count": {
+            "get": {
+                "description": "Count unread notifications of current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notification"
+                ],
+                "summary": "Count unread notifications of current user",
+                "responses": {
+                    "200": {
+                        "description": "Count successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/{id}/read": {
+            "post": {
+                "description": "Mark a notification of current user as read",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notification"
+                ],
+                "summary": "Mark a notification as read",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "notification ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Mark successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Notification not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth2/register": {
+            "post": {
+                "description": "Register using oauth2",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register using oauth2",
+                "parameters": [
+                    {
+                        "description": "Register new user using oauth2",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/auth.OAuth2Input"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Register successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth2/sign-in": {
+            "post": {
+                "description": "Sign in using oauth2",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign in using oauth2",
+                "parameters": [
+                    {
+                        "description": "Sign in using oauth2",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/auth.OAuth2Input"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sign in successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profiles/{id}": {
+            "get": {
+                "description": "Get the public profile of a user, with followers and following counts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "follow"
+                ],
+                "summary": "Get a user's public profile",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "user ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Get profile successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profiles/{id}/follow": {
+            "post": {
+                "description": "Follow a user, the user is notified",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "follow"
+                ],
+                "summary": "Follow a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "user ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Follow successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Already following",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "follow"
+                ],
+                "summary": "Unfollow a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "user ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Unfollow successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not following",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profiles/{id}/followers": {
+            "get": {
+                "description": "Get users following a user, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "follow"
+                ],
+                "summary": "Get a user's followers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "user ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "default 0",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "default 20",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Get followers successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profiles/{id}/following": {
+            "get": {
+                "description": "Get users followed by a user, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "follow"
+                ],
+                "summary": "Get users a user follows",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "user ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "default 0",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "default 20",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Get following successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports": {
+            "post": {
+                "description": "Report an item for moderators to review. Supported target types: user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "moderation"
+                ],
+                "summary": "Report abusive content or user",
+                "parameters": [
+                    {
+                        "description": "Report",
+                        "name": "report",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/moderation.ReportInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Report successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stream": {
+            "get": {
+                "description": "Push notifications of current user using Server-Sent Events,\nor WebSocket if the request asks for an upgrade.\nBrowsers can't set the Authorization header for these, so the token is also accepted in the access_token query.\nSend Last-Event-ID header (or last_event_id query) to resume after reconnecting.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "stream"
+                ],
+                "summary": "Stream events of current user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT token, if Authorization header is not set",
+                        "name": "access_token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "resume after this event",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads": {
+            "post": {
+                "description": "Start a resumable upload, the content is sent later in chunks using PATCH /uploads/{id}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Create an upload",
+                "parameters": [
+                    {
+                        "description": "File size and hex encoded SHA-256 checksum",
+                        "name": "upload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/upload.CreateInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Create successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "File too large or quota exceeded",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads/{id}": {
+            "get": {
+                "description": "Get status and current offset of an upload, used for resuming",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Get an upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Get successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Upload not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete an upload and its content, its size no longer counts toward the quota",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Delete an upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Delete successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Upload not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Append the request body to the upload. Upload-Offset must be the current offset of the upload.\nThe upload is verified and completed when the last chunk is received.",
+                "consumes": [
+                    "application/offset+octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Send a chunk of an upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "offset of this chunk",
+                        "name": "Upload-Offset",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chunk received",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or file rejected",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Upload not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Offset mismatch",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads/{id}/url": {
+            "get": {
+                "description": "Get a signed URL to download a completed upload without authentication, the URL expires after a while",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Get a download URL of an upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sign successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Upload not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/profile": {
+            "get": {
+                "description": "Get profile by user_id in token,",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ping"
+                    "user"
                 ],
-                "summary": "PING PONG",
+                "summary": "Get current sign-inned user's profile",
                 "responses": {
                     "200": {
-                        "description": "PING PONG",
+                        "description": "Get profile successfully",
                         "schema": {
+                            "type": "object",
                             "$ref": "#/definitions/api.BaseResponse"
                         }
                     }
                 }
             }
         },
-        "/oauth2/register": {
+        "/users/register": {
             "post": {
-                "description": "Register using oauth2",
+                "description": "Register using email and password",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "auth"
                 ],
-                "summary": "Register using oauth2",
+                "summary": "Register using email and password",
                 "parameters": [
                     {
-                        "description": "Register new user using oauth2",
+                        "description": "Register new user",
                         "name": "user",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/auth.OAuth2Input"
+                            "type": "object",
+                            "$ref": "#/definitions/auth.RegisterInput"
                         }
                     }
                 ],
@@ -70,229 +1293,325 @@ var doc = `{
                     "201": {
                         "description": "Register successfully",
                         "schema": {
+                            "type": "object",
                             "$ref": "#/definitions/api.BaseResponse"
                         }
                     },
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/api.BaseResponse"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "errors": {
-                                            "type": "array",
-                                            "items": {
-                                                "$ref": "#/definitions/api.Error"
-                                            }
-                                        }
-                                    }
-                                }
-                            ]
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
                         }
                     }
                 }
             }
         },
-        "/oauth2/sign-in": {
+        "/users/sign-in": {
             "post": {
-                "description": "Sign in using oauth2",
+                "description": "Sign in using email and password",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "auth"
                 ],
-                "summary": "Sign in using oauth2",
-                "parameters": [
-                    {
-                        "description": "Sign in using oauth2",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/auth.OAuth2Input"
-                        }
-                    }
-                ],
+                "summary": "Basic sign in using email, password",
                 "responses": {
                     "200": {
                         "description": "Sign in successfully",
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/api.BaseResponse"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "data": {
-                                            "$ref": "#/definitions/api.authToken"
-                                        }
-                                    }
-                                }
-                            ]
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
                         }
                     },
                     "401": {
                         "description": "Not authenticated",
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/api.BaseResponse"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "errors": {
-                                            "type": "array",
-                                            "items": {
-                                                "$ref": "#/definitions/api.Error"
-                                            }
-                                        }
-                                    }
-                                }
-                            ]
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
                         }
                     }
                 }
             }
         },
-        "/users/profile": {
+        "/webhooks": {
             "get": {
-                "description": "Get profile by user_id in token,",
+                "description": "Admins only",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "webhook"
                 ],
-                "summary": "Get current sign-inned user's profile",
+                "summary": "Get webhooks",
                 "responses": {
                     "200": {
-                        "description": "Get profile successfully",
+                        "description": "Get webhooks successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/api.BaseResponse"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "data": {
-                                            "$ref": "#/definitions/user.ProfileDTO"
-                                        }
-                                    }
-                                }
-                            ]
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
                         }
                     }
                 }
-            }
-        },
-        "/users/register": {
+            },
             "post": {
-                "description": "Register using email and password",
+                "description": "Register a URL receiving events. The secret used to sign deliveries is only returned here. Admins only",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "webhook"
                 ],
-                "summary": "Register using email and password",
+                "summary": "Create a webhook",
                 "parameters": [
                     {
-                        "description": "Register new user",
-                        "name": "user",
+                        "description": "Webhook",
+                        "name": "webhook",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/auth.RegisterInput"
+                            "type": "object",
+                            "$ref": "#/definitions/webhook.WebhookInput"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Register successfully",
+                        "description": "Create successfully",
                         "schema": {
+                            "type": "object",
                             "$ref": "#/definitions/api.BaseResponse"
                         }
                     },
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/api.BaseResponse"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "errors": {
-                                            "type": "array",
-                                            "items": {
-                                                "$ref": "#/definitions/api.Error"
-                                            }
-                                        }
-                                    }
-                                }
-                            ]
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
                         }
                     }
                 }
             }
         },
-        "/users/sign-in": {
+        "/webhooks/{id}": {
+            "put": {
+                "description": "Change the URL, the subscribed events or disable the webhook. Admins only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Update a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/webhook.WebhookInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Update successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Admins only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Delete successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Get the delivery log of a webhook, newest first. Admins only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Get webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "default 0",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "default 20",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Get deliveries successfully",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/test": {
             "post": {
-                "description": "Sign in using email and password",
+                "description": "Deliver a webhook.test event right away and return the result, failed test events are not retried. Admins only",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "webhook"
+                ],
+                "summary": "Send a test event",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Basic sign in using email, password",
                 "responses": {
                     "200": {
-                        "description": "Sign in successfully",
+                        "description": "Test event sent",
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/api.BaseResponse"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "data": {
-                                            "$ref": "#/definitions/api.authToken"
-                                        }
-                                    }
-                                }
-                            ]
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
                         }
                     },
-                    "401": {
-                        "description": "Not authenticated",
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/api.BaseResponse"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "errors": {
-                                            "type": "array",
-                                            "items": {
-                                                "$ref": "#/definitions/api.Error"
-                                            }
-                                        }
-                                    }
-                                }
-                            ]
+                            "type": "object",
+                            "$ref": "#/definitions/api.BaseResponse"
                         }
                     }
                 }
@@ -325,14 +1644,6 @@ var doc = `{
                 }
             }
         },
-        "api.authToken": {
-            "type": "object",
-            "properties": {
-                "token": {
-                    "type": "string"
-                }
-            }
-        },
         "auth.OAuth2Input": {
             "type": "object",
             "properties": {
@@ -371,37 +1682,103 @@ var doc = `{
                 }
             }
         },
-        "user.ProfileDTO": {
+        "moderation.ReportInput": {
             "type": "object",
+            "required": [
+                "reason",
+                "target_id",
+                "target_type"
+            ],
             "properties": {
-                "country": {
+                "comment": {
                     "type": "string"
                 },
-                "email": {
+                "reason": {
                     "type": "string"
                 },
-                "full_name": {
-                    "type": "string"
+                "target_id": {
+                    "type": "integer"
                 },
-                "gender": {
+                "target_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "moderation.ResolveInput": {
+            "type": "object",
+            "required": [
+                "action"
+            ],
+            "properties": {
+                "action": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
-                },
-                "language": {
+                "note": {
+                    "type": "string"
+                }
+            }
+        },
+        "notification.PreferenceInput": {
+            "type": "object",
+            "required": [
+                "channel",
+                "type"
+            ],
+            "properties": {
+                "channel": {
                     "type": "string"
                 },
-                "phone": {
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "upload.CreateInput": {
+            "type": "object",
+            "required": [
+                "checksum",
+                "filename",
+                "size"
+            ],
+            "properties": {
+                "checksum": {
+                    "description": "hex encoded SHA-256 of the whole file",
                     "type": "string"
                 },
-                "username": {
+                "filename": {
                     "type": "string"
                 },
-                "year_of_birth": {
+                "size": {
                     "type": "integer"
                 }
             }
+        },
+        "webhook.WebhookInput": {
+            "type": "object",
+            "required": [
+                "event_types",
+                "url"
+            ],
+            "properties": {
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "is_active": {
+                    "description": "IsActive defaults to true when creating a webhook",
+                    "type": "boolean"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BasicAuth": {
+            "type": "basic"
         }
     }
 }`
@@ -410,39 +1787,23 @@ type swaggerInfo struct {
 	Version     string
 	Host        string
 	BasePath    string
-	Schemes     []string
 	Title       string
 	Description string
 }
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
-var SwaggerInfo = swaggerInfo{
-	Version:     "",
-	Host:        "",
-	BasePath:    "",
-	Schemes:     []string{},
-	Title:       "",
-	Description: "",
-}
+var SwaggerInfo swaggerInfo
 
 type s struct{}
 
 func (s *s) ReadDoc() string {
-	sInfo := SwaggerInfo
-	sInfo.Description = strings.Replace(sInfo.Description, "\n", "\\n", -1)
-
-	t, err := template.New("swagger_info").Funcs(template.FuncMap{
-		"marshal": func(v interface{}) string {
-			a, _ := json.Marshal(v)
-			return string(a)
-		},
-	}).Parse(doc)
+	t, err := template.New("swagger_info").Parse(doc)
 	if err != nil {
 		return doc
 	}
 
 	var tpl bytes.Buffer
-	if err := t.Execute(&tpl, sInfo); err != nil {
+	if err := t.Execute(&tpl, SwaggerInfo); err != nil {
 		return doc
 	}
 
@@ -2,12 +2,12 @@ package auth
 
 import (
 	"fmt"
-	"time"
 	"unicode"
 
 	"github.com/go-playground/validator/v10"
 
 	"github.com/victornm/es-backend/pkg/errorutil"
+	"github.com/victornm/es-backend/pkg/event"
 )
 
 var _ Service = (*service)(nil)
@@ -22,21 +22,22 @@ type Config struct {
 
 	JWTService JWTService
 
-	ActivateURL string
-	Mailer      Mailer
+	// Bus receives UserRegistered and UserSignedIn, optional.
+	// Activation emails are sent by SubscribeActivationEmail.
+	Bus *event.Bus
 }
 
 type service struct {
 	userRepository UserRepository
 	jwtService     JWTService
-	sender         *activationEmailSender
+	bus            *event.Bus
 }
 
 func New(config *Config) Service {
 	s := &service{
 		userRepository: config.UserRepository,
 		jwtService:     config.JWTService,
-		sender:         &activationEmailSender{mailer: config.Mailer, repository: config.UserRepository, path: config.ActivateURL},
+		bus:            config.Bus,
 	}
 
 	return s
@@ -69,7 +70,14 @@ func (s *service) BasicSignIn(email, password string) (string, error) {
 	}
 
 	// sign successfully
-	return s.jwtService.generateToken(u)
+	token, err := s.jwtService.generateToken(u)
+	if err != nil {
+		return "", err
+	}
+
+	publish(s.bus, u.ID, UserSignedIn{UserID: u.ID})
+
+	return token, nil
 }
 
 type SignInInput struct {
@@ -109,9 +117,7 @@ func (s *service) Register(input *RegisterInput) error {
 		return errorutil.Wrap(ErrUnknown, err)
 	}
 
-	time.AfterFunc(time.Millisecond, func() {
-		s.sender.SendActivationEmail(id)
-	})
+	publish(s.bus, id, UserRegistered{UserID: id, Email: u.Email})
 
 	return nil
 }
@@ -3,7 +3,6 @@ package auth_test
 import (
 	"errors"
 	"fmt"
-	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -11,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	. "github.com/victornm/es-backend/pkg/auth"
 	"github.com/victornm/es-backend/pkg/auth/mock"
+	"github.com/victornm/es-backend/pkg/event"
 	gatewayMemory "github.com/victornm/es-backend/pkg/store/memory"
 )
 
@@ -77,6 +77,28 @@ func TestBasicSignIn(t *testing.T) {
 	}
 }
 
+func TestBasicSignIn_PublishEvent(t *testing.T) {
+	repository := newUserRepository()
+	repository.Seed([]*User{
+		{Email: "victornm@es.com", Username: "victornm", HashedPassword: MustHashPassword("1234abcd"), IsActive: true},
+	})
+
+	bus := event.NewBus()
+	sub := bus.Subscribe(UserSignedIn{}, nil)
+
+	s := New(&Config{
+		UserRepository: repository,
+		JWTService:     NewJWTService("#12345", 24),
+		Bus:            bus,
+	})
+
+	_, err := s.BasicSignIn("victornm@es.com", "1234abcd")
+	require.NoError(t, err)
+
+	u, _ := repository.FindUserByEmail("victornm@es.com")
+	assert.Equal(t, UserSignedIn{UserID: u.ID}, <-sub.C())
+}
+
 func TestRegister(t *testing.T) {
 	usersInDB := []*User{
 		{
@@ -133,8 +155,6 @@ func TestRegister(t *testing.T) {
 
 			s := New(&Config{
 				UserRepository: repository,
-				Mailer:         &mock.Mailer{},
-				ActivateURL:    "/activate",
 			})
 
 			// when
@@ -150,34 +170,38 @@ func TestRegister_SendActivationMail(t *testing.T) {
 	// given
 	repository := newUserRepository()
 
-	wg := new(sync.WaitGroup)
-	wg.Add(1)
-	sent := false
+	var sentTo []string
 	mailer := &mock.Mailer{SendFunc: func(subject string, tmpl string, data interface{}, to []string) error {
-		sent = true
-		wg.Done()
+		sentTo = to
 		return nil
 	}}
 
-	s := New(&Config{
+	bus := event.NewBus()
+	_, err := SubscribeActivationEmail(bus, &ActivationConfig{
 		UserRepository: repository,
 		Mailer:         mailer,
 		ActivateURL:    "/activation",
 	})
+	require.NoError(t, err)
+
+	s := New(&Config{
+		UserRepository: repository,
+		Bus:            bus,
+	})
 
 	// when
-	err := s.Register(&RegisterInput{
+	err = s.Register(&RegisterInput{
 		Email:                "newEmail@gmail.com",
 		Username:             "newUser",
 		Password:             "1234abcd",
 		PasswordConfirmation: "1234abcd",
 		FullName:             "VictorNM",
 	})
-	wg.Wait()
+	bus.Close()
 
 	// then
 	require.NoError(t, err)
-	assert.True(t, sent)
+	assert.Equal(t, []string{"newEmail@gmail.com"}, sentTo)
 }
 
 func TestRegister_ValidateInput(t *testing.T) {
@@ -199,7 +223,6 @@ func TestRegister_ValidateInput(t *testing.T) {
 
 				s := New(&Config{
 					UserRepository: repository,
-					Mailer:         &mock.Mailer{},
 				})
 
 				assert.NoError(t, s.Register(input))
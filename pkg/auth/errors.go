@@ -7,6 +7,9 @@ var (
 	ErrNotAuthenticated = errors.New("not authenticated")
 	ErrNotActivated     = errors.New("not activated")
 
+	// Authorization errors
+	ErrNotAdmin = errors.New("not an admin")
+
 	// Registration errors
 	ErrEmailExisted    = errors.New("email already existed")
 	ErrUsernameExisted = errors.New("username already existed")
@@ -0,0 +1,19 @@
+package auth
+
+// UserRegistered is published when a user account is created.
+// Provider is empty for users registered with email and password.
+type UserRegistered struct {
+	UserID   int
+	Email    string
+	Provider string
+}
+
+func (UserRegistered) EventName() string { return "user.registered" }
+
+// UserSignedIn is published when a user receives a token
+type UserSignedIn struct {
+	UserID   int
+	Provider string
+}
+
+func (UserSignedIn) EventName() string { return "user.signed_in" }
@@ -1,24 +1,56 @@
 package auth
 
 import (
+	"context"
 	"html/template"
 	"path"
+
+	"github.com/victornm/es-backend/pkg/event"
 )
 
 type Mailer interface {
 	Send(subject string, tmpl string, data interface{}, to []string) error
 }
 
+type ActivationConfig struct {
+	UserRepository UserRepository
+	Mailer         Mailer
+	ActivateURL    string
+
+	// Options of the subscription, such as its name and retry policy, may be nil
+	Options *event.Options
+}
+
+// SubscribeActivationEmail sends the activation email to users registered with email and password
+func SubscribeActivationEmail(b *event.Bus, config *ActivationConfig) (*event.Subscription, error) {
+	sender := &activationEmailSender{
+		mailer:     config.Mailer,
+		repository: config.UserRepository,
+		path:       config.ActivateURL,
+	}
+
+	return b.Handle(sender.handleUserRegistered, config.Options)
+}
+
 type activationEmailSender struct {
 	mailer     Mailer
 	repository UserRepository
 	path       string
 }
 
-func (sender *activationEmailSender) SendActivationEmail(userID int) {
+func (sender *activationEmailSender) handleUserRegistered(ctx context.Context, e UserRegistered) error {
+	// users of OAuth2 providers are already activated
+	if e.Provider != "" {
+		return nil
+	}
+
+	return sender.SendActivationEmail(e.UserID)
+}
+
+func (sender *activationEmailSender) SendActivationEmail(userID int) error {
 	u, err := sender.repository.FindUserByID(userID)
 	if err != nil {
-		return
+		return err
 	}
 
 	link := path.Join(sender.path, u.ActivationKey)
@@ -34,5 +66,5 @@ func (sender *activationEmailSender) SendActivationEmail(userID int) {
 	</body>
 </html>`
 
-	_ = sender.mailer.Send("Activate your email!", tpl, map[string]interface{}{"link": template.URL(link)}, []string{u.Email})
+	return sender.mailer.Send("Activate your email!", tpl, map[string]interface{}{"link": template.URL(link)}, []string{u.Email})
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/victornm/es-backend/pkg/errorutil"
+	"github.com/victornm/es-backend/pkg/event"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	googleOAuth2 "google.golang.org/api/oauth2/v2"
@@ -21,6 +22,9 @@ type OAuth2Config struct {
 	JWTService     JWTService
 
 	Providers []OAuth2Provider
+
+	// Bus receives UserRegistered and UserSignedIn, optional
+	Bus *event.Bus
 }
 
 func NewOAuth2Service(config *OAuth2Config) OAuth2Service {
@@ -28,6 +32,7 @@ func NewOAuth2Service(config *OAuth2Config) OAuth2Service {
 		userRepository: config.UserRepository,
 		factory:        newProviderFactory(config.Providers...),
 		jwtService:     config.JWTService,
+		bus:            config.Bus,
 	}
 }
 
@@ -40,6 +45,7 @@ type oauth2Service struct {
 	userRepository UserRepository
 	factory        providerFactory
 	jwtService     JWTService
+	bus            *event.Bus
 }
 
 type OAuth2Input struct {
@@ -63,11 +69,13 @@ func (s *oauth2Service) OAuth2Register(input OAuth2Input) error {
 		return errorutil.Wrap(ErrEmailExisted, err)
 	}
 
-	_, err = s.userRepository.CreateUser(u)
+	id, err := s.userRepository.CreateUser(u)
 	if err != nil {
 		return errorutil.Wrap(ErrUnknown, err)
 	}
 
+	publish(s.bus, id, UserRegistered{UserID: id, Email: u.Email, Provider: u.Provider})
+
 	return nil
 }
 
@@ -95,7 +103,14 @@ func (s *oauth2Service) OAuth2SignIn(input OAuth2Input) (string, error) {
 		return "", errorutil.Wrap(ErrNotActivated)
 	}
 
-	return s.jwtService.generateToken(user)
+	token, err := s.jwtService.generateToken(user)
+	if err != nil {
+		return "", err
+	}
+
+	publish(s.bus, user.ID, UserSignedIn{UserID: user.ID, Provider: user.Provider})
+
+	return token, nil
 }
 
 func newProviderFactory(providers ...OAuth2Provider) providerFactory {
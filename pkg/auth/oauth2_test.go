@@ -4,8 +4,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	. "github.com/victornm/es-backend/pkg/auth"
 	"github.com/victornm/es-backend/pkg/auth/mock"
+	"github.com/victornm/es-backend/pkg/event"
 )
 
 func TestOAuth2Register(t *testing.T) {
@@ -75,6 +77,37 @@ func TestOAuth2Register(t *testing.T) {
 	}
 }
 
+func TestOAuth2Register_NoActivationMail(t *testing.T) {
+	provider := mock.NewOAuth2Provider()
+	provider.Seed(map[string]*User{"code_1": {Email: "foo@bar.com", Provider: mock.ProviderName}})
+	repository := newUserRepository()
+
+	sent := false
+	bus := event.NewBus()
+	registered := bus.Subscribe(UserRegistered{}, nil)
+	_, err := SubscribeActivationEmail(bus, &ActivationConfig{
+		UserRepository: repository,
+		Mailer: &mock.Mailer{SendFunc: func(subject string, tmpl string, data interface{}, to []string) error {
+			sent = true
+			return nil
+		}},
+	})
+	require.NoError(t, err)
+
+	s := NewOAuth2Service(&OAuth2Config{
+		UserRepository: repository,
+		Providers:      []OAuth2Provider{provider},
+		Bus:            bus,
+	})
+
+	require.NoError(t, s.OAuth2Register(OAuth2Input{Provider: mock.ProviderName, Code: "code_1"}))
+	bus.Close()
+
+	e := <-registered.C()
+	assert.Equal(t, mock.ProviderName, e.(UserRegistered).Provider)
+	assert.False(t, sent, "users of OAuth2 providers are already activated")
+}
+
 func TestOAuth2SignIn(t *testing.T) {
 	providerName := mock.ProviderName
 	usersFromProvider := map[string]*User{
@@ -1,5 +1,11 @@
 package auth
 
+import (
+	"context"
+
+	"github.com/victornm/es-backend/pkg/event"
+)
+
 func validate(o interface{}) error {
 	if i, ok := o.(interface {
 		Valid() error
@@ -9,3 +15,10 @@ func validate(o interface{}) error {
 
 	return nil
 }
+
+// publish sends e, caused by the user, on the bus if the service has one
+func publish(b *event.Bus, userID int, e interface{}) {
+	if b != nil {
+		b.PublishContext(event.WithActor(context.Background(), userID), e)
+	}
+}
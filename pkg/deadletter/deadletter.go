@@ -0,0 +1,205 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+	"github.com/victornm/es-backend/pkg/event"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var _ Service = (*service)(nil)
+
+type Service interface {
+	// Record stores l, it receives the dead letters of a bus:
+	//
+	//	bus.OnDeadLetter(func(l *event.DeadLetter) { service.Record(l) })
+	Record(l *event.DeadLetter) error
+
+	// GetDeadLetters returns dead letters with the status, oldest first. An empty status is StatusDead.
+	// It lists StatusRedelivering dead letters too, so those left by a stopped process can be requeued.
+	GetDeadLetters(status string, offset, limit int) ([]*DeadLetterDTO, error)
+	GetDeadLetter(id int) (*DeadLetterDTO, error)
+
+	// Requeue marks a dead letter to be sent again to its handler by Redeliver,
+	// so it can be called by another process than the one running the handlers
+	Requeue(id int) error
+
+	// Discard deletes a dead letter
+	Discard(id int) error
+
+	// Redeliver sends requeued events to their handler on b, and deletes them.
+	// Events which can not be decoded or have no handler on b are dead again.
+	// The events are claimed first, so processes redelivering at the same time do not send them twice.
+	// It returns the number of events sent.
+	Redeliver(b *event.Bus) (int, error)
+}
+
+type Config struct {
+	Repository Repository
+
+	// Events are zero values of the event types which can be redelivered
+	Events []interface{}
+}
+
+const redeliverBatchSize = 100
+
+type service struct {
+	repository Repository
+	registry   *event.Registry
+}
+
+func New(config *Config) Service {
+	return &service{
+		repository: config.Repository,
+		registry:   event.NewRegistry(config.Events...),
+	}
+}
+
+func (s *service) Record(l *event.DeadLetter) error {
+	payload, err := json.Marshal(l.Event)
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	env := l.Envelope
+	if env == nil {
+		env = event.NewEnvelope(context.Background(), l.Event)
+	}
+
+	_, err = s.repository.CreateDeadLetter(&store.DeadLetterRow{
+		Handler:       l.Handler,
+		EventID:       env.ID,
+		EventType:     env.Type,
+		Version:       env.Version,
+		Payload:       payload,
+		ActorID:       env.ActorID,
+		CorrelationID: env.CorrelationID,
+		OccurredAt:    env.OccurredAt,
+		Error:         l.Err.Error(),
+		Attempts:      l.Attempts,
+		Status:        StatusDead,
+	})
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return nil
+}
+
+func (s *service) GetDeadLetters(status string, offset, limit int) ([]*DeadLetterDTO, error) {
+	if status == "" {
+		status = StatusDead
+	}
+
+	if !validStatus(status) || offset < 0 || limit <= 0 {
+		return nil, errorutil.Wrap(ErrInvalidInput, "status = %s, offset = %d, limit = %d", status, offset, limit)
+	}
+
+	rows, err := s.repository.FindDeadLetters(status, offset, limit)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	dtos := make([]*DeadLetterDTO, len(rows))
+	for i, row := range rows {
+		dtos[i] = toDeadLetterDTO(row)
+	}
+
+	return dtos, nil
+}
+
+func (s *service) GetDeadLetter(id int) (*DeadLetterDTO, error) {
+	row, err := s.repository.FindDeadLetterByID(id)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrDeadLetterNotFound, err)
+	}
+
+	return toDeadLetterDTO(row), nil
+}
+
+func (s *service) Requeue(id int) error {
+	row, err := s.repository.FindDeadLetterByID(id)
+	if err != nil {
+		return errorutil.Wrap(ErrDeadLetterNotFound, err)
+	}
+
+	row.Status = StatusRequeued
+	if err := s.repository.UpdateDeadLetter(row); err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return nil
+}
+
+func (s *service) Discard(id int) error {
+	if err := s.repository.DeleteDeadLetter(id); err != nil {
+		return errorutil.Wrap(ErrDeadLetterNotFound, err)
+	}
+
+	return nil
+}
+
+func (s *service) Redeliver(b *event.Bus) (int, error) {
+	rows, err := s.repository.ClaimDeadLetters(StatusRequeued, StatusRedelivering, redeliverBatchSize)
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	sent := 0
+	for _, row := range rows {
+		env, err := s.decode(row)
+		if err == nil && b.Redeliver(row.Handler, env) == 0 {
+			err = errorutil.Wrap(ErrHandlerNotFound, row.Handler)
+		}
+
+		if err != nil {
+			// the error is kept for admins, the event stays until it is discarded
+			row.Status = StatusDead
+			row.Error = err.Error()
+			if err := s.repository.UpdateDeadLetter(row); err != nil {
+				return sent, errorutil.Wrap(ErrUnknown, err)
+			}
+			continue
+		}
+
+		sent++
+		if err := s.repository.DeleteDeadLetter(row.ID); err != nil {
+			return sent, errorutil.Wrap(ErrUnknown, err)
+		}
+	}
+
+	return sent, nil
+}
+
+func validStatus(status string) bool {
+	switch status {
+	case StatusDead, StatusRequeued, StatusRedelivering:
+		return true
+	}
+
+	return false
+}
+
+// decode returns the envelope of row, with the payload upcast to the current version of its type
+func (s *service) decode(row *store.DeadLetterRow) (*event.Envelope, error) {
+	e, err := s.registry.Decode(row.EventType, row.Version, row.Payload)
+	if errors.Is(err, event.ErrNotRegistered) {
+		return nil, errorutil.Wrap(ErrEventNotRegistered, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &event.Envelope{
+		ID:            row.EventID,
+		Type:          event.Name(e),
+		Version:       event.Version(e),
+		OccurredAt:    row.OccurredAt,
+		ActorID:       row.ActorID,
+		CorrelationID: row.CorrelationID,
+		Payload:       e,
+	}, nil
+}
@@ -0,0 +1,185 @@
+package deadletter_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/victornm/es-backend/pkg/deadletter"
+	"github.com/victornm/es-backend/pkg/event"
+	"github.com/victornm/es-backend/pkg/store/memory"
+)
+
+type userRegistered struct {
+	UserID int
+}
+
+type userActivated struct {
+	UserID int
+}
+
+func newService() Service {
+	return New(&Config{
+		Repository: memory.NewDeadLetterGateway(),
+		Events:     []interface{}{userRegistered{}},
+	})
+}
+
+func TestRecord(t *testing.T) {
+	s := newService()
+
+	b := event.NewBus()
+	b.OnDeadLetter(func(l *event.DeadLetter) {
+		assert.NoError(t, s.Record(l))
+	})
+	_, _ = b.Handle(func(ctx context.Context, e userRegistered) error {
+		return errors.New("mail server down")
+	}, &event.Options{Name: "send_welcome_email"})
+
+	b.Publish(userRegistered{UserID: 1})
+	b.Publish(userRegistered{UserID: 2})
+	b.Close()
+
+	letters, err := s.GetDeadLetters("", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, letters, 2)
+	assert.Equal(t, "send_welcome_email", letters[0].Handler)
+	assert.Equal(t, event.Name(userRegistered{}), letters[0].EventType)
+	assert.JSONEq(t, `{"UserID":1}`, string(letters[0].Payload))
+	assert.Equal(t, "mail server down", letters[0].Error)
+	assert.Equal(t, 1, letters[0].Attempts)
+	assert.Equal(t, StatusDead, letters[0].Status)
+
+	_, err = s.GetDeadLetters("deleted", 0, 10)
+	assertIsError(t, ErrInvalidInput, err)
+}
+
+func TestRequeue(t *testing.T) {
+	s := newService()
+	require.NoError(t, s.Record(&event.DeadLetter{Handler: "a", Event: userRegistered{UserID: 1}, Attempts: 3, Err: errors.New("failed")}))
+	require.NoError(t, s.Record(&event.DeadLetter{Handler: "b", Event: userRegistered{UserID: 2}, Attempts: 3, Err: errors.New("failed")}))
+	require.NoError(t, s.Record(&event.DeadLetter{Handler: "a", Event: userActivated{UserID: 3}, Attempts: 3, Err: errors.New("failed")}))
+	require.NoError(t, s.Record(&event.DeadLetter{Handler: "unknown", Event: userRegistered{UserID: 4}, Attempts: 3, Err: errors.New("failed")}))
+
+	b := event.NewBus()
+	var received []userRegistered
+	_, _ = b.Handle(func(ctx context.Context, e userRegistered) error {
+		received = append(received, e)
+		return nil
+	}, &event.Options{Name: "a"})
+
+	for id := 1; id <= 4; id++ {
+		if id != 2 {
+			require.NoError(t, s.Requeue(id))
+		}
+	}
+	assertIsError(t, ErrDeadLetterNotFound, s.Requeue(100))
+
+	requeued, err := s.GetDeadLetters(StatusRequeued, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, requeued, 3)
+
+	sent, err := s.Redeliver(b)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	b.Close()
+
+	assert.Equal(t, []userRegistered{{UserID: 1}}, received, "only the handler which failed should receive the event again")
+
+	_, err = s.GetDeadLetter(1)
+	assertIsError(t, ErrDeadLetterNotFound, err, "redelivered events should be deleted")
+
+	l, err := s.GetDeadLetter(3)
+	require.NoError(t, err)
+	assert.Equal(t, StatusDead, l.Status)
+	assert.Contains(t, l.Error, ErrEventNotRegistered.Error())
+
+	l, err = s.GetDeadLetter(4)
+	require.NoError(t, err)
+	assert.Equal(t, StatusDead, l.Status)
+	assert.Contains(t, l.Error, ErrHandlerNotFound.Error())
+}
+
+func TestRedeliver_Envelope(t *testing.T) {
+	s := newService()
+
+	env := event.NewEnvelope(event.WithActor(context.Background(), 7), userRegistered{UserID: 1})
+	require.NoError(t, s.Record(&event.DeadLetter{Handler: "a", Event: env.Payload, Envelope: env, Attempts: 3, Err: errors.New("failed")}))
+	require.NoError(t, s.Requeue(1))
+
+	b := event.NewBus()
+	received := make(chan *event.Envelope, 1)
+	_, _ = b.Handle(func(ctx context.Context, e userRegistered) error {
+		received <- event.EnvelopeFromContext(ctx)
+		return nil
+	}, &event.Options{Name: "a"})
+
+	_, err := s.Redeliver(b)
+	require.NoError(t, err)
+	b.Close()
+
+	got := <-received
+	assert.Equal(t, env.ID, got.ID, "the envelope of the failed event should be redelivered")
+	assert.Equal(t, 7, got.ActorID)
+	assert.Equal(t, env.CorrelationID, got.CorrelationID)
+	assert.True(t, env.OccurredAt.Equal(got.OccurredAt))
+}
+
+func TestRedeliver_Concurrent(t *testing.T) {
+	repository := memory.NewDeadLetterGateway()
+	s := New(&Config{Repository: repository, Events: []interface{}{userRegistered{}}})
+	for id := 1; id <= 50; id++ {
+		require.NoError(t, s.Record(&event.DeadLetter{Handler: "a", Event: userRegistered{UserID: id}, Attempts: 3, Err: errors.New("failed")}))
+		require.NoError(t, s.Requeue(id))
+	}
+
+	b := event.NewBus()
+	var received int64
+	_, _ = b.Handle(func(ctx context.Context, e userRegistered) error {
+		atomic.AddInt64(&received, 1)
+		return nil
+	}, &event.Options{Name: "a", BufferSize: 100})
+
+	// replicas share the repository
+	wg := &sync.WaitGroup{}
+	var sent int64
+	for i := 0; i < 2; i++ {
+		replica := New(&Config{Repository: repository, Events: []interface{}{userRegistered{}}})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := replica.Redeliver(b)
+			assert.NoError(t, err)
+			atomic.AddInt64(&sent, int64(n))
+		}()
+	}
+	wg.Wait()
+	b.Close()
+
+	assert.Equal(t, int64(50), sent)
+	assert.Equal(t, int64(50), atomic.LoadInt64(&received), "every event should be redelivered once")
+}
+
+func TestDiscard(t *testing.T) {
+	s := newService()
+	require.NoError(t, s.Record(&event.DeadLetter{Handler: "a", Event: userRegistered{UserID: 1}, Attempts: 1, Err: errors.New("failed")}))
+
+	require.NoError(t, s.Discard(1))
+	assertIsError(t, ErrDeadLetterNotFound, s.Discard(1))
+
+	letters, err := s.GetDeadLetters(StatusDead, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+}
+
+func assertIsError(t *testing.T, wanted, got error, msgAndArgs ...interface{}) {
+	t.Helper()
+	if !errors.Is(got, wanted) {
+		t.Errorf("Error %v is not an %v %v", got, wanted, msgAndArgs)
+	}
+}
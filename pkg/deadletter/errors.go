@@ -0,0 +1,11 @@
+package deadletter
+
+import "errors"
+
+var (
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	ErrEventNotRegistered = errors.New("event type not registered")
+	ErrHandlerNotFound    = errors.New("handler not found")
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrUnknown            = errors.New("unknown error")
+)
@@ -0,0 +1,46 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+// Dead letter statuses
+const (
+	StatusDead = "dead"
+
+	// StatusRequeued dead letters are waiting to be sent again to their handler
+	StatusRequeued = "requeued"
+
+	// StatusRedelivering dead letters are claimed by Redeliver. They are only left with this status
+	// when the process stopped while sending them, and can be requeued.
+	StatusRedelivering = "redelivering"
+)
+
+type DeadLetterDTO struct {
+	ID        int             `json:"id"`
+	Handler   string          `json:"handler"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	Error     string          `json:"error"`
+	Attempts  int             `json:"attempts"`
+	Status    string          `json:"status"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+func toDeadLetterDTO(row *store.DeadLetterRow) *DeadLetterDTO {
+	return &DeadLetterDTO{
+		ID:        row.ID,
+		Handler:   row.Handler,
+		EventType: row.EventType,
+		Payload:   row.Payload,
+		Error:     row.Error,
+		Attempts:  row.Attempts,
+		Status:    row.Status,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
@@ -0,0 +1,17 @@
+package deadletter
+
+import "github.com/victornm/es-backend/pkg/store"
+
+type Repository interface {
+	CreateDeadLetter(l *store.DeadLetterRow) (int, error)
+	FindDeadLetterByID(id int) (*store.DeadLetterRow, error)
+
+	// FindDeadLetters returns dead letters with the status, oldest first
+	FindDeadLetters(status string, offset, limit int) ([]*store.DeadLetterRow, error)
+
+	// ClaimDeadLetters sets the status of up to limit dead letters with the status to claimedStatus, oldest first.
+	// Rows being claimed by another process are skipped, so a dead letter is never claimed twice.
+	ClaimDeadLetters(status, claimedStatus string, limit int) ([]*store.DeadLetterRow, error)
+	UpdateDeadLetter(l *store.DeadLetterRow) error
+	DeleteDeadLetter(id int) error
+}
@@ -0,0 +1,111 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Envelope carries an event with its metadata, every event published on a Bus is wrapped in one.
+// Handlers receive the event itself, and its envelope with EnvelopeFromContext.
+type Envelope struct {
+	ID   string `json:"id"`
+	Type string `json:"type"` // see Name
+
+	// Version is the version of the payload schema, see Versioned
+	Version    int       `json:"version"`
+	OccurredAt time.Time `json:"occurred_at"`
+
+	// ActorID is the user who caused the event, 0 for the system
+	ActorID int `json:"actor_id,omitempty"`
+
+	// CorrelationID is shared by the events caused by the same action,
+	// it is the ID of the first of them
+	CorrelationID string `json:"correlation_id"`
+
+	Payload interface{} `json:"payload"`
+}
+
+// Named events declare a stable name, such as "user.registered", which is stored with them.
+// Events which do not are stored with the name of their Go type, so moving or renaming the type breaks decoding them.
+// Events stored with the name of the Go type are still decoded after the event declares a name, see Registry.
+type Named interface {
+	EventName() string
+}
+
+// Versioned events declare the version of their schema, events which do not are version 1.
+// The version is increased when a change would break consumers of old payloads,
+// and an Upcaster from the previous version is registered, see Registry.RegisterVersion.
+type Versioned interface {
+	EventVersion() int
+}
+
+// Version returns the version of the schema of e
+func Version(e interface{}) int {
+	if v, ok := e.(Versioned); ok {
+		return v.EventVersion()
+	}
+
+	return 1
+}
+
+// NewEnvelope wraps e with a new ID.
+// The actor and the correlation ID are taken from ctx: from WithActor and WithCorrelationID,
+// or from the envelope of the event being handled, so the events published by a handler share its correlation ID.
+func NewEnvelope(ctx context.Context, e interface{}) *Envelope {
+	env := &Envelope{
+		ID:         uuid.New().String(),
+		Type:       Name(e),
+		Version:    Version(e),
+		OccurredAt: time.Now(),
+		Payload:    e,
+	}
+
+	if cause := EnvelopeFromContext(ctx); cause != nil {
+		env.ActorID = cause.ActorID
+		env.CorrelationID = cause.CorrelationID
+	}
+
+	if actorID, ok := ctx.Value(actorKey{}).(int); ok {
+		env.ActorID = actorID
+	}
+
+	if id, ok := ctx.Value(correlationKey{}).(string); ok && id != "" {
+		env.CorrelationID = id
+	}
+
+	if env.CorrelationID == "" {
+		env.CorrelationID = env.ID
+	}
+
+	return env
+}
+
+type (
+	envelopeKey    struct{}
+	actorKey       struct{}
+	correlationKey struct{}
+)
+
+// WithActor returns a context publishing events caused by the user
+func WithActor(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// WithCorrelationID returns a context publishing events with the correlation ID, such as the ID of a request
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// EnvelopeFromContext returns the envelope of the event given to a handler, or nil outside of handlers
+func EnvelopeFromContext(ctx context.Context) *Envelope {
+	env, _ := ctx.Value(envelopeKey{}).(*Envelope)
+	return env
+}
+
+// WithEnvelope returns a context handling the event of env, like the context given to handlers.
+// Events published with it are caused by the event of env.
+func WithEnvelope(ctx context.Context, env *Envelope) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, env)
+}
@@ -0,0 +1,54 @@
+package event
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type userActivated struct {
+	UserID int
+}
+
+func TestEnvelope(t *testing.T) {
+	b := NewBus()
+
+	received := make(chan *Envelope, 2)
+	_, _ = b.Handle(func(ctx context.Context, e UserRegistered) error {
+		env := EnvelopeFromContext(ctx)
+		received <- env
+
+		// events published by handlers are caused by the event being handled
+		b.PublishContext(ctx, userActivated{UserID: e.UserID})
+		return nil
+	}, nil)
+	_, _ = b.Handle(func(ctx context.Context, e userActivated) error {
+		received <- EnvelopeFromContext(ctx)
+		return nil
+	}, nil)
+
+	ctx := WithCorrelationID(WithActor(context.Background(), 1), "request-1")
+	b.PublishContext(ctx, UserRegistered{UserID: 1})
+
+	registered, activated := <-received, <-received
+	assert.Equal(t, UserRegistered{UserID: 1}, registered.Payload)
+	assert.Equal(t, Name(UserRegistered{}), registered.Type)
+	assert.Equal(t, 1, registered.Version)
+	assert.Equal(t, 1, registered.ActorID)
+	assert.Equal(t, "request-1", registered.CorrelationID)
+	assert.NotEqual(t, "", registered.ID)
+
+	assert.Equal(t, userActivated{UserID: 1}, activated.Payload)
+	assert.Equal(t, 1, activated.ActorID)
+	assert.Equal(t, "request-1", activated.CorrelationID)
+	assert.NotEqual(t, registered.ID, activated.ID)
+
+	b.Close()
+
+	t.Run("without context", func(t *testing.T) {
+		env := NewEnvelope(context.Background(), UserRegistered{})
+		assert.Equal(t, 0, env.ActorID)
+		assert.Equal(t, env.ID, env.CorrelationID)
+	})
+}
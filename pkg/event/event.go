@@ -1,29 +1,353 @@
 package event
 
 import (
+	"context"
+	"errors"
 	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Policy decides what happens when an event is published to a full subscription
+type Policy int
+
+const (
+	// Block waits for room in the buffer up to Options.Timeout, then drops the event
+	Block Policy = iota
+
+	// DropOldest drops the oldest buffered event to make room
+	DropOldest
+
+	// DropNewest drops the event being published
+	DropNewest
+)
+
+type Options struct {
+	// BufferSize is the number of events a subscriber can fall behind
+	BufferSize int
+
+	// Policy is applied when the buffer is full.
+	// Events dropped by handlers are dead letters, see Bus.OnDeadLetter.
+	Policy Policy
+
+	// Timeout is the longest time a publisher waits with the Block policy
+	Timeout time.Duration
+
+	// Name identifies a handler in dead letters and Redeliver, it defaults to the name of the function.
+	// Names of closures change with the code around them, so handlers which may fail should be named.
+	Name string
+
+	// Retry is the retry policy of a handler, events are not retried if nil
+	Retry *RetryPolicy
+}
+
+const (
+	defaultBufferSize = 64
+	defaultTimeout    = time.Second
+
+	// handlers mostly wait on the database or the mail server, so they are not bound by CPUs
+	defaultWorkers = 8
+)
+
+var (
+	ErrInvalidHandler = errors.New("handler must be a func(context.Context, Event) error")
+	ErrNotRegistered  = errors.New("event type not registered")
+	ErrBufferFull     = errors.New("subscription buffer full")
+)
+
+// Bus delivers events to subscribers of the same event type.
+// Types are compared as a whole, so events with the same name in different packages are distinct.
+// Publishers never wait on a subscriber longer than its Options allow.
 type Bus struct {
-	eventMap map[string][]chan<- interface{}
+	mu       sync.RWMutex
+	eventMap map[reflect.Type][]*Subscription
+	closed   bool
+
+	onDeadLetter func(l *DeadLetter)
+
+	// handlers run on the pool, which is started by the first Handle
+	poolOnce sync.Once
+	pool     *pool
+	handlers sync.WaitGroup
+}
+
+// Subscription receives events on its own buffered channel
+type Subscription struct {
+	bus       *Bus
+	eventType reflect.Type
+
+	// handler is set for subscriptions created by Handle
+	handler *handler
+
+	// mu serializes sends and closing the channel
+	mu      sync.Mutex
+	c       chan interface{} // *Envelope for handlers, the events themselves otherwise
+	closed  bool
+	dropped int64
+
+	policy  Policy
+	timeout time.Duration
 }
 
+// anyType is the key of handlers receiving every event
+var anyType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// Publish delivers e to every subscription of its type, then to handlers of every event.
+// e is wrapped in a new Envelope, unless it is one already.
+// Events published after the bus is closed are discarded.
 func (b *Bus) Publish(e interface{}) {
-	name := reflect.TypeOf(e).Name()
+	b.PublishContext(context.Background(), e)
+}
+
+// PublishContext is Publish with the actor and the correlation ID of ctx, see NewEnvelope
+func (b *Bus) PublishContext(ctx context.Context, e interface{}) {
+	env, ok := e.(*Envelope)
+	if !ok {
+		env = NewEnvelope(ctx, e)
+	}
+
+	b.mu.RLock()
+	subs := b.eventMap[reflect.TypeOf(env.Payload)]
+	all := b.eventMap[anyType]
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.deliver(env)
+	}
+
+	for _, sub := range all {
+		sub.deliver(env)
+	}
+}
+
+// Subscribe returns a subscription for events of the same type as e.
+// opts may be nil for the defaults: a buffer of 64 events, blocking for up to a second.
+// Subscribing to a closed bus returns a subscription whose channel is already closed.
+func (b *Bus) Subscribe(e interface{}, opts *Options) *Subscription {
+	sub := newSubscription(b, reflect.TypeOf(e), opts)
+	b.add(sub)
+
+	return sub
+}
+
+func newSubscription(b *Bus, eventType reflect.Type, opts *Options) *Subscription {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	sub := &Subscription{
+		bus:       b,
+		eventType: eventType,
+		policy:    opts.Policy,
+		timeout:   opts.Timeout,
+	}
+
+	size := opts.BufferSize
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+	sub.c = make(chan interface{}, size)
+
+	if sub.timeout <= 0 {
+		sub.timeout = defaultTimeout
+	}
+
+	return sub
+}
+
+// add registers sub, it returns false and closes sub if the bus is closed
+func (b *Bus) add(sub *Subscription) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		// nothing to drain, the handler never runs
+		sub.handler = nil
+		sub.close()
+		return false
+	}
 
-	for _, c := range b.eventMap[name] {
-		c <- e
+	if sub.handler != nil {
+		b.handlers.Add(1)
 	}
+
+	// copy on write, Publish iterates over the old slice without holding the lock
+	old := b.eventMap[sub.eventType]
+	subs := make([]*Subscription, len(old), len(old)+1)
+	copy(subs, old)
+	b.eventMap[sub.eventType] = append(subs, sub)
+
+	return true
 }
 
-func (b *Bus) Subscribe(e interface{}, c chan<- interface{}) {
-	name := reflect.TypeOf(e).Name()
+// Close stops all deliveries.
+// Events already buffered are still received, then every subscription channel is closed.
+// Close waits until handlers have processed their buffered events,
+// so it must not be called from a handler. Failed events are not retried after Close, they are dead letters.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+
+	b.closed = true
+	eventMap := b.eventMap
+	b.eventMap = make(map[reflect.Type][]*Subscription)
+	b.mu.Unlock()
 
-	b.eventMap[name] = append(b.eventMap[name], c)
+	for _, subs := range eventMap {
+		for _, sub := range subs {
+			sub.close()
+		}
+	}
+
+	b.handlers.Wait()
+	if b.pool != nil {
+		b.pool.close()
+	}
+}
+
+func (b *Bus) remove(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	old := b.eventMap[sub.eventType]
+	subs := make([]*Subscription, 0, len(old))
+	for _, s := range old {
+		if s != sub {
+			subs = append(subs, s)
+		}
+	}
+
+	if len(subs) == 0 {
+		delete(b.eventMap, sub.eventType)
+		return
+	}
+
+	b.eventMap[sub.eventType] = subs
+}
+
+// C returns the channel events are received on, without their envelopes.
+// It is closed after Unsubscribe or Bus.Close.
+// Subscriptions created by Handle are read by their handler and must not be read directly.
+func (s *Subscription) C() <-chan interface{} {
+	return s.c
+}
+
+// Unsubscribe stops deliveries to the subscription, it is safe to call more than once
+func (s *Subscription) Unsubscribe() {
+	s.bus.remove(s)
+	s.close()
+}
+
+// Dropped returns the number of events dropped because the subscription was full
+func (s *Subscription) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+func (s *Subscription) deliver(env *Envelope) {
+	if s.handler == nil {
+		s.send(env.Payload)
+		return
+	}
+
+	// handlers give the envelope to their func in the context
+	dropped := s.send(env)
+	s.handler.schedule()
+
+	// a handler must not lose events silently, they can be redelivered from the dead letters
+	if dropped != nil {
+		s.bus.deadLetter(&DeadLetter{
+			Handler:  s.handler.name,
+			Event:    dropped.(*Envelope).Payload,
+			Envelope: dropped.(*Envelope),
+			Err:      ErrBufferFull,
+		})
+	}
+}
+
+// send returns the event dropped because the buffer is full, if any
+func (s *Subscription) send(e interface{}) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	select {
+	case s.c <- e:
+		return nil
+	default:
+	}
+
+	switch s.policy {
+	case DropOldest:
+		// the subscriber may have read in the meantime, so the receive must not block
+		var dropped interface{}
+		select {
+		case dropped = <-s.c:
+			atomic.AddInt64(&s.dropped, 1)
+		default:
+		}
+		s.c <- e
+		return dropped
+
+	case DropNewest:
+		atomic.AddInt64(&s.dropped, 1)
+		return e
+
+	default:
+		timer := time.NewTimer(s.timeout)
+		defer timer.Stop()
+
+		select {
+		case s.c <- e:
+			return nil
+		case <-timer.C:
+			atomic.AddInt64(&s.dropped, 1)
+			return e
+		}
+	}
+}
+
+func (s *Subscription) close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+
+	s.closed = true
+	close(s.c)
+	s.mu.Unlock()
+
+	// let the handler see the channel is closed, without waiting for a retry
+	if s.handler != nil {
+		s.handler.schedule()
+		s.handler.retryNow()
+	}
+}
+
+func (s *Subscription) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
 }
 
 func NewBus() *Bus {
 	return &Bus{
-		eventMap: make(map[string][]chan<- interface{}),
+		eventMap: make(map[reflect.Type][]*Subscription),
 	}
 }
+
+func (b *Bus) startPool() *pool {
+	b.poolOnce.Do(func() {
+		b.pool = newPool(defaultWorkers)
+	})
+
+	return b.pool
+}
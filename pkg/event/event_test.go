@@ -5,6 +5,7 @@ import (
 	"github.com/go-playground/assert/v2"
 	"sync"
 	"testing"
+	"time"
 )
 
 type UserRegistered struct {
@@ -55,27 +56,117 @@ func TestBus(t *testing.T) {
 	})
 }
 
-type listener struct {
-	c chan interface{}
+func TestBus_Policy(t *testing.T) {
+	tests := map[string]struct {
+		policy Policy
+		wanted []interface{}
+	}{
+		"drop oldest": {DropOldest, []interface{}{UserRegistered{UserID: 2}, UserRegistered{UserID: 3}}},
+		"drop newest": {DropNewest, []interface{}{UserRegistered{UserID: 1}, UserRegistered{UserID: 2}}},
+		"block":       {Block, []interface{}{UserRegistered{UserID: 1}, UserRegistered{UserID: 2}}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := NewBus()
+			sub := b.Subscribe(UserRegistered{}, &Options{BufferSize: 2, Policy: test.policy, Timeout: 10 * time.Millisecond})
+
+			for i := 1; i <= 3; i++ {
+				b.Publish(UserRegistered{UserID: i})
+			}
+			b.Close()
+
+			var received []interface{}
+			for e := range sub.C() {
+				received = append(received, e)
+			}
+
+			assert.Equal(t, test.wanted, received)
+			assert.Equal(t, int64(1), sub.Dropped())
+		})
+	}
+}
+
+func TestBus_BlockUntilRead(t *testing.T) {
+	b := NewBus()
+	sub := b.Subscribe(UserRegistered{}, &Options{BufferSize: 1, Timeout: time.Second})
+
+	b.Publish(UserRegistered{UserID: 1})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-sub.C()
+	}()
+	b.Publish(UserRegistered{UserID: 2})
+
+	assert.Equal(t, UserRegistered{UserID: 2}, <-sub.C())
+	assert.Equal(t, int64(0), sub.Dropped())
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	b := NewBus()
+	sub := b.Subscribe(UserRegistered{}, nil)
+	other := b.Subscribe(UserRegistered{}, nil)
 
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	b.Publish(UserRegistered{UserID: 1})
+
+	_, ok := <-sub.C()
+	assert.Equal(t, false, ok)
+	assert.Equal(t, UserRegistered{UserID: 1}, <-other.C())
+}
+
+func TestBus_Close(t *testing.T) {
+	b := NewBus()
+	sub := b.Subscribe(UserRegistered{}, nil)
+
+	b.Publish(UserRegistered{UserID: 1})
+	b.Close()
+	b.Publish(UserRegistered{UserID: 2})
+
+	assert.Equal(t, UserRegistered{UserID: 1}, <-sub.C())
+	_, ok := <-sub.C()
+	assert.Equal(t, false, ok)
+
+	_, ok = <-b.Subscribe(UserRegistered{}, nil).C()
+	assert.Equal(t, false, ok)
+}
+
+func TestBus_Concurrent(t *testing.T) {
+	b := NewBus()
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			sub := b.Subscribe(UserRegistered{}, &Options{Policy: DropNewest})
+			sub.Unsubscribe()
+		}()
+		go func() {
+			defer wg.Done()
+			b.Publish(UserRegistered{UserID: 1})
+		}()
+	}
+
+	wg.Wait()
+	b.Close()
+}
+
+type listener struct {
 	receivedEvents []interface{}
 }
 
 func newListener() *listener {
-	return &listener{
-		c: make(chan interface{}),
-	}
+	return &listener{}
 }
 
 func (l *listener) doSubscribe(b *Bus, e interface{}, wg *sync.WaitGroup) {
-	b.Subscribe(e, l.c)
+	sub := b.Subscribe(e, nil)
 	go func() {
-		for {
-			select {
-			case received := <-l.c:
-				l.receivedEvents = append(l.receivedEvents, received)
-				wg.Done()
-			}
+		for received := range sub.C() {
+			l.receivedEvents = append(l.receivedEvents, received)
+			wg.Done()
 		}
 	}()
 }
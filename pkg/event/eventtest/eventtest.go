@@ -0,0 +1,127 @@
+// Package eventtest checks that events survive being stored
+package eventtest
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/victornm/es-backend/pkg/event"
+)
+
+// AssertRoundTrip encodes every type registered in r in an envelope and decodes it back.
+// Exported fields are filled with values which are not zero, so fields lost by the JSON encoding,
+// for example because of a custom MarshalJSON, make the test fail. Fields tagged `json:"-"` are not stored and stay zero.
+// Old versions are checked to decode into the current version, through their upcasters.
+// Current types must declare a stable name, see event.Named.
+func AssertRoundTrip(t testing.TB, r *event.Registry) {
+	t.Helper()
+
+	for _, v := range r.Versions() {
+		if _, ok := v.Event.(event.Named); v.Current && !ok {
+			t.Errorf("%s does not declare a stable name", v.Name)
+		}
+
+		e := sample(reflect.TypeOf(v.Event), 0).Interface()
+		env := &event.Envelope{
+			ID:            "d3b07384-d9a0-4c9b-8f3e-1a2b3c4d5e6f",
+			Type:          v.Name,
+			Version:       v.Version,
+			OccurredAt:    sampleTime,
+			ActorID:       1,
+			CorrelationID: "f1d2d2f9-24e6-4a1f-9c6b-6e5d4c3b2a19",
+			Payload:       e,
+		}
+
+		data, err := json.Marshal(env)
+		if !assert.NoError(t, err, "encode %s version %d", v.Name, v.Version) {
+			continue
+		}
+
+		got, err := r.Unmarshal(data)
+		if !assert.NoError(t, err, "decode %s version %d", v.Name, v.Version) {
+			continue
+		}
+
+		if v.Current {
+			assert.Equal(t, env, got, "%s version %d does not round-trip", v.Name, v.Version)
+			continue
+		}
+
+		current, _ := r.New(v.Name)
+		assert.IsType(t, reflect.ValueOf(current).Elem().Interface(), got.Payload,
+			"%s version %d is not upcast to the current version", v.Name, v.Version)
+	}
+}
+
+var (
+	sampleTime     = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	timeType       = reflect.TypeOf(time.Time{})
+	rawType        = reflect.TypeOf(json.RawMessage{})
+	maxSampleDepth = 8
+)
+
+// sample returns a value of t without zero fields, as far as JSON can encode it
+func sample(t reflect.Type, depth int) reflect.Value {
+	v := reflect.New(t).Elem()
+	if depth > maxSampleDepth {
+		return v
+	}
+
+	switch {
+	case t == timeType:
+		v.Set(reflect.ValueOf(sampleTime))
+		return v
+
+	case t == rawType:
+		v.SetBytes([]byte(`"raw"`))
+		return v
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString("sample")
+
+	case reflect.Bool:
+		v.SetBool(true)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+
+	case reflect.Ptr:
+		v.Set(sample(t.Elem(), depth+1).Addr())
+
+	case reflect.Slice:
+		v.Set(reflect.Append(v, sample(t.Elem(), depth+1)))
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			v.Index(i).Set(sample(t.Elem(), depth+1))
+		}
+
+	case reflect.Map:
+		v.Set(reflect.MakeMap(t))
+		v.SetMapIndex(sample(t.Key(), depth+1), sample(t.Elem(), depth+1))
+
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" || f.Tag.Get("json") == "-" {
+				continue
+			}
+
+			v.Field(i).Set(sample(f.Type, depth+1))
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,260 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Handle subscribes fn to the event type of its second parameter.
+// fn must be a func(context.Context, Event) error, for example
+//
+//	b.Handle(func(ctx context.Context, e UserRegistered) error { ... }, nil)
+//
+// A func(context.Context, interface{}) error receives every event published on the bus.
+// Handlers run on a pool of workers shared by the bus.
+// Different handlers run concurrently, but each handler receives its events one by one,
+// in the order they were published. Errors and panics are logged and do not stop the handler.
+//
+// Failed events are retried with the retry policy of opts, if any.
+// Events which failed every attempt, and events the handler panicked on, are dead letters:
+// they are not retried and are passed to the function set by OnDeadLetter.
+func (b *Bus) Handle(fn interface{}, opts *Options) (*Subscription, error) {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func ||
+		t.NumIn() != 2 || t.In(0) != contextType || (t.In(1).Kind() == reflect.Interface && t.In(1) != anyType) ||
+		t.NumOut() != 1 || t.Out(0) != errorType {
+		return nil, errorutil.Wrap(ErrInvalidHandler, "got %v", t)
+	}
+
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = runtime.FuncForPC(v.Pointer()).Name()
+	}
+
+	sub := newSubscription(b, t.In(1), opts)
+	sub.handler = &handler{
+		sub:   sub,
+		fn:    v,
+		name:  name,
+		retry: opts.Retry,
+		pool:  b.startPool(),
+		done:  b.handlers.Done,
+	}
+	b.add(sub)
+
+	return sub, nil
+}
+
+type handler struct {
+	sub   *Subscription
+	fn    reflect.Value
+	name  string
+	retry *RetryPolicy
+	pool  *pool
+
+	// done is called once the subscription is closed and drained
+	done func()
+
+	mu sync.Mutex
+	// scheduled is true while the handler is queued or running,
+	// so a handler never runs on two workers at once
+	scheduled bool
+	// dirty is set when events arrive after the handler started running
+	dirty bool
+
+	// retryTimer is set while a failed event waits for its retry
+	retryTimer *time.Timer
+
+	// failed is the event being retried, it is only used by the running worker
+	failed   *Envelope
+	attempts int
+}
+
+func (h *handler) schedule() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.dirty = true
+	if h.scheduled {
+		return
+	}
+
+	h.scheduled = true
+	h.pool.push(h)
+}
+
+// retryNow runs a waiting retry right away
+func (h *handler) retryNow() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the timer may have fired already
+	if h.retryTimer == nil || !h.retryTimer.Stop() {
+		return
+	}
+
+	h.retryTimer = nil
+	h.pool.push(h)
+}
+
+// run handles a single event, then queues the handler again if more are waiting,
+// so busy handlers take turns with the others
+func (h *handler) run() {
+	h.mu.Lock()
+	h.dirty = false
+	h.mu.Unlock()
+
+	e, ok := h.failed, h.attempts > 0
+	if !ok {
+		select {
+		case next, open := <-h.sub.c:
+			if !open {
+				h.done()
+				return
+			}
+			e, ok = next.(*Envelope), true
+		default:
+		}
+	}
+
+	if ok {
+		if delay, retry := h.handle(e); retry {
+			// the handler stays scheduled, so its other events wait for the retry
+			h.mu.Lock()
+			h.retryTimer = time.AfterFunc(delay, func() {
+				h.mu.Lock()
+				h.retryTimer = nil
+				h.mu.Unlock()
+
+				h.pool.push(h)
+			})
+			h.mu.Unlock()
+			return
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// a closed channel must be read once more, even when it is empty, to see it is closed
+	if h.dirty || len(h.sub.c) > 0 || h.sub.isClosed() {
+		h.pool.push(h)
+		return
+	}
+
+	h.scheduled = false
+}
+
+// handle calls the handler with e, it returns true and the delay before the next attempt if e should be retried
+func (h *handler) handle(e *Envelope) (time.Duration, bool) {
+	h.attempts++
+	poison, err := h.call(e)
+	if err == nil {
+		h.failed, h.attempts = nil, 0
+		return 0, false
+	}
+
+	// events are not retried once the bus is closed, so Close does not wait for every retry
+	if !poison && h.retry != nil && h.attempts < h.retry.MaxAttempts && !h.sub.isClosed() {
+		h.failed = e
+		return h.retry.delay(h.attempts), true
+	}
+
+	h.sub.bus.deadLetter(&DeadLetter{Handler: h.name, Event: e.Payload, Envelope: e, Attempts: h.attempts, Err: err})
+	h.failed, h.attempts = nil, 0
+
+	return 0, false
+}
+
+// call returns the error of the handler.
+// A panic is returned as a poison error: the event is likely to make the handler panic again.
+func (h *handler) call(e *Envelope) (poison bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("event handler %s panicked on %T: %v", h.name, e.Payload, r)
+			poison, err = true, fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	ctx := WithEnvelope(context.Background(), e)
+	out := h.fn.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(e.Payload)})
+	if err, _ := out[0].Interface().(error); err != nil {
+		log.Printf("event handler %s failed on %T (attempt %d): %v", h.name, e.Payload, h.attempts, err)
+		return false, err
+	}
+
+	return false, nil
+}
+
+// pool runs queued handlers on a fixed number of workers
+type pool struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []*handler
+	closed bool
+}
+
+func newPool(workers int) *pool {
+	p := &pool{}
+	p.cond = sync.NewCond(&p.mu)
+
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+
+	return p
+}
+
+func (p *pool) push(h *handler) {
+	p.mu.Lock()
+	p.queue = append(p.queue, h)
+	p.mu.Unlock()
+
+	p.cond.Signal()
+}
+
+func (p *pool) work() {
+	for {
+		p.mu.Lock()
+		for len(p.queue) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+
+		if len(p.queue) == 0 {
+			p.mu.Unlock()
+			return
+		}
+
+		h := p.queue[0]
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		h.run()
+	}
+}
+
+// close stops the workers once the queue is empty
+func (p *pool) close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	p.cond.Broadcast()
+}
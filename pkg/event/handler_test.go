@@ -0,0 +1,158 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestHandle(t *testing.T) {
+	b := NewBus()
+
+	var mu sync.Mutex
+	var received []int
+	_, err := b.Handle(func(ctx context.Context, e UserRegistered) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, e.UserID)
+		return nil
+	}, &Options{BufferSize: 100})
+	assert.Equal(t, nil, err)
+
+	for i := 1; i <= 100; i++ {
+		b.Publish(UserRegistered{UserID: i})
+	}
+	b.Close()
+
+	assert.Equal(t, 100, len(received))
+	for i, id := range received {
+		assert.Equal(t, i+1, id)
+	}
+}
+
+func TestHandle_Any(t *testing.T) {
+	type UserActivated struct {
+		UserID int
+	}
+
+	b := NewBus()
+
+	var received []interface{}
+	_, err := b.Handle(func(ctx context.Context, e interface{}) error {
+		received = append(received, e)
+		return nil
+	}, nil)
+	assert.Equal(t, nil, err)
+
+	b.Publish(UserRegistered{UserID: 1})
+	b.Publish(UserActivated{UserID: 1})
+	b.Close()
+
+	assert.Equal(t, []interface{}{UserRegistered{UserID: 1}, UserActivated{UserID: 1}}, received)
+}
+
+func TestHandle_DistinctTypes(t *testing.T) {
+	// same name and package as the UserRegistered of the other tests, but a different type
+	type UserRegistered struct {
+		UserID int
+	}
+
+	b := NewBus()
+	sub := b.Subscribe(UserRegistered{}, nil)
+
+	b.Publish(UserRegistered{UserID: 1})
+	b.Publish(userRegistered(2))
+	b.Close()
+
+	var received []interface{}
+	for e := range sub.C() {
+		received = append(received, e)
+	}
+
+	assert.Equal(t, []interface{}{UserRegistered{UserID: 1}}, received)
+}
+
+func userRegistered(id int) interface{} {
+	return UserRegistered{UserID: id}
+}
+
+func TestHandle_PanicAndError(t *testing.T) {
+	b := NewBus()
+
+	var handled int
+	_, _ = b.Handle(func(ctx context.Context, e UserRegistered) error {
+		handled++
+		switch e.UserID {
+		case 1:
+			panic("boom")
+		case 2:
+			return errors.New("failed")
+		}
+		return nil
+	}, nil)
+
+	b.Publish(UserRegistered{UserID: 1})
+	b.Publish(UserRegistered{UserID: 2})
+	b.Publish(UserRegistered{UserID: 3})
+	b.Close()
+
+	assert.Equal(t, 3, handled)
+}
+
+func TestHandle_Concurrent(t *testing.T) {
+	b := NewBus()
+
+	// each handler blocks until the other has started, so they must run on different workers
+	first, second := make(chan struct{}), make(chan struct{})
+	_, _ = b.Handle(func(ctx context.Context, e UserRegistered) error {
+		close(first)
+		<-second
+		return nil
+	}, nil)
+	_, _ = b.Handle(func(ctx context.Context, e UserRegistered) error {
+		close(second)
+		<-first
+		return nil
+	}, nil)
+
+	b.Publish(UserRegistered{UserID: 1})
+	b.Close()
+}
+
+func TestHandle_Unsubscribe(t *testing.T) {
+	b := NewBus()
+
+	var handled int
+	sub, _ := b.Handle(func(ctx context.Context, e UserRegistered) error {
+		handled++
+		return nil
+	}, nil)
+
+	sub.Unsubscribe()
+	b.Publish(UserRegistered{UserID: 1})
+	b.Close()
+
+	assert.Equal(t, 0, handled)
+}
+
+func TestHandle_InvalidHandler(t *testing.T) {
+	tests := map[string]interface{}{
+		"not a func":        UserRegistered{},
+		"no context":        func(e UserRegistered) error { return nil },
+		"no error":          func(ctx context.Context, e UserRegistered) {},
+		"interface event":   func(ctx context.Context, e fmt.Stringer) error { return nil },
+		"too many argument": func(ctx context.Context, e UserRegistered, n int) error { return nil },
+	}
+
+	for name, fn := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := NewBus()
+			_, err := b.Handle(fn, nil)
+			assert.Equal(t, true, errors.Is(err, ErrInvalidHandler))
+		})
+	}
+}
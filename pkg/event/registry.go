@@ -0,0 +1,202 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+)
+
+// Upcaster converts an event to the next version of its type, for example
+//
+//	func(e interface{}) (interface{}, error) {
+//		old := e.(UserRegisteredV1)
+//		return UserRegistered{UserID: old.ID}, nil
+//	}
+type Upcaster func(e interface{}) (interface{}, error)
+
+// Registry maps the names and versions of event types to the types, to decode stored events.
+// Events are registered at startup, a Registry is not safe for concurrent Register.
+type Registry struct {
+	types     map[versionKey]reflect.Type
+	upcasters map[versionKey]Upcaster
+
+	// current is the version of the registered type of each name
+	current map[string]int
+
+	// aliases are the names of Go types of Named events, which were stored before the events declared a name
+	aliases map[string]string
+}
+
+type versionKey struct {
+	name    string
+	version int
+}
+
+func NewRegistry(events ...interface{}) *Registry {
+	r := &Registry{
+		types:     make(map[versionKey]reflect.Type),
+		upcasters: make(map[versionKey]Upcaster),
+		current:   make(map[string]int),
+		aliases:   make(map[string]string),
+	}
+	r.Register(events...)
+
+	return r
+}
+
+// Register adds the current types of events, given as zero values
+func (r *Registry) Register(events ...interface{}) {
+	for _, e := range events {
+		t := reflect.TypeOf(e)
+		name, version := Name(e), Version(e)
+		r.types[versionKey{name, version}] = t
+		r.current[name] = version
+
+		if goName := typeName(t); goName != name {
+			r.aliases[goName] = name
+		}
+	}
+}
+
+// RegisterVersion adds an old version of the named event type, decoded into the type of e.
+// up converts it to the next version, events are upcast one version at a time up to the current one.
+func (r *Registry) RegisterVersion(name string, version int, e interface{}, up Upcaster) {
+	key := versionKey{r.resolve(name), version}
+	r.types[key] = reflect.TypeOf(e)
+	r.upcasters[key] = up
+}
+
+// New returns a pointer to a zero value of the current version of the named type
+func (r *Registry) New(name string) (interface{}, bool) {
+	name = r.resolve(name)
+	version, ok := r.current[name]
+	if !ok {
+		return nil, false
+	}
+
+	return reflect.New(r.types[versionKey{name, version}]).Interface(), true
+}
+
+// Decode returns the JSON payload of the version of the named type, upcast to the current version.
+// Version 0 is version 1, which is the version of events stored before versions existed.
+func (r *Registry) Decode(name string, version int, payload []byte) (interface{}, error) {
+	if version == 0 {
+		version = 1
+	}
+
+	name = r.resolve(name)
+	current, ok := r.current[name]
+	if !ok {
+		return nil, errorutil.Wrap(ErrNotRegistered, name)
+	}
+
+	t, ok := r.types[versionKey{name, version}]
+	if !ok {
+		return nil, errorutil.Wrap(ErrNotRegistered, "%s version %d", name, version)
+	}
+
+	ptr := reflect.New(t)
+	if err := json.Unmarshal(payload, ptr.Interface()); err != nil {
+		return nil, err
+	}
+
+	e := ptr.Elem().Interface()
+	for ; version < current; version++ {
+		up := r.upcasters[versionKey{name, version}]
+		if up == nil {
+			return nil, errorutil.Wrap(ErrNotRegistered, "%s version %d has no upcaster", name, version)
+		}
+
+		var err error
+		if e, err = up(e); err != nil {
+			return nil, fmt.Errorf("upcast %s from version %d: %w", name, version, err)
+		}
+	}
+
+	if reflect.TypeOf(e) != r.types[versionKey{name, current}] {
+		return nil, fmt.Errorf("upcast %s to version %d returned %T", name, current, e)
+	}
+
+	return e, nil
+}
+
+// Unmarshal decodes an envelope encoded as JSON, its payload is upcast to the current version
+func (r *Registry) Unmarshal(data []byte) (*Envelope, error) {
+	var raw struct {
+		Envelope
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	e, err := r.Decode(raw.Type, raw.Version, raw.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	env := raw.Envelope
+	env.Type = r.resolve(raw.Type)
+	env.Version = r.current[env.Type]
+	env.Payload = e
+
+	return &env, nil
+}
+
+// resolve returns the name registered for name, which may be the name of the Go type of a Named event
+func (r *Registry) resolve(name string) string {
+	if registered, ok := r.aliases[name]; ok {
+		return registered
+	}
+
+	return name
+}
+
+// Versions returns zero values of every registered type and the version they decode, sorted by name and version
+func (r *Registry) Versions() []RegisteredVersion {
+	versions := make([]RegisteredVersion, 0, len(r.types))
+	for key, t := range r.types {
+		versions = append(versions, RegisteredVersion{
+			Name:    key.name,
+			Version: key.version,
+			Current: r.current[key.name] == key.version,
+			Event:   reflect.Zero(t).Interface(),
+		})
+	}
+
+	sort.Slice(versions, func(i, k int) bool {
+		if versions[i].Name != versions[k].Name {
+			return versions[i].Name < versions[k].Name
+		}
+
+		return versions[i].Version < versions[k].Version
+	})
+
+	return versions
+}
+
+type RegisteredVersion struct {
+	Name    string
+	Version int
+
+	// Current is false for old versions, which are upcast when they are decoded
+	Current bool
+	Event   interface{}
+}
+
+// Name returns the name of e declared by Named,
+// or else the fully qualified name of its type, for example github.com/victornm/es-backend/pkg/auth.UserRegistered
+func Name(e interface{}) string {
+	if n, ok := e.(Named); ok {
+		return n.EventName()
+	}
+
+	return typeName(reflect.TypeOf(e))
+}
+
+func typeName(t reflect.Type) string {
+	return t.PkgPath() + "." + t.Name()
+}
@@ -0,0 +1,94 @@
+package event
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(UserRegistered{})
+
+	name := Name(UserRegistered{})
+	assert.Equal(t, "github.com/victornm/es-backend/pkg/event.UserRegistered", name)
+
+	e, ok := r.New(name)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, &UserRegistered{}, e)
+
+	_, ok = r.New("github.com/victornm/es-backend/pkg/event.UserActivated")
+	assert.Equal(t, false, ok)
+}
+
+type userFollowed struct {
+	FollowerID int
+}
+
+func (userFollowed) EventName() string { return "user.followed" }
+
+func TestRegistry_Named(t *testing.T) {
+	r := NewRegistry(userFollowed{})
+	assert.Equal(t, "user.followed", Name(userFollowed{}))
+
+	e, ok := r.New("user.followed")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, &userFollowed{}, e)
+
+	// stored before the event declared its name
+	env, err := r.Unmarshal([]byte(`{"id": "1", "type": "github.com/victornm/es-backend/pkg/event.userFollowed", "payload": {"FollowerID": 1}}`))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "user.followed", env.Type)
+	assert.Equal(t, userFollowed{FollowerID: 1}, env.Payload)
+}
+
+type UserRegisteredV1 struct {
+	ID int
+}
+
+type accountCreated struct {
+	UserID int
+	Email  string
+}
+
+func (accountCreated) EventVersion() int { return 3 }
+
+type accountCreatedV2 struct {
+	UserID int
+}
+
+func TestRegistry_Decode(t *testing.T) {
+	name := Name(accountCreated{})
+	r := NewRegistry(accountCreated{})
+	r.RegisterVersion(name, 1, UserRegisteredV1{}, func(e interface{}) (interface{}, error) {
+		return accountCreatedV2{UserID: e.(UserRegisteredV1).ID}, nil
+	})
+	r.RegisterVersion(name, 2, accountCreatedV2{}, func(e interface{}) (interface{}, error) {
+		return accountCreated{UserID: e.(accountCreatedV2).UserID, Email: "unknown"}, nil
+	})
+
+	for version, payload := range map[int]string{
+		0: `{"ID": 1}`,
+		1: `{"ID": 1}`,
+		2: `{"UserID": 1}`,
+		3: `{"UserID": 1, "Email": "unknown"}`,
+	} {
+		e, err := r.Decode(name, version, []byte(payload))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, accountCreated{UserID: 1, Email: "unknown"}, e)
+	}
+
+	_, err := r.Decode(name, 4, []byte(`{}`))
+	assert.Equal(t, true, errors.Is(err, ErrNotRegistered))
+
+	_, err = r.Decode(Name(UserRegistered{}), 1, []byte(`{}`))
+	assert.Equal(t, true, errors.Is(err, ErrNotRegistered))
+
+	t.Run("envelope", func(t *testing.T) {
+		env, err := r.Unmarshal([]byte(`{"id": "1", "type": "` + name + `", "version": 2, "actor_id": 1, "correlation_id": "1", "payload": {"UserID": 1}}`))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 3, env.Version)
+		assert.Equal(t, 1, env.ActorID)
+		assert.Equal(t, accountCreated{UserID: 1, Email: "unknown"}, env.Payload)
+	})
+}
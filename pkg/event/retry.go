@@ -0,0 +1,105 @@
+package event
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"time"
+)
+
+// RetryPolicy retries events a handler failed on, with an exponential backoff.
+// While an event is retried, the later events of the handler wait, so they are still handled in order.
+type RetryPolicy struct {
+	// MaxAttempts is the number of calls before an event is dead, including the first one
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, it doubles on each retry up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+const (
+	defaultBackoff    = time.Second
+	defaultMaxBackoff = time.Minute
+)
+
+// delay returns the delay after the given number of failed attempts
+func (p *RetryPolicy) delay(attempts int) time.Duration {
+	delay, max := p.Backoff, p.MaxBackoff
+	if delay <= 0 {
+		delay = defaultBackoff
+	}
+
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+
+	if delay > max {
+		return max
+	}
+
+	return delay
+}
+
+// DeadLetter is an event a handler failed on every attempt,
+// or dropped without an attempt because the handler fell too far behind
+type DeadLetter struct {
+	Handler  string // Options.Name of the handler
+	Event    interface{}
+	Envelope *Envelope
+	Attempts int
+	Err      error
+}
+
+// OnDeadLetter sets the function receiving dead letters, for example to store them.
+// It is called by the handler which failed, or by the publisher of a dropped event, so it should not take long.
+// Without it, dead letters are only logged.
+func (b *Bus) OnDeadLetter(f func(l *DeadLetter)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onDeadLetter = f
+}
+
+// Redeliver sends e again to the handlers with the given name, but not to other subscribers.
+// e is wrapped in a new Envelope, unless it is one already.
+// It returns the number of handlers e was sent to.
+func (b *Bus) Redeliver(handler string, e interface{}) int {
+	env, ok := e.(*Envelope)
+	if !ok {
+		env = NewEnvelope(context.Background(), e)
+	}
+
+	b.mu.RLock()
+	subs := b.eventMap[reflect.TypeOf(env.Payload)]
+	all := b.eventMap[anyType]
+	b.mu.RUnlock()
+
+	n := 0
+	for _, subs := range [][]*Subscription{subs, all} {
+		for _, sub := range subs {
+			if sub.handler != nil && sub.handler.name == handler {
+				sub.deliver(env)
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
+func (b *Bus) deadLetter(l *DeadLetter) {
+	log.Printf("event handler %s gave up on %T after %d attempts: %v", l.Handler, l.Event, l.Attempts, l.Err)
+
+	b.mu.RLock()
+	f := b.onDeadLetter
+	b.mu.RUnlock()
+
+	if f != nil {
+		f(l)
+	}
+}
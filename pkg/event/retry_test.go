@@ -0,0 +1,178 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestHandle_Retry(t *testing.T) {
+	b := NewBus()
+
+	var received []int
+	done := make(chan struct{})
+	failures := 2
+	_, _ = b.Handle(func(ctx context.Context, e UserRegistered) error {
+		received = append(received, e.UserID)
+		if e.UserID == 1 && failures > 0 {
+			failures--
+			return errors.New("failed")
+		}
+
+		if e.UserID == 2 {
+			close(done)
+		}
+		return nil
+	}, &Options{Retry: &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}})
+
+	var dead []*DeadLetter
+	b.OnDeadLetter(func(l *DeadLetter) {
+		dead = append(dead, l)
+	})
+
+	b.Publish(UserRegistered{UserID: 1})
+	b.Publish(UserRegistered{UserID: 2})
+	<-done
+	b.Close()
+
+	assert.Equal(t, []int{1, 1, 1, 2}, received)
+	assert.Equal(t, 0, len(dead))
+}
+
+func TestHandle_DeadLetter(t *testing.T) {
+	b := NewBus()
+
+	dead := make(chan *DeadLetter, 10)
+	b.OnDeadLetter(func(l *DeadLetter) {
+		dead <- l
+	})
+
+	var handled []int
+	_, _ = b.Handle(func(ctx context.Context, e UserRegistered) error {
+		handled = append(handled, e.UserID)
+		switch e.UserID {
+		case 1:
+			return errors.New("failed")
+		case 2:
+			panic("boom")
+		}
+		return nil
+	}, &Options{Name: "test", Retry: &RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}})
+
+	b.Publish(UserRegistered{UserID: 1})
+	b.Publish(UserRegistered{UserID: 2})
+	b.Publish(UserRegistered{UserID: 3})
+
+	// wait for the retry, Close stops retrying
+	l := <-dead
+	b.Close()
+
+	assert.Equal(t, []int{1, 1, 2, 3}, handled)
+	assert.Equal(t, "test", l.Handler)
+	assert.Equal(t, UserRegistered{UserID: 1}, l.Event)
+	assert.Equal(t, 2, l.Attempts)
+	assert.Equal(t, "failed", l.Err.Error())
+
+	// a panic is a poison message, it is not retried
+	l = <-dead
+	assert.Equal(t, UserRegistered{UserID: 2}, l.Event)
+	assert.Equal(t, 1, l.Attempts)
+	assert.Equal(t, 0, len(dead))
+}
+
+func TestHandle_DeadLetterOnClose(t *testing.T) {
+	b := NewBus()
+
+	var dead []*DeadLetter
+	b.OnDeadLetter(func(l *DeadLetter) {
+		dead = append(dead, l)
+	})
+
+	_, _ = b.Handle(func(ctx context.Context, e UserRegistered) error {
+		return errors.New("failed")
+	}, &Options{Retry: &RetryPolicy{MaxAttempts: 100, Backoff: time.Hour}})
+
+	b.Publish(UserRegistered{UserID: 1})
+	b.Publish(UserRegistered{UserID: 2})
+	time.Sleep(10 * time.Millisecond)
+
+	// the first event is waiting for its retry, Close must not wait for an hour
+	b.Close()
+
+	assert.Equal(t, 2, len(dead))
+	assert.Equal(t, UserRegistered{UserID: 1}, dead[0].Event)
+	assert.Equal(t, 2, dead[0].Attempts)
+}
+
+func TestRedeliver(t *testing.T) {
+	b := NewBus()
+
+	var mu sync.Mutex
+	received := map[string][]interface{}{}
+	handler := func(name string) func(context.Context, UserRegistered) error {
+		return func(ctx context.Context, e UserRegistered) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received[name] = append(received[name], e)
+			return nil
+		}
+	}
+
+	_, _ = b.Handle(handler("a"), &Options{Name: "a"})
+	_, _ = b.Handle(handler("b"), &Options{Name: "b"})
+	sub := b.Subscribe(UserRegistered{}, nil)
+
+	assert.Equal(t, 1, b.Redeliver("a", UserRegistered{UserID: 1}))
+	assert.Equal(t, 0, b.Redeliver("c", UserRegistered{UserID: 1}))
+	b.Close()
+
+	assert.Equal(t, []interface{}{UserRegistered{UserID: 1}}, received["a"])
+	assert.Equal(t, 0, len(received["b"]))
+
+	_, ok := <-sub.C()
+	assert.Equal(t, false, ok)
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := &RetryPolicy{Backoff: time.Second, MaxBackoff: 10 * time.Second}
+
+	assert.Equal(t, time.Second, p.delay(1))
+	assert.Equal(t, 2*time.Second, p.delay(2))
+	assert.Equal(t, 8*time.Second, p.delay(4))
+	assert.Equal(t, 10*time.Second, p.delay(5))
+	assert.Equal(t, 10*time.Second, p.delay(100))
+}
+
+func TestHandle_DroppedDeadLetter(t *testing.T) {
+	b := NewBus()
+
+	dead := make(chan *DeadLetter, 10)
+	b.OnDeadLetter(func(l *DeadLetter) {
+		dead <- l
+	})
+
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	_, _ = b.Handle(func(ctx context.Context, e UserRegistered) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}, &Options{Name: "slow", BufferSize: 1, Policy: DropNewest})
+
+	b.Publish(UserRegistered{UserID: 1})
+	<-started
+	b.Publish(UserRegistered{UserID: 2})
+	b.Publish(UserRegistered{UserID: 3})
+
+	l := <-dead
+	close(release)
+	b.Close()
+
+	assert.Equal(t, "slow", l.Handler)
+	assert.Equal(t, UserRegistered{UserID: 3}, l.Event)
+	assert.Equal(t, 0, l.Attempts)
+	assert.Equal(t, true, errors.Is(l.Err, ErrBufferFull))
+}
@@ -0,0 +1,10 @@
+package eventlog
+
+import "errors"
+
+var (
+	ErrEventNotRegistered = errors.New("event type not registered")
+	ErrHandle             = errors.New("handle event failed")
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrUnknown            = errors.New("unknown error")
+)
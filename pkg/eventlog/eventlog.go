@@ -0,0 +1,146 @@
+package eventlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+	"github.com/victornm/es-backend/pkg/event"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var _ Service = (*service)(nil)
+
+type Service interface {
+	// Record appends e to the log, it handles every event of a bus:
+	//
+	//	bus.Handle(service.Record, nil)
+	Record(ctx context.Context, e interface{}) error
+
+	// Replay passes logged events to handle one by one, in order, with their original envelopes.
+	// Payloads of old versions are upcast to the current version of their type.
+	// Events of types or versions which are not registered are skipped.
+	// Replay stops at the first error of handle, the replay can be resumed from ReplayResult.LastOffset + 1.
+	Replay(input *ReplayInput, handle Handler) (*ReplayResult, error)
+}
+
+// Handler handles a replayed event, it is called synchronously so no event is lost
+type Handler func(env *event.Envelope) error
+
+type Config struct {
+	Repository Repository
+
+	// Events are zero values of the event types which can be replayed
+	Events []interface{}
+
+	// Registry is used instead of Events if set, to upcast old versions of the events, see event.Registry.RegisterVersion
+	Registry *event.Registry
+}
+
+const replayBatchSize = 100
+
+type service struct {
+	repository Repository
+	registry   *event.Registry
+}
+
+func New(config *Config) Service {
+	s := &service{
+		repository: config.Repository,
+		registry:   config.Registry,
+	}
+
+	if s.registry == nil {
+		s.registry = event.NewRegistry(config.Events...)
+	}
+
+	return s
+}
+
+func (s *service) Record(ctx context.Context, e interface{}) error {
+	env := event.EnvelopeFromContext(ctx)
+	if env == nil {
+		env = event.NewEnvelope(ctx, e)
+	}
+
+	payload, err := json.Marshal(env.Payload)
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	err = s.repository.AppendEvent(&store.EventRow{
+		EventID:       env.ID,
+		EventType:     env.Type,
+		Version:       env.Version,
+		Payload:       payload,
+		ActorID:       env.ActorID,
+		CorrelationID: env.CorrelationID,
+		OccurredAt:    env.OccurredAt,
+	})
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return nil
+}
+
+func (s *service) Replay(input *ReplayInput, handle Handler) (*ReplayResult, error) {
+	if err := validate(input); err != nil {
+		return nil, errorutil.Wrap(ErrInvalidInput, err)
+	}
+
+	result := &ReplayResult{}
+	offset := input.FromOffset
+	for {
+		rows, err := s.repository.FindEvents(offset, input.Since, input.Until, replayBatchSize)
+		if err != nil {
+			return result, errorutil.Wrap(ErrUnknown, err)
+		}
+
+		for _, row := range rows {
+			e, err := s.decode(row)
+			if err != nil {
+				log.Printf("skip event %d: %v", row.Offset, err)
+				result.LastOffset = row.Offset
+				result.Skipped++
+				continue
+			}
+
+			if err := handle(e); err != nil {
+				return result, errorutil.Wrap(ErrHandle, "event %d: %v", row.Offset, err)
+			}
+
+			result.LastOffset = row.Offset
+			result.Replayed++
+		}
+
+		if len(rows) < replayBatchSize {
+			return result, nil
+		}
+
+		offset = result.LastOffset + 1
+	}
+}
+
+// decode returns the envelope of row, with the payload upcast to the current version of its type
+func (s *service) decode(row *store.EventRow) (*event.Envelope, error) {
+	e, err := s.registry.Decode(row.EventType, row.Version, row.Payload)
+	if errors.Is(err, event.ErrNotRegistered) {
+		return nil, errorutil.Wrap(ErrEventNotRegistered, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &event.Envelope{
+		ID:            row.EventID,
+		Type:          event.Name(e),
+		Version:       event.Version(e),
+		OccurredAt:    row.OccurredAt,
+		ActorID:       row.ActorID,
+		CorrelationID: row.CorrelationID,
+		Payload:       e,
+	}, nil
+}
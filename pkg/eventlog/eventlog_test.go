@@ -0,0 +1,205 @@
+package eventlog_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victornm/es-backend/pkg/event"
+	. "github.com/victornm/es-backend/pkg/eventlog"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type userRegistered struct {
+	UserID int
+}
+
+type userSignedIn struct {
+	UserID int
+}
+
+func newFileLog(t *testing.T) (*FileLog, string, func()) {
+	dir, err := ioutil.TempDir("", "eventlog")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "events.jsonl")
+	l, err := NewFileLog(path)
+	require.NoError(t, err)
+
+	return l, path, func() {
+		_ = l.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	l, _, cleanup := newFileLog(t)
+	defer cleanup()
+
+	s := New(&Config{Repository: l, Events: []interface{}{userRegistered{}}})
+
+	// record every event published
+	bus := event.NewBus()
+	_, err := bus.Handle(s.Record, nil)
+	require.NoError(t, err)
+
+	bus.Publish(userRegistered{UserID: 1})
+	bus.Publish(userSignedIn{UserID: 1})
+	bus.Publish(userRegistered{UserID: 2})
+	bus.Close()
+
+	tests := map[string]struct {
+		input  *ReplayInput
+		wanted []interface{}
+		result *ReplayResult
+	}{
+		"all": {
+			&ReplayInput{},
+			[]interface{}{userRegistered{UserID: 1}, userRegistered{UserID: 2}},
+			&ReplayResult{Replayed: 2, Skipped: 1, LastOffset: 3},
+		},
+		"from offset": {
+			&ReplayInput{FromOffset: 2},
+			[]interface{}{userRegistered{UserID: 2}},
+			&ReplayResult{Replayed: 1, Skipped: 1, LastOffset: 3},
+		},
+		"until": {
+			&ReplayInput{Until: time.Now().Add(-time.Hour)},
+			nil,
+			&ReplayResult{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var received []interface{}
+			result, err := s.Replay(test.input, func(env *event.Envelope) error {
+				received = append(received, env.Payload)
+				return nil
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, test.result, result)
+			assert.Equal(t, test.wanted, received)
+		})
+	}
+
+	_, err = s.Replay(&ReplayInput{Since: time.Now(), Until: time.Now().Add(-time.Hour)}, nil)
+	assertIsError(t, ErrInvalidInput, err)
+
+	// the replay stops at the first error, and can be resumed after the last offset
+	result, err := s.Replay(&ReplayInput{}, func(env *event.Envelope) error {
+		if env.Payload == (userRegistered{UserID: 2}) {
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+	assertIsError(t, ErrHandle, err)
+	assert.Equal(t, &ReplayResult{Replayed: 1, Skipped: 1, LastOffset: 2}, result)
+}
+
+type accountCreated struct {
+	UserID int
+	Email  string
+}
+
+func (accountCreated) EventVersion() int { return 2 }
+
+type accountCreatedV1 struct {
+	ID int
+}
+
+func TestReplay_Upcast(t *testing.T) {
+	l, _, cleanup := newFileLog(t)
+	defer cleanup()
+
+	name := event.Name(accountCreated{})
+	registry := event.NewRegistry(accountCreated{})
+	registry.RegisterVersion(name, 1, accountCreatedV1{}, func(e interface{}) (interface{}, error) {
+		return accountCreated{UserID: e.(accountCreatedV1).ID}, nil
+	})
+
+	s := New(&Config{Repository: l, Registry: registry})
+
+	// logged by an older version of the application
+	occurredAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, l.AppendEvent(&store.EventRow{
+		EventID:       "1",
+		EventType:     name,
+		Version:       1,
+		Payload:       []byte(`{"ID":1}`),
+		ActorID:       1,
+		CorrelationID: "request-1",
+		OccurredAt:    occurredAt,
+	}))
+
+	bus := event.NewBus()
+	_, err := bus.Handle(s.Record, nil)
+	require.NoError(t, err)
+	bus.PublishContext(event.WithActor(context.Background(), 2), accountCreated{UserID: 2, Email: "foo@bar.com"})
+	bus.Close()
+
+	var received []*event.Envelope
+	result, err := s.Replay(&ReplayInput{}, func(env *event.Envelope) error {
+		received = append(received, env)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Replayed)
+
+	require.Len(t, received, 2)
+	assert.Equal(t, &event.Envelope{
+		ID:            "1",
+		Type:          name,
+		Version:       2,
+		OccurredAt:    occurredAt,
+		ActorID:       1,
+		CorrelationID: "request-1",
+		Payload:       accountCreated{UserID: 1},
+	}, received[0])
+	assert.Equal(t, accountCreated{UserID: 2, Email: "foo@bar.com"}, received[1].Payload)
+	assert.Equal(t, 2, received[1].ActorID)
+	assert.NotEmpty(t, received[1].ID)
+}
+
+func TestFileLog_Reopen(t *testing.T) {
+	l, path, cleanup := newFileLog(t)
+	defer cleanup()
+
+	s := New(&Config{Repository: l})
+	require.NoError(t, s.Record(context.Background(), userRegistered{UserID: 1}))
+	require.NoError(t, l.Close())
+
+	// a crash during a write leaves a partial line
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, _ = f.WriteString(`{"offset":2,"ty`)
+	_ = f.Close()
+
+	reopened, err := NewFileLog(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	s = New(&Config{Repository: reopened})
+	require.NoError(t, s.Record(context.Background(), userRegistered{UserID: 2}))
+
+	rows, err := reopened.FindEvents(0, time.Time{}, time.Time{}, 10)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, int64(2), rows[1].Offset)
+	assert.JSONEq(t, `{"UserID":2}`, string(rows[1].Payload))
+}
+
+func assertIsError(t *testing.T, wanted, got error) {
+	t.Helper()
+	if !errors.Is(got, wanted) {
+		t.Errorf("Error %v is not an %v", got, wanted)
+	}
+}
@@ -0,0 +1,164 @@
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var _ Repository = (*FileLog)(nil)
+
+// FileLog is a Repository keeping events in a JSON Lines file, for local development.
+// A file must not be shared by several processes.
+type FileLog struct {
+	mu         sync.Mutex
+	path       string
+	file       *os.File
+	lastOffset int64
+}
+
+// fileRecord fields added with envelopes are omitted from older files
+type fileRecord struct {
+	Offset        int64           `json:"offset"`
+	ID            string          `json:"id,omitempty"`
+	Type          string          `json:"type"`
+	Version       int             `json:"version,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+	ActorID       int             `json:"actor_id,omitempty"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// NewFileLog opens the log at path, creating it if needed
+func NewFileLog(path string) (*FileLog, error) {
+	l := &FileLog{path: path}
+
+	// continue from the last offset in the file
+	size, err := l.scan(func(r *fileRecord) bool {
+		l.lastOffset = r.Offset
+		return true
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// drop a partial line left by a crash during a write, so the next record starts on its own line
+	if err == nil {
+		if err := os.Truncate(path, size); err != nil {
+			return nil, err
+		}
+	}
+
+	l.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *FileLog) AppendEvent(e *store.EventRow) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Offset = l.lastOffset + 1
+	e.CreatedAt = time.Now()
+
+	line, err := json.Marshal(&fileRecord{
+		Offset:        e.Offset,
+		ID:            e.EventID,
+		Type:          e.EventType,
+		Version:       e.Version,
+		Payload:       e.Payload,
+		ActorID:       e.ActorID,
+		CorrelationID: e.CorrelationID,
+		OccurredAt:    e.OccurredAt,
+		CreatedAt:     e.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	l.lastOffset = e.Offset
+
+	return nil
+}
+
+func (l *FileLog) FindEvents(fromOffset int64, since, until time.Time, limit int) ([]*store.EventRow, error) {
+	var rows []*store.EventRow
+
+	_, err := l.scan(func(r *fileRecord) bool {
+		if r.Offset < fromOffset ||
+			(!since.IsZero() && r.CreatedAt.Before(since)) ||
+			(!until.IsZero() && !r.CreatedAt.Before(until)) {
+			return true
+		}
+
+		row := &store.EventRow{
+			Offset:        r.Offset,
+			EventID:       r.ID,
+			EventType:     r.Type,
+			Version:       r.Version,
+			Payload:       r.Payload,
+			ActorID:       r.ActorID,
+			CorrelationID: r.CorrelationID,
+			OccurredAt:    r.OccurredAt,
+			CreatedAt:     r.CreatedAt,
+		}
+		if row.OccurredAt.IsZero() {
+			row.OccurredAt = row.CreatedAt
+		}
+		rows = append(rows, row)
+
+		return len(rows) < limit
+	})
+
+	return rows, err
+}
+
+// scan calls f with each record of the file until f returns false.
+// It returns the size of the complete lines read.
+func (l *FileLog) scan(f func(r *fileRecord) bool) (int64, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var size int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// ignore a partial line
+			return size, nil
+		}
+		if err != nil {
+			return size, err
+		}
+
+		var r fileRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			return size, err
+		}
+		size += int64(len(line))
+
+		if !f(&r) {
+			return size, nil
+		}
+	}
+}
+
+func (l *FileLog) Close() error {
+	return l.file.Close()
+}
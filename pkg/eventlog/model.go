@@ -0,0 +1,34 @@
+package eventlog
+
+import (
+	"fmt"
+	"time"
+)
+
+type ReplayInput struct {
+	FromOffset int64
+	Since      time.Time
+	Until      time.Time
+}
+
+func (i *ReplayInput) Valid() error {
+	if i.FromOffset < 0 {
+		return fmt.Errorf("from offset = %d", i.FromOffset)
+	}
+
+	if !i.Since.IsZero() && !i.Until.IsZero() && !i.Since.Before(i.Until) {
+		return fmt.Errorf("since %v is not before until %v", i.Since, i.Until)
+	}
+
+	return nil
+}
+
+type ReplayResult struct {
+	Replayed int `json:"replayed"`
+
+	// Skipped counts events of types which are not registered
+	Skipped int `json:"skipped"`
+
+	// LastOffset is the offset of the last event replayed or skipped, 0 if none
+	LastOffset int64 `json:"last_offset"`
+}
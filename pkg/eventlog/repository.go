@@ -0,0 +1,15 @@
+package eventlog
+
+import (
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type Repository interface {
+	// AppendEvent sets the offset and creation time of e
+	AppendEvent(e *store.EventRow) error
+
+	// FindEvents returns events from the offset, in order. Zero since or until are not used.
+	FindEvents(fromOffset int64, since, until time.Time, limit int) ([]*store.EventRow, error)
+}
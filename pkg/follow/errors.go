@@ -0,0 +1,11 @@
+package follow
+
+import "errors"
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrAlreadyFollowing = errors.New("already following")
+	ErrNotFollowing     = errors.New("not following")
+	ErrInvalidInput     = errors.New("invalid input")
+	ErrUnknown          = errors.New("unknown error")
+)
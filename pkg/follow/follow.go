@@ -0,0 +1,144 @@
+package follow
+
+import (
+	"context"
+	"errors"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+	"github.com/victornm/es-backend/pkg/event"
+	"github.com/victornm/es-backend/pkg/outbox"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var _ Service = (*service)(nil)
+
+type Service interface {
+	Follow(followerID, followeeID int) error
+	Unfollow(followerID, followeeID int) error
+
+	GetFollowers(userID, offset, limit int) ([]*FollowDTO, error)
+	GetFollowing(userID, offset, limit int) ([]*FollowDTO, error)
+}
+
+type Config struct {
+	Repository Repository
+	UserFinder UserFinder
+
+	// Transaction stores a Followed in the outbox with every new follow, optional.
+	// The follow and the event are only stored together if Transaction is atomic.
+	// Bus is not used when it is set.
+	Transaction Transaction
+
+	// Bus receives a Followed for every new follow, optional
+	Bus *event.Bus
+}
+
+type service struct {
+	repository  Repository
+	userFinder  UserFinder
+	transaction Transaction
+	bus         *event.Bus
+}
+
+func New(config *Config) Service {
+	return &service{
+		repository:  config.Repository,
+		userFinder:  config.UserFinder,
+		transaction: config.Transaction,
+		bus:         config.Bus,
+	}
+}
+
+func (s *service) Follow(followerID, followeeID int) error {
+	if followerID == followeeID {
+		return errorutil.Wrap(ErrInvalidInput, "can not follow yourself")
+	}
+
+	if _, err := s.userFinder.FindUserByID(followeeID); err != nil {
+		return errorutil.Wrap(ErrUserNotFound, err)
+	}
+
+	row := &store.FollowRow{FollowerID: followerID, FolloweeID: followeeID}
+	ctx := event.WithActor(context.Background(), followerID)
+
+	if s.transaction != nil {
+		err := s.transaction(func(r Repository, w outbox.Writer) error {
+			if err := r.CreateFollow(row); err != nil {
+				return errorutil.Wrap(ErrAlreadyFollowing, err)
+			}
+
+			_, err := outbox.Add(ctx, w, Followed{FollowerID: followerID, FolloweeID: followeeID})
+			return err
+		})
+		if err != nil && !errors.Is(err, ErrAlreadyFollowing) {
+			return errorutil.Wrap(ErrUnknown, err)
+		}
+
+		return err
+	}
+
+	if err := s.repository.CreateFollow(row); err != nil {
+		return errorutil.Wrap(ErrAlreadyFollowing, err)
+	}
+
+	if s.bus != nil {
+		s.bus.PublishContext(ctx, Followed{FollowerID: followerID, FolloweeID: followeeID})
+	}
+
+	return nil
+}
+
+func (s *service) Unfollow(followerID, followeeID int) error {
+	if err := s.repository.DeleteFollow(followerID, followeeID); err != nil {
+		return errorutil.Wrap(ErrNotFollowing, err)
+	}
+
+	return nil
+}
+
+func (s *service) GetFollowers(userID, offset, limit int) ([]*FollowDTO, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, errorutil.Wrap(ErrInvalidInput, "offset = %d, limit = %d", offset, limit)
+	}
+
+	rows, err := s.repository.FindFollowers(userID, offset, limit)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return s.toDTOs(rows, func(f *store.FollowRow) int { return f.FollowerID })
+}
+
+func (s *service) GetFollowing(userID, offset, limit int) ([]*FollowDTO, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, errorutil.Wrap(ErrInvalidInput, "offset = %d, limit = %d", offset, limit)
+	}
+
+	rows, err := s.repository.FindFollowing(userID, offset, limit)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return s.toDTOs(rows, func(f *store.FollowRow) int { return f.FolloweeID })
+}
+
+// toDTOs describes the user returned by other on each side of the follows
+func (s *service) toDTOs(rows []*store.FollowRow, other func(f *store.FollowRow) int) ([]*FollowDTO, error) {
+	dtos := make([]*FollowDTO, 0, len(rows))
+	for _, row := range rows {
+		u, err := s.userFinder.FindUserByID(other(row))
+		if err != nil {
+			// deleted meanwhile
+			continue
+		}
+
+		dtos = append(dtos, &FollowDTO{
+			UserID:     u.ID,
+			Username:   u.Username,
+			FullName:   u.FullName,
+			FollowedAt: row.CreatedAt,
+		})
+	}
+
+	return dtos, nil
+}
@@ -0,0 +1,132 @@
+package follow_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victornm/es-backend/pkg/event"
+	. "github.com/victornm/es-backend/pkg/follow"
+	"github.com/victornm/es-backend/pkg/outbox"
+	"github.com/victornm/es-backend/pkg/store"
+	"github.com/victornm/es-backend/pkg/store/memory"
+)
+
+const (
+	aliceID = 1
+	bobID   = 2
+	carolID = 3
+)
+
+func newUserFinder() UserFinder {
+	users := memory.NewUserGateway()
+	users.Seed([]*store.UserRow{
+		{Email: "alice@es.com", Username: "alice", IsActive: true},
+		{Email: "bob@es.com", Username: "bob", IsActive: true},
+		{Email: "carol@es.com", Username: "carol", IsActive: true},
+	})
+
+	return users
+}
+
+func newService(bus *event.Bus) Service {
+	return New(&Config{
+		Repository: memory.NewFollowGateway(),
+		UserFinder: newUserFinder(),
+		Bus:        bus,
+	})
+}
+
+func TestFollow(t *testing.T) {
+	s := newService(nil)
+	require.NoError(t, s.Follow(bobID, aliceID))
+
+	tests := map[string]struct {
+		followerID int
+		followeeID int
+		wantedErr  error
+	}{
+		"follow twice":       {bobID, aliceID, ErrAlreadyFollowing},
+		"follow yourself":    {bobID, bobID, ErrInvalidInput},
+		"followee not found": {bobID, 100, ErrUserNotFound},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assertIsError(t, test.wantedErr, s.Follow(test.followerID, test.followeeID))
+		})
+	}
+}
+
+func TestFollow_PublishEvent(t *testing.T) {
+	bus := event.NewBus()
+	sub := bus.Subscribe(Followed{}, nil)
+
+	s := newService(bus)
+	require.NoError(t, s.Follow(bobID, aliceID))
+
+	assert.Equal(t, Followed{FollowerID: bobID, FolloweeID: aliceID}, <-sub.C())
+}
+
+func TestFollow_Outbox(t *testing.T) {
+	follows := memory.NewFollowGateway()
+	entries := memory.NewOutboxGateway()
+
+	s := New(&Config{
+		Repository: follows,
+		UserFinder: newUserFinder(),
+		Transaction: func(f func(r Repository, w outbox.Writer) error) error {
+			return f(follows, entries)
+		},
+	})
+	require.NoError(t, s.Follow(bobID, aliceID))
+	assertIsError(t, ErrAlreadyFollowing, s.Follow(bobID, aliceID))
+
+	rows, err := entries.FindPendingOutboxEntries(1, 10)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, event.Name(Followed{}), rows[0].EventType)
+	assert.JSONEq(t, `{"FollowerID":2,"FolloweeID":1}`, string(rows[0].Payload))
+}
+
+func TestUnfollow(t *testing.T) {
+	s := newService(nil)
+	require.NoError(t, s.Follow(bobID, aliceID))
+
+	require.NoError(t, s.Unfollow(bobID, aliceID))
+	assertIsError(t, ErrNotFollowing, s.Unfollow(bobID, aliceID))
+
+	followers, err := s.GetFollowers(aliceID, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, followers)
+}
+
+func TestGetFollowers(t *testing.T) {
+	s := newService(nil)
+	require.NoError(t, s.Follow(bobID, aliceID))
+	require.NoError(t, s.Follow(carolID, aliceID))
+	require.NoError(t, s.Follow(aliceID, carolID))
+
+	followers, err := s.GetFollowers(aliceID, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, followers, 2)
+	assert.Equal(t, "carol", followers[0].Username, "newest first")
+	assert.Equal(t, "bob", followers[1].Username)
+
+	following, err := s.GetFollowing(aliceID, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, following, 1)
+	assert.Equal(t, carolID, following[0].UserID)
+
+	_, err = s.GetFollowers(aliceID, -1, 10)
+	assertIsError(t, ErrInvalidInput, err)
+}
+
+func assertIsError(t *testing.T, wanted, got error) {
+	t.Helper()
+	if !errors.Is(got, wanted) {
+		t.Errorf("Error %v is not an %v", got, wanted)
+	}
+}
@@ -0,0 +1,24 @@
+package follow
+
+import (
+	"time"
+
+	"github.com/victornm/es-backend/pkg/outbox"
+)
+
+// Followed is published on the bus when a user follows another.
+// It is relayed from the outbox with an idempotency key, so subscribers can drop repeated deliveries.
+type Followed struct {
+	outbox.Key
+	FollowerID int
+	FolloweeID int
+}
+
+func (Followed) EventName() string { return "user.followed" }
+
+type FollowDTO struct {
+	UserID     int       `json:"user_id"`
+	Username   string    `json:"username"`
+	FullName   string    `json:"full_name"`
+	FollowedAt time.Time `json:"followed_at"`
+}
@@ -0,0 +1,23 @@
+package follow
+
+import (
+	"github.com/victornm/es-backend/pkg/outbox"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type Repository interface {
+	CreateFollow(f *store.FollowRow) error
+	DeleteFollow(followerID, followeeID int) error
+	FindFollowers(userID, offset, limit int) ([]*store.FollowRow, error)
+	FindFollowing(userID, offset, limit int) ([]*store.FollowRow, error)
+	CountFollowers(userID int) (int, error)
+	CountFollowing(userID int) (int, error)
+}
+
+type UserFinder interface {
+	FindUserByID(id int) (*store.UserRow, error)
+}
+
+// Transaction runs f with a repository and an outbox writer sharing a transaction,
+// which is committed if f returns nil and rolled back otherwise
+type Transaction func(f func(r Repository, w outbox.Writer) error) error
@@ -0,0 +1,200 @@
+package job
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule decides when a periodic job runs
+type Schedule interface {
+	// Next returns the first activation time after t
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses a cron expression of 5 fields: minute, hour, day of month, month and day of week.
+// Fields are * or comma separated values, ranges (1-5) and steps (*/15, 0-30/10).
+// Days of week are 0 to 7, both 0 and 7 being Sunday.
+// As in cron, a job runs when either the day of month or the day of week matches if both are restricted.
+//
+// The descriptors @yearly, @monthly, @weekly, @daily, @hourly and @every <duration>, like @every 90s, are supported too.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("invalid duration in %q", spec)
+		}
+
+		return every(d), nil
+	}
+
+	if expr, ok := descriptors[spec]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%q should have 5 fields", spec)
+	}
+
+	s := &cronSchedule{}
+	var err error
+	for i, b := range cronBounds {
+		if s.fields[i], err = parseField(fields[i], b); err != nil {
+			return nil, fmt.Errorf("%q: %v", spec, err)
+		}
+	}
+
+	// 7 is Sunday too
+	if s.fields[dow]&(1<<7) != 0 {
+		s.fields[dow] |= 1
+	}
+
+	s.domStar = strings.HasPrefix(fields[dom], "*")
+	s.dowStar = strings.HasPrefix(fields[dow], "*")
+
+	return s, nil
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e)).Truncate(time.Second)
+}
+
+// field indexes
+const (
+	minute = iota
+	hour
+	dom
+	month
+	dow
+)
+
+type bounds struct {
+	min, max int
+}
+
+var cronBounds = [5]bounds{
+	minute: {0, 59},
+	hour:   {0, 23},
+	dom:    {1, 31},
+	month:  {1, 12},
+	dow:    {0, 7},
+}
+
+// cronSchedule has a bit set per field, bit n is set if the value n matches
+type cronSchedule struct {
+	fields           [5]uint64
+	domStar, dowStar bool
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rng = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		lo, hi := b.min, b.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			ends := strings.SplitN(rng, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(ends[0])
+			hi, err2 = strconv.Atoi(ends[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+
+			lo = n
+			// a/n is from a to the maximum
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		if lo < b.min || hi > b.max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, b.min, b.max)
+		}
+
+		for n := lo; n <= hi; n += step {
+			bits |= 1 << uint(n)
+		}
+	}
+
+	return bits, nil
+}
+
+// Next finds the first matching minute after t, field by field from the month down,
+// resetting the smaller fields whenever a larger one moves
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// schedules which never match, like 0 0 30 2 *, give up after 5 years
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !s.match(month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !s.match(hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !s.match(minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *cronSchedule) match(field, n int) bool {
+	return s.fields[field]&(1<<uint(n)) != 0
+}
+
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	domMatch := s.match(dom, t.Day())
+	dowMatch := s.match(dow, int(t.Weekday()))
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
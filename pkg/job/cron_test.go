@@ -0,0 +1,115 @@
+package job_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/victornm/es-backend/pkg/job"
+)
+
+func TestParseSchedule(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2020, 7, 15, 10, 17, 30, 0, time.UTC)
+
+	tests := map[string]struct {
+		spec string
+		want []time.Time
+	}{
+		"every minute": {
+			spec: "* * * * *",
+			want: []time.Time{
+				time.Date(2020, 7, 15, 10, 18, 0, 0, time.UTC),
+				time.Date(2020, 7, 15, 10, 19, 0, 0, time.UTC),
+			},
+		},
+		"step": {
+			spec: "*/15 * * * *",
+			want: []time.Time{
+				time.Date(2020, 7, 15, 10, 30, 0, 0, time.UTC),
+				time.Date(2020, 7, 15, 10, 45, 0, 0, time.UTC),
+				time.Date(2020, 7, 15, 11, 0, 0, 0, time.UTC),
+			},
+		},
+		"list and range": {
+			spec: "0 9,18 * * 1-5",
+			want: []time.Time{
+				time.Date(2020, 7, 15, 18, 0, 0, 0, time.UTC),
+				time.Date(2020, 7, 16, 9, 0, 0, 0, time.UTC),
+				time.Date(2020, 7, 16, 18, 0, 0, 0, time.UTC),
+				time.Date(2020, 7, 17, 9, 0, 0, 0, time.UTC),
+				time.Date(2020, 7, 17, 18, 0, 0, 0, time.UTC),
+				time.Date(2020, 7, 20, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		"sunday is 7": {
+			spec: "30 2 * * 7",
+			want: []time.Time{time.Date(2020, 7, 19, 2, 30, 0, 0, time.UTC)},
+		},
+		"day of month or day of week": {
+			spec: "0 0 1 * 5",
+			want: []time.Time{
+				time.Date(2020, 7, 17, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, 7, 24, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, 7, 31, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		"leap day": {
+			spec: "0 0 29 2 *",
+			want: []time.Time{time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		},
+		"descriptor": {
+			spec: "@daily",
+			want: []time.Time{
+				time.Date(2020, 7, 16, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, 7, 17, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		"every": {
+			spec: "@every 90m",
+			want: []time.Time{
+				time.Date(2020, 7, 15, 11, 47, 30, 0, time.UTC),
+				time.Date(2020, 7, 15, 13, 17, 30, 0, time.UTC),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := ParseSchedule(test.spec)
+			require.NoError(t, err)
+
+			next := from
+			for _, want := range test.want {
+				next = s.Next(next)
+				assert.Equal(t, want, next)
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* * 0 * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every 1ms",
+		"@sometimes",
+	} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestParseSchedule_Never(t *testing.T) {
+	s, err := ParseSchedule("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
@@ -0,0 +1,10 @@
+package job
+
+import "errors"
+
+var (
+	ErrJobNotRegistered = errors.New("job not registered")
+	ErrJobNotFound      = errors.New("job not found")
+	ErrInvalidInput     = errors.New("invalid input")
+	ErrUnknown          = errors.New("unknown error")
+)
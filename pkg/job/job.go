@@ -0,0 +1,472 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+	"github.com/victornm/es-backend/pkg/queue"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var _ Service = (*service)(nil)
+
+// Func runs a job, payload is the JSON given to Schedule, or null for periodic jobs.
+// ctx is cancelled when the scheduler stops.
+type Func func(ctx context.Context, payload json.RawMessage) error
+
+type Service interface {
+	// Register adds the function running the jobs with the name.
+	// With a schedule, see ParseSchedule, the job is also periodic: it is created unless another replica did,
+	// and its next run is computed again if the schedule changed.
+	Register(name, schedule string, f Func) error
+
+	// Schedule creates a one-off job of a registered name to run at t, payload is encoded as JSON
+	Schedule(name string, payload interface{}, t time.Time) (int, error)
+
+	// Trigger creates a one-off job running a periodic job now, or once its running run finishes.
+	// It can be called by another process than the leader.
+	Trigger(name string) (int, error)
+
+	// GetJobs returns the scheduled jobs, the soonest first
+	GetJobs() ([]*JobDTO, error)
+
+	// GetRuns returns the history of the named job, or of every job if name is empty, newest first
+	GetRuns(name string, offset, limit int) ([]*RunDTO, error)
+
+	// RunDue starts the due jobs if this process is the leader, it returns the number of jobs started.
+	// With a queue, the jobs are queued as RunTask tasks instead.
+	// Runs of the same name never overlap: a due periodic job is skipped until its next run,
+	// a due one-off job waits for the next call.
+	RunDue(ctx context.Context) (int, error)
+
+	// HandleRunTask is the queue.Handler of RunTask, it runs a job queued by RunDue on a worker
+	HandleRunTask(ctx context.Context, payload json.RawMessage) error
+
+	// Wait waits for the jobs started by RunDue
+	Wait()
+
+	// Run calls RunDue every interval until ctx is done, then waits for the running jobs and gives up leadership
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type Config struct {
+	Repository Repository
+
+	// Lock elects the replica running the jobs
+	Lock Locker
+
+	// Queue is where the leader queues the due jobs, to be run by the workers handling RunTask.
+	// The leader runs them itself if it is not set.
+	Queue queue.Writer
+}
+
+// RunTask is the kind of the tasks running a job
+const RunTask = "job.run"
+
+type runPayload struct {
+	JobID   int             `json:"job_id"`
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+const runBatchSize = 100
+
+type service struct {
+	repository Repository
+	lock       Locker
+	queue      queue.Writer
+
+	mu    sync.Mutex
+	funcs map[string]Func
+	// names of the running jobs, a name is not started again before its run finishes
+	running map[string]bool
+
+	wg sync.WaitGroup
+}
+
+func New(config *Config) Service {
+	return &service{
+		repository: config.Repository,
+		lock:       config.Lock,
+		queue:      config.Queue,
+		funcs:      make(map[string]Func),
+		running:    make(map[string]bool),
+	}
+}
+
+func (s *service) Register(name, schedule string, f Func) error {
+	if name == "" || f == nil {
+		return errorutil.Wrap(ErrInvalidInput, "name and func are required")
+	}
+
+	s.mu.Lock()
+	s.funcs[name] = f
+	s.mu.Unlock()
+
+	if schedule == "" {
+		return nil
+	}
+
+	sched, err := ParseSchedule(schedule)
+	if err != nil {
+		return errorutil.Wrap(ErrInvalidInput, err)
+	}
+
+	next := sched.Next(time.Now())
+	if next.IsZero() {
+		return errorutil.Wrap(ErrInvalidInput, "%s never runs", schedule)
+	}
+
+	created, err := s.repository.CreatePeriodicJob(&store.JobRow{
+		Name:     name,
+		Schedule: schedule,
+		Payload:  []byte("null"),
+		RunAt:    next,
+		Status:   StatusScheduled,
+	})
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	if created {
+		return nil
+	}
+
+	j, err := s.repository.FindPeriodicJob(name)
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	if j.Schedule == schedule {
+		return nil
+	}
+
+	j.Schedule = schedule
+	j.RunAt = next
+	if err := s.repository.UpdateJob(j); err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return nil
+}
+
+func (s *service) Schedule(name string, payload interface{}, t time.Time) (int, error) {
+	s.mu.Lock()
+	_, ok := s.funcs[name]
+	s.mu.Unlock()
+
+	if !ok {
+		return 0, errorutil.Wrap(ErrJobNotRegistered, name)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, errorutil.Wrap(ErrInvalidInput, err)
+	}
+
+	id, err := s.repository.CreateJob(&store.JobRow{
+		Name:    name,
+		Payload: data,
+		RunAt:   t,
+		Status:  StatusScheduled,
+	})
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return id, nil
+}
+
+func (s *service) Trigger(name string) (int, error) {
+	if _, err := s.repository.FindPeriodicJob(name); err != nil {
+		return 0, errorutil.Wrap(ErrJobNotFound, err)
+	}
+
+	id, err := s.repository.CreateJob(&store.JobRow{
+		Name:    name,
+		Payload: []byte("null"),
+		RunAt:   time.Now(),
+		Status:  StatusScheduled,
+	})
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return id, nil
+}
+
+func (s *service) GetJobs() ([]*JobDTO, error) {
+	rows, err := s.repository.FindJobs(StatusScheduled)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	dtos := make([]*JobDTO, len(rows))
+	for i, row := range rows {
+		dtos[i] = toJobDTO(row)
+	}
+
+	return dtos, nil
+}
+
+func (s *service) GetRuns(name string, offset, limit int) ([]*RunDTO, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, errorutil.Wrap(ErrInvalidInput, "offset = %d, limit = %d", offset, limit)
+	}
+
+	rows, err := s.repository.FindJobRuns(name, offset, limit)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	dtos := make([]*RunDTO, len(rows))
+	for i, row := range rows {
+		dtos[i] = toRunDTO(row)
+	}
+
+	return dtos, nil
+}
+
+func (s *service) RunDue(ctx context.Context) (int, error) {
+	leader, err := s.lock.TryLock()
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	if !leader {
+		return 0, nil
+	}
+
+	now := time.Now()
+	jobs, err := s.repository.FindDueJobs(StatusScheduled, now, runBatchSize)
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	start := s.start
+	if s.queue != nil {
+		start = s.enqueue
+	}
+
+	started := 0
+	for _, j := range jobs {
+		ok, err := start(ctx, j, now)
+		if err != nil {
+			return started, errorutil.Wrap(ErrUnknown, err)
+		}
+
+		if ok {
+			started++
+		}
+	}
+
+	return started, nil
+}
+
+// start runs j in a goroutine, it returns false if j should not run now, see advance
+func (s *service) start(ctx context.Context, j *store.JobRow, now time.Time) (bool, error) {
+	// jobs are moved to their next run before they start, so they are not started twice
+	skip, err := s.advance(j, now)
+	if err != nil || skip {
+		return false, err
+	}
+
+	s.mu.Lock()
+	f := s.funcs[j.Name]
+	s.running[j.Name] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.run(ctx, j, f)
+
+	return true, nil
+}
+
+// enqueue queues a task running j, unless a task of the same name is waiting or running:
+// the periodic job is then skipped until its next run, the one-off job is left scheduled.
+// The key of the task keeps runs of a name from overlapping across the workers.
+func (s *service) enqueue(ctx context.Context, j *store.JobRow, now time.Time) (bool, error) {
+	if j.Schedule != "" {
+		if skip, err := s.advance(j, now); err != nil || skip {
+			return false, err
+		}
+	}
+
+	id, err := queue.EnqueueUnique(s.queue, RunTask, RunTask+":"+j.Name, &runPayload{
+		JobID:   j.ID,
+		Name:    j.Name,
+		Payload: j.Payload,
+	}, now)
+	if err != nil {
+		return false, err
+	}
+
+	if id == 0 {
+		if j.Schedule != "" {
+			log.Printf("skip job %s, the previous run is not finished", j.Name)
+		}
+		return false, nil
+	}
+
+	if j.Schedule == "" {
+		j.Status = StatusDone
+		return true, s.repository.UpdateJob(j)
+	}
+
+	return true, nil
+}
+
+// advance schedules the next run of a periodic job, or marks a one-off job as done.
+// It returns true if the job should not run now, because a run of the same name is not finished.
+// One-off jobs, such as triggers of periodic jobs, are then left scheduled.
+func (s *service) advance(j *store.JobRow, now time.Time) (bool, error) {
+	if j.Schedule == "" {
+		if s.isRunning(j.Name) {
+			return true, nil
+		}
+
+		j.Status = StatusDone
+		return false, s.repository.UpdateJob(j)
+	}
+
+	sched, err := ParseSchedule(j.Schedule)
+	if err != nil {
+		// only possible if the schedule was changed outside of Register
+		log.Printf("disable job %s: %v", j.Name, err)
+		j.Status = StatusDone
+		return true, s.repository.UpdateJob(j)
+	}
+
+	j.RunAt = sched.Next(now)
+	if err := s.repository.UpdateJob(j); err != nil {
+		return false, err
+	}
+
+	if s.isRunning(j.Name) {
+		log.Printf("skip job %s, the previous run is not finished", j.Name)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (s *service) isRunning(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.running[name]
+}
+
+func (s *service) run(ctx context.Context, j *store.JobRow, f Func) {
+	defer s.wg.Done()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, j.Name)
+		s.mu.Unlock()
+	}()
+
+	_ = s.record(ctx, j, f)
+}
+
+func (s *service) HandleRunTask(ctx context.Context, payload json.RawMessage) error {
+	var p runPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return errorutil.Wrap(ErrInvalidInput, err)
+	}
+
+	s.mu.Lock()
+	f, ok := s.funcs[p.Name]
+	s.mu.Unlock()
+
+	// the task is retried, the job may be registered by workers of a newer version
+	if !ok {
+		return errorutil.Wrap(ErrJobNotRegistered, p.Name)
+	}
+
+	err := s.record(ctx, &store.JobRow{ID: p.JobID, Name: p.Name, Payload: p.Payload}, f)
+
+	// cancelled by the drain of the worker, the task is released to another worker
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// failures are kept in the history of the runs, like the failures of the jobs run by the leader
+	return nil
+}
+
+// record calls f with the payload of j, keeping the run in the history. It returns the error of f.
+func (s *service) record(ctx context.Context, j *store.JobRow, f Func) error {
+	r := &store.JobRunRow{
+		JobID:     j.ID,
+		Name:      j.Name,
+		Status:    RunRunning,
+		StartedAt: time.Now(),
+	}
+
+	id, err := s.repository.CreateJobRun(r)
+	if err != nil {
+		log.Printf("record run of job %s failed: %v", j.Name, err)
+	}
+	r.ID = id
+
+	err = call(ctx, f, j)
+	finishedAt := time.Now()
+	r.FinishedAt = &finishedAt
+	r.Status = RunSucceeded
+	if err != nil {
+		log.Printf("job %s failed: %v", j.Name, err)
+		r.Status = RunFailed
+		r.Error = err.Error()
+	}
+
+	if err := s.repository.UpdateJobRun(r); err != nil {
+		log.Printf("record run of job %s failed: %v", j.Name, err)
+	}
+
+	return err
+}
+
+func call(ctx context.Context, f Func, j *store.JobRow) (err error) {
+	if f == nil {
+		return errorutil.Wrap(ErrJobNotRegistered, j.Name)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return f(ctx, j.Payload)
+}
+
+func (s *service) Wait() {
+	s.wg.Wait()
+}
+
+func (s *service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.RunDue(ctx); err != nil {
+				log.Printf("run due jobs failed: %v", err)
+			}
+
+		case <-ctx.Done():
+			s.Wait()
+			if err := s.lock.Unlock(); err != nil {
+				log.Printf("give up job leadership failed: %v", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package job_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/victornm/es-backend/pkg/job"
+	"github.com/victornm/es-backend/pkg/queue"
+	"github.com/victornm/es-backend/pkg/store/memory"
+)
+
+// recorder is a job func recording its payloads
+type recorder struct {
+	mu       sync.Mutex
+	payloads []string
+	err      error
+}
+
+func (r *recorder) run(ctx context.Context, payload json.RawMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.payloads = append(r.payloads, string(payload))
+	return r.err
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.payloads)
+}
+
+func newService(repository Repository, lock *memory.Lock) Service {
+	return New(&Config{
+		Repository: repository,
+		Lock:       lock.Session(),
+	})
+}
+
+func TestRegister(t *testing.T) {
+	repository := memory.NewJobGateway()
+	lock := memory.NewLock()
+	r := &recorder{}
+
+	s := newService(repository, lock)
+	require.NoError(t, s.Register("send_digests", "@hourly", r.run))
+
+	// another replica
+	require.NoError(t, newService(repository, lock).Register("send_digests", "@hourly", r.run))
+
+	jobs, err := s.GetJobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1, "a periodic job should be created once")
+	assert.Equal(t, "@hourly", jobs[0].Schedule)
+	assert.Equal(t, 0, jobs[0].RunAt.Minute())
+
+	require.NoError(t, s.Register("send_digests", "*/5 * * * *", r.run))
+	jobs, err = s.GetJobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "*/5 * * * *", jobs[0].Schedule)
+	assert.Equal(t, 0, jobs[0].RunAt.Minute()%5)
+
+	assertIsError(t, ErrInvalidInput, s.Register("invalid", "every day", r.run))
+	assertIsError(t, ErrInvalidInput, s.Register("never", "0 0 31 2 *", r.run))
+}
+
+func TestSchedule(t *testing.T) {
+	s := newService(memory.NewJobGateway(), memory.NewLock())
+	r := &recorder{}
+	require.NoError(t, s.Register("purge_user", "", r.run))
+
+	_, err := s.Schedule("unknown", nil, time.Now())
+	assertIsError(t, ErrJobNotRegistered, err)
+
+	_, err = s.Schedule("purge_user", map[string]int{"user_id": 1}, time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	_, err = s.Schedule("purge_user", map[string]int{"user_id": 2}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	started, err := s.RunDue(context.Background())
+	require.NoError(t, err)
+	s.Wait()
+	assert.Equal(t, 1, started)
+	assert.Equal(t, []string{`{"user_id":1}`}, r.payloads)
+
+	started, err = s.RunDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, started, "one-off jobs should run once")
+
+	jobs, err := s.GetJobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.JSONEq(t, `{"user_id":2}`, string(jobs[0].Payload))
+
+	runs, err := s.GetRuns("purge_user", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, RunSucceeded, runs[0].Status)
+	assert.NotNil(t, runs[0].FinishedAt)
+}
+
+func TestRunDue_Periodic(t *testing.T) {
+	repository := memory.NewJobGateway()
+	s := newService(repository, memory.NewLock())
+	r := &recorder{}
+	require.NoError(t, s.Register("collect_garbage", "@every 1h", r.run))
+
+	started, err := s.RunDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, started, "the job is not due before an hour")
+
+	makeDue(t, repository, "collect_garbage")
+	started, err = s.RunDue(context.Background())
+	require.NoError(t, err)
+	s.Wait()
+	assert.Equal(t, 1, started)
+	assert.Equal(t, 1, r.count())
+
+	jobs, err := s.GetJobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1, "periodic jobs should stay scheduled")
+	assert.True(t, jobs[0].RunAt.After(time.Now().Add(59*time.Minute)))
+}
+
+func TestRunDue_Failed(t *testing.T) {
+	s := newService(memory.NewJobGateway(), memory.NewLock())
+	require.NoError(t, s.Register("fail", "", func(ctx context.Context, payload json.RawMessage) error {
+		return errors.New("mail server down")
+	}))
+	require.NoError(t, s.Register("panic", "", func(ctx context.Context, payload json.RawMessage) error {
+		panic("boom")
+	}))
+
+	_, _ = s.Schedule("fail", nil, time.Now())
+	_, _ = s.Schedule("panic", nil, time.Now())
+	_, err := s.RunDue(context.Background())
+	require.NoError(t, err)
+	s.Wait()
+
+	runs, err := s.GetRuns("", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+
+	errs := map[string]string{}
+	for _, run := range runs {
+		assert.Equal(t, RunFailed, run.Status)
+		errs[run.Name] = run.Error
+	}
+	assert.Equal(t, map[string]string{"fail": "mail server down", "panic": "panic: boom"}, errs)
+}
+
+func TestRunDue_Leader(t *testing.T) {
+	repository := memory.NewJobGateway()
+	lock := memory.NewLock()
+	r := &recorder{}
+
+	leader := newService(repository, lock)
+	follower := newService(repository, lock)
+	require.NoError(t, leader.Register("purge_user", "", r.run))
+	require.NoError(t, follower.Register("purge_user", "", r.run))
+
+	_, err := leader.RunDue(context.Background())
+	require.NoError(t, err)
+
+	_, _ = follower.Schedule("purge_user", nil, time.Now())
+	started, err := follower.RunDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, started, "only the leader should run jobs")
+
+	// the leader gives up leadership when it stops
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	leader.Run(ctx, time.Hour)
+
+	started, err = follower.RunDue(context.Background())
+	require.NoError(t, err)
+	follower.Wait()
+	assert.Equal(t, 1, started)
+	assert.Equal(t, 1, r.count())
+}
+
+func TestTrigger(t *testing.T) {
+	repository := memory.NewJobGateway()
+	s := newService(repository, memory.NewLock())
+	r := &recorder{}
+	require.NoError(t, s.Register("send_digests", "@daily", r.run))
+
+	_, err := s.Trigger("unknown")
+	assertIsError(t, ErrJobNotFound, err)
+
+	// from the CLI, which registers no job
+	_, err = newService(repository, memory.NewLock()).Trigger("send_digests")
+	require.NoError(t, err)
+
+	started, err := s.RunDue(context.Background())
+	require.NoError(t, err)
+	s.Wait()
+	assert.Equal(t, 1, started)
+	assert.Equal(t, []string{"null"}, r.payloads)
+}
+
+func TestTrigger_Overlap(t *testing.T) {
+	repository := memory.NewJobGateway()
+	s := newService(repository, memory.NewLock())
+
+	release := make(chan struct{})
+	r := &recorder{}
+	require.NoError(t, s.Register("send_digests", "@daily", func(ctx context.Context, payload json.RawMessage) error {
+		<-release
+		return r.run(ctx, payload)
+	}))
+
+	makeDue(t, repository, "send_digests")
+	started, err := s.RunDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, started)
+
+	_, err = s.Trigger("send_digests")
+	require.NoError(t, err)
+	started, err = s.RunDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, started, "a trigger should not overlap the running run")
+
+	close(release)
+	s.Wait()
+
+	started, err = s.RunDue(context.Background())
+	require.NoError(t, err)
+	s.Wait()
+	assert.Equal(t, 1, started, "the trigger should run once the previous run finished")
+	assert.Equal(t, 2, r.count())
+}
+
+func TestRunDue_Queue(t *testing.T) {
+	repository := memory.NewJobGateway()
+	tasks := memory.NewTaskGateway()
+	s := New(&Config{Repository: repository, Lock: memory.NewLock().Session(), Queue: tasks})
+
+	r := &recorder{}
+	require.NoError(t, s.Register("send_digests", "@daily", r.run))
+	require.NoError(t, s.Register("purge_user", "", r.run))
+
+	makeDue(t, repository, "send_digests")
+	_, _ = s.Schedule("purge_user", 7, time.Now())
+	started, err := s.RunDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, started)
+	assert.Zero(t, r.count(), "queued jobs should run on the workers")
+
+	// the previous tasks are not run yet
+	makeDue(t, repository, "send_digests")
+	_, _ = s.Schedule("purge_user", 8, time.Now())
+	started, err = s.RunDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, started, "runs of a name should not overlap")
+
+	claimed, err := tasks.ClaimTasks(queue.StatusQueued, []string{RunTask}, time.Now(), time.Now().Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+
+	// a worker, which registers the same jobs
+	worker := New(&Config{Repository: repository, Lock: memory.NewLock().Session()})
+	require.NoError(t, worker.Register("send_digests", "@daily", r.run))
+	require.NoError(t, worker.Register("purge_user", "", r.run))
+	for _, task := range claimed {
+		require.NoError(t, worker.HandleRunTask(context.Background(), task.Payload))
+		require.NoError(t, tasks.DeleteTask(task.ID))
+	}
+	assert.ElementsMatch(t, []string{"null", "7"}, r.payloads)
+
+	runs, err := s.GetRuns("", 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, runs, 2)
+
+	started, err = s.RunDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, started, "the one-off job left scheduled should be queued once the previous run finished")
+
+	err = New(&Config{Repository: repository}).HandleRunTask(context.Background(), claimed[0].Payload)
+	assertIsError(t, ErrJobNotRegistered, err)
+}
+
+func makeDue(t *testing.T, repository *memory.JobGateway, name string) {
+	t.Helper()
+
+	j, err := repository.FindPeriodicJob(name)
+	require.NoError(t, err)
+
+	j.RunAt = time.Now().Add(-time.Second)
+	require.NoError(t, repository.UpdateJob(j))
+}
+
+func assertIsError(t *testing.T, wanted, got error, msgAndArgs ...interface{}) {
+	t.Helper()
+	if !errors.Is(got, wanted) {
+		t.Errorf("Error %v is not an %v %v", got, wanted, msgAndArgs)
+	}
+}
@@ -0,0 +1,65 @@
+package job
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+// Job statuses
+const (
+	StatusScheduled = "scheduled"
+
+	// StatusDone one-off jobs have run, whether they failed or not
+	StatusDone = "done"
+)
+
+// Run statuses
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+type JobDTO struct {
+	ID       int             `json:"id"`
+	Name     string          `json:"name"`
+	Schedule string          `json:"schedule"`
+	Payload  json.RawMessage `json:"payload"`
+	RunAt    time.Time       `json:"run_at"`
+	Status   string          `json:"status"`
+}
+
+type RunDTO struct {
+	ID         int        `json:"id"`
+	JobID      int        `json:"job_id"`
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+func toJobDTO(row *store.JobRow) *JobDTO {
+	return &JobDTO{
+		ID:       row.ID,
+		Name:     row.Name,
+		Schedule: row.Schedule,
+		Payload:  row.Payload,
+		RunAt:    row.RunAt,
+		Status:   row.Status,
+	}
+}
+
+func toRunDTO(row *store.JobRunRow) *RunDTO {
+	return &RunDTO{
+		ID:         row.ID,
+		JobID:      row.JobID,
+		Name:       row.Name,
+		Status:     row.Status,
+		Error:      row.Error,
+		StartedAt:  row.StartedAt,
+		FinishedAt: row.FinishedAt,
+	}
+}
@@ -0,0 +1,34 @@
+package job
+
+import (
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type Repository interface {
+	// CreatePeriodicJob creates j unless a periodic job with the same name exists, it returns true if j was created
+	CreatePeriodicJob(j *store.JobRow) (bool, error)
+	CreateJob(j *store.JobRow) (int, error)
+	FindPeriodicJob(name string) (*store.JobRow, error)
+
+	// FindJobs returns jobs with the status, the soonest first
+	FindJobs(status string) ([]*store.JobRow, error)
+
+	// FindDueJobs returns jobs with the status to run at or before now, the soonest first
+	FindDueJobs(status string, now time.Time, limit int) ([]*store.JobRow, error)
+	UpdateJob(j *store.JobRow) error
+
+	CreateJobRun(r *store.JobRunRow) (int, error)
+	UpdateJobRun(r *store.JobRunRow) error
+
+	// FindJobRuns returns the runs of the named job, or of every job if name is empty, newest first
+	FindJobRuns(name string, offset, limit int) ([]*store.JobRunRow, error)
+}
+
+// Locker elects the leader among replicas, only the holder of the lock runs jobs
+type Locker interface {
+	// TryLock returns true if the lock is held by this process, taking it if it is free
+	TryLock() (bool, error)
+	Unlock() error
+}
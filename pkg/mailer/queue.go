@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/queue"
+)
+
+// SendTask is the kind of the tasks sending an email
+const SendTask = "email.send"
+
+// Sender sends an email rendered from the template tmpl with data
+type Sender interface {
+	Send(subject string, tmpl string, data interface{}, to []string) error
+}
+
+type sendPayload struct {
+	Subject  string      `json:"subject"`
+	Template string      `json:"template"`
+	Data     interface{} `json:"data"`
+	To       []string    `json:"to"`
+}
+
+// Queue sends emails from a worker, so requests do not wait on the mail server and failures are retried.
+// data goes through JSON, so templates receive structs as maps with the keys of their json tags,
+// and safe types such as template.URL as plain strings which are escaped.
+type Queue struct {
+	w queue.Writer
+}
+
+func NewQueue(w queue.Writer) *Queue {
+	return &Queue{w: w}
+}
+
+func (q *Queue) Send(subject string, tmpl string, data interface{}, to []string) error {
+	_, err := queue.Enqueue(q.w, SendTask, &sendPayload{
+		Subject:  subject,
+		Template: tmpl,
+		Data:     data,
+		To:       to,
+	}, time.Time{})
+
+	return err
+}
+
+// HandleSendTask returns the handler of SendTask, sending the emails with s
+func HandleSendTask(s Sender) queue.Handler {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		var p sendPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+
+		return s.Send(p.Subject, p.Template, p.Data, p.To)
+	}
+}
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victornm/es-backend/pkg/queue"
+	"github.com/victornm/es-backend/pkg/store/memory"
+)
+
+type senderFunc func(subject string, tmpl string, data interface{}, to []string) error
+
+func (f senderFunc) Send(subject string, tmpl string, data interface{}, to []string) error {
+	return f(subject, tmpl, data, to)
+}
+
+func TestQueue(t *testing.T) {
+	tasks := memory.NewTaskGateway()
+	q := NewQueue(tasks)
+
+	require.NoError(t, q.Send("My page", tpl, data{Title: "My page", Items: []string{"My blog"}}, []string{"foo@bar.com"}))
+
+	claimed, err := tasks.ClaimTasks(queue.StatusQueued, []string{SendTask}, time.Now(), time.Now().Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	var body string
+	h := HandleSendTask(senderFunc(func(subject string, tmpl string, data interface{}, to []string) error {
+		assert.Equal(t, "My page", subject)
+		assert.Equal(t, []string{"foo@bar.com"}, to)
+
+		msg, err := parseTemplate(tmpl, data)
+		body = string(msg)
+		return err
+	}))
+
+	require.NoError(t, h(context.Background(), claimed[0].Payload))
+	assert.Contains(t, body, "<title>My page</title>")
+	assert.Contains(t, body, "<div>My blog</div>")
+}
@@ -0,0 +1,15 @@
+package moderation
+
+import "errors"
+
+var (
+	ErrNotModerator          = errors.New("not a moderator")
+	ErrReportNotFound        = errors.New("report not found")
+	ErrTargetNotFound        = errors.New("reported target not found")
+	ErrTargetNotSupported    = errors.New("target type not supported")
+	ErrActionNotSupported    = errors.New("action not supported for this target")
+	ErrAlreadyReported       = errors.New("already reported")
+	ErrReportAlreadyResolved = errors.New("report already resolved")
+	ErrInvalidInput          = errors.New("invalid input")
+	ErrUnknown               = errors.New("unknown error")
+)
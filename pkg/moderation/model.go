@@ -0,0 +1,107 @@
+package moderation
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+const TargetUser = "user"
+
+const (
+	StatusOpen     = "open"
+	StatusAssigned = "assigned"
+	StatusResolved = "resolved"
+)
+
+var unresolved = []string{StatusOpen, StatusAssigned}
+
+// Moderator decisions, also used as report resolutions
+const (
+	ActionHide    = "hide"
+	ActionWarn    = "warn"
+	ActionSuspend = "suspend"
+	ActionDismiss = "dismiss"
+)
+
+// Actions only recorded in the audit trail
+const (
+	ActionAssign   = "assign"
+	ActionAutoHide = "auto_hide"
+)
+
+const warningNotificationType = "moderation_warning"
+
+type ReportInput struct {
+	TargetType string `json:"target_type" validate:"required"`
+	TargetID   int    `json:"target_id" validate:"required"`
+	Reason     string `json:"reason" validate:"required,oneof=spam harassment hate_speech violence sexual_content copyright other"`
+	Comment    string `json:"comment" validate:"max=1000"`
+}
+
+func (i *ReportInput) Valid() error {
+	return validator.New().Struct(i)
+}
+
+type ResolveInput struct {
+	Action string `json:"action" validate:"required,oneof=hide warn suspend dismiss"`
+	Note   string `json:"note" validate:"max=1000"`
+}
+
+func (i *ResolveInput) Valid() error {
+	return validator.New().Struct(i)
+}
+
+type ReportDTO struct {
+	ID         int       `json:"id"`
+	ReporterID int       `json:"reporter_id"`
+	TargetType string    `json:"target_type"`
+	TargetID   int       `json:"target_id"`
+	Reason     string    `json:"reason"`
+	Comment    string    `json:"comment"`
+	Status     string    `json:"status"`
+	AssigneeID *int      `json:"assignee_id"`
+	Resolution string    `json:"resolution"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ActionDTO struct {
+	ID          int       `json:"id"`
+	ModeratorID *int      `json:"moderator_id"`
+	ReportID    *int      `json:"report_id"`
+	TargetType  string    `json:"target_type"`
+	TargetID    int       `json:"target_id"`
+	Action      string    `json:"action"`
+	Note        string    `json:"note"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func toReportDTO(row *store.ReportRow) *ReportDTO {
+	return &ReportDTO{
+		ID:         row.ID,
+		ReporterID: row.ReporterID,
+		TargetType: row.TargetType,
+		TargetID:   row.TargetID,
+		Reason:     row.Reason,
+		Comment:    row.Comment,
+		Status:     row.Status,
+		AssigneeID: row.AssigneeID,
+		Resolution: row.Resolution,
+		CreatedAt:  row.CreatedAt,
+	}
+}
+
+func toActionDTO(row *store.ModerationActionRow) *ActionDTO {
+	return &ActionDTO{
+		ID:          row.ID,
+		ModeratorID: row.ModeratorID,
+		ReportID:    row.ReportID,
+		TargetType:  row.TargetType,
+		TargetID:    row.TargetID,
+		Action:      row.Action,
+		Note:        row.Note,
+		CreatedAt:   row.CreatedAt,
+	}
+}
@@ -0,0 +1,388 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+	"github.com/victornm/es-backend/pkg/event"
+	"github.com/victornm/es-backend/pkg/notification"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var _ Service = (*service)(nil)
+
+type Service interface {
+	// Report is available to every user
+	Report(reporterID int, input *ReportInput) (*ReportDTO, error)
+
+	// The rest is for moderators only
+	GetQueue(moderatorID int, status string, offset, limit int) ([]*ReportDTO, error)
+	Assign(moderatorID, reportID, assigneeID int) error
+	Resolve(moderatorID, reportID int, input *ResolveInput) error
+	GetAuditTrail(moderatorID int, targetType string, targetID int) ([]*ActionDTO, error)
+}
+
+type Config struct {
+	Repository     Repository
+	UserRepository UserRepository
+	Notifier       Notifier
+
+	// Targets are the reportable item types besides users, by type name
+	Targets map[string]Target
+
+	// AutoHideThreshold is the number of distinct users who may report a Hideable item,
+	// it is hidden automatically when the reporters exceed it. 0 disables automatic hiding.
+	AutoHideThreshold int
+}
+
+type service struct {
+	repository        Repository
+	users             UserRepository
+	notifier          Notifier
+	targets           map[string]Target
+	autoHideThreshold int
+}
+
+func New(config *Config) Service {
+	s := &service{
+		repository:        config.Repository,
+		users:             config.UserRepository,
+		notifier:          config.Notifier,
+		targets:           map[string]Target{TargetUser: &userTarget{users: config.UserRepository}},
+		autoHideThreshold: config.AutoHideThreshold,
+	}
+
+	for name, t := range config.Targets {
+		s.targets[name] = t
+	}
+
+	return s
+}
+
+func (s *service) Report(reporterID int, input *ReportInput) (*ReportDTO, error) {
+	if err := validate(input); err != nil {
+		return nil, errorutil.Wrap(ErrInvalidInput, err)
+	}
+
+	target, ok := s.targets[input.TargetType]
+	if !ok {
+		return nil, errorutil.Wrap(ErrTargetNotSupported, input.TargetType)
+	}
+
+	ownerID, err := target.OwnerID(input.TargetID)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrTargetNotFound, err)
+	}
+
+	if ownerID == reporterID {
+		return nil, errorutil.Wrap(ErrInvalidInput, "can not report yourself")
+	}
+
+	reported, err := s.hasReported(reporterID, input.TargetType, input.TargetID)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	if reported {
+		return nil, errorutil.Wrap(ErrAlreadyReported, "%s %d", input.TargetType, input.TargetID)
+	}
+
+	row := &store.ReportRow{
+		ReporterID: reporterID,
+		TargetType: input.TargetType,
+		TargetID:   input.TargetID,
+		Reason:     input.Reason,
+		Comment:    input.Comment,
+		Status:     StatusOpen,
+	}
+
+	id, err := s.repository.CreateReport(row)
+	if err != nil {
+		// the repository keeps one unresolved report per reporter, a concurrent report may have been created since the check
+		if reported, _ := s.hasReported(reporterID, input.TargetType, input.TargetID); reported {
+			return nil, errorutil.Wrap(ErrAlreadyReported, err)
+		}
+
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+	row.ID = id
+
+	if err := s.autoHide(target, input.TargetType, input.TargetID); err != nil {
+		log.Printf("auto hide %s %d failed: %v", input.TargetType, input.TargetID, err)
+	}
+
+	return toReportDTO(row), nil
+}
+
+// hasReported tells if the user has an unresolved report of the target
+func (s *service) hasReported(reporterID int, targetType string, targetID int) (bool, error) {
+	reports, err := s.repository.FindReportsByTarget(targetType, targetID, unresolved)
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range reports {
+		if r.ReporterID == reporterID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// autoHide hides the target when the number of reporters exceeds the threshold, unless it is hidden already.
+// Reports stay in the queue so a moderator can review the decision.
+func (s *service) autoHide(target Target, targetType string, targetID int) error {
+	hideable, ok := target.(Hideable)
+	if !ok || s.autoHideThreshold <= 0 {
+		return nil
+	}
+
+	// counted after the report is created, so concurrent reports are not missed.
+	// Every unresolved report has a distinct reporter, enforced by the repository.
+	reports, err := s.repository.FindReportsByTarget(targetType, targetID, unresolved)
+	if err != nil {
+		return err
+	}
+
+	reporters := len(reports)
+	if reporters <= s.autoHideThreshold {
+		return nil
+	}
+
+	hidden, err := s.isHidden(targetType, targetID)
+	if err != nil || hidden {
+		return err
+	}
+
+	if err := hideable.Hide(targetID); err != nil {
+		return err
+	}
+
+	_, err = s.repository.CreateModerationAction(&store.ModerationActionRow{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Action:     ActionAutoHide,
+		Note:       fmt.Sprintf("reported by %d users", reporters),
+	})
+
+	return err
+}
+
+// isHidden tells if the target was hidden by a moderator or automatically
+func (s *service) isHidden(targetType string, targetID int) (bool, error) {
+	actions, err := s.repository.FindModerationActionsByTarget(targetType, targetID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, a := range actions {
+		if a.Action == ActionHide || a.Action == ActionAutoHide {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *service) GetQueue(moderatorID int, status string, offset, limit int) ([]*ReportDTO, error) {
+	if err := s.checkModerator(moderatorID); err != nil {
+		return nil, err
+	}
+
+	statuses := unresolved
+	if status != "" {
+		statuses = []string{status}
+	}
+
+	if offset < 0 || limit <= 0 {
+		return nil, errorutil.Wrap(ErrInvalidInput, "offset = %d, limit = %d", offset, limit)
+	}
+
+	rows, err := s.repository.FindReportsByStatus(statuses, offset, limit)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	dtos := make([]*ReportDTO, len(rows))
+	for i, row := range rows {
+		dtos[i] = toReportDTO(row)
+	}
+
+	return dtos, nil
+}
+
+func (s *service) Assign(moderatorID, reportID, assigneeID int) error {
+	if err := s.checkModerator(moderatorID); err != nil {
+		return err
+	}
+
+	if err := s.checkModerator(assigneeID); err != nil {
+		return errorutil.Wrap(ErrInvalidInput, "assignee: %v", err)
+	}
+
+	r, err := s.findUnresolvedReport(reportID)
+	if err != nil {
+		return err
+	}
+
+	r.Status = StatusAssigned
+	r.AssigneeID = &assigneeID
+	if err := s.repository.UpdateReport(r); err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return s.audit(moderatorID, r, ActionAssign, fmt.Sprintf("assigned to user %d", assigneeID))
+}
+
+// Resolve applies the moderator decision to the reported target.
+// The decision is about the target, so every unresolved report of the same target is resolved with it.
+func (s *service) Resolve(moderatorID, reportID int, input *ResolveInput) error {
+	if err := s.checkModerator(moderatorID); err != nil {
+		return err
+	}
+
+	if err := validate(input); err != nil {
+		return errorutil.Wrap(ErrInvalidInput, err)
+	}
+
+	r, err := s.findUnresolvedReport(reportID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.apply(event.WithActor(context.Background(), moderatorID), r, input); err != nil {
+		return err
+	}
+
+	if err := s.audit(moderatorID, r, input.Action, input.Note); err != nil {
+		return err
+	}
+
+	reports, err := s.repository.FindReportsByTarget(r.TargetType, r.TargetID, unresolved)
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	for _, report := range reports {
+		report.Status = StatusResolved
+		report.Resolution = input.Action
+		if err := s.repository.UpdateReport(report); err != nil {
+			return errorutil.Wrap(ErrUnknown, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *service) apply(ctx context.Context, r *store.ReportRow, input *ResolveInput) error {
+	target, ok := s.targets[r.TargetType]
+	if !ok {
+		return errorutil.Wrap(ErrTargetNotSupported, r.TargetType)
+	}
+
+	switch input.Action {
+	case ActionHide:
+		hideable, ok := target.(Hideable)
+		if !ok {
+			return errorutil.Wrap(ErrActionNotSupported, "%s can not be hidden", r.TargetType)
+		}
+
+		if err := hideable.Hide(r.TargetID); err != nil {
+			return errorutil.Wrap(ErrUnknown, err)
+		}
+
+	case ActionWarn:
+		ownerID, err := target.OwnerID(r.TargetID)
+		if err != nil {
+			return errorutil.Wrap(ErrTargetNotFound, err)
+		}
+
+		err = s.notifier.Notify(ctx, &notification.NotifyInput{
+			UserID: ownerID,
+			Type:   warningNotificationType,
+			Title:  "You received a warning from a moderator",
+			Body:   input.Note,
+		})
+		if err != nil {
+			return errorutil.Wrap(ErrUnknown, err)
+		}
+
+	case ActionSuspend:
+		ownerID, err := target.OwnerID(r.TargetID)
+		if err != nil {
+			return errorutil.Wrap(ErrTargetNotFound, err)
+		}
+
+		if s.checkModerator(ownerID) == nil {
+			return errorutil.Wrap(ErrActionNotSupported, "moderators can not be suspended")
+		}
+
+		if err := s.users.UpdateUserActive(ownerID, false); err != nil {
+			return errorutil.Wrap(ErrUnknown, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *service) GetAuditTrail(moderatorID int, targetType string, targetID int) ([]*ActionDTO, error) {
+	if err := s.checkModerator(moderatorID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.repository.FindModerationActionsByTarget(targetType, targetID)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	dtos := make([]*ActionDTO, len(rows))
+	for i, row := range rows {
+		dtos[i] = toActionDTO(row)
+	}
+
+	return dtos, nil
+}
+
+func (s *service) audit(moderatorID int, r *store.ReportRow, action, note string) error {
+	reportID := r.ID
+	_, err := s.repository.CreateModerationAction(&store.ModerationActionRow{
+		ModeratorID: &moderatorID,
+		ReportID:    &reportID,
+		TargetType:  r.TargetType,
+		TargetID:    r.TargetID,
+		Action:      action,
+		Note:        note,
+	})
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return nil
+}
+
+func (s *service) findUnresolvedReport(id int) (*store.ReportRow, error) {
+	r, err := s.repository.FindReportByID(id)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrReportNotFound, err)
+	}
+
+	if r.Status == StatusResolved {
+		return nil, errorutil.Wrap(ErrReportAlreadyResolved, "report %d", id)
+	}
+
+	return r, nil
+}
+
+// checkModerator returns nil if the user is a moderator.
+// For now, moderators are super admins.
+func (s *service) checkModerator(userID int) error {
+	u, err := s.users.FindUserByID(userID)
+	if err != nil || !u.IsSuperAdmin {
+		return errorutil.Wrap(ErrNotModerator, "user %d", userID)
+	}
+
+	return nil
+}
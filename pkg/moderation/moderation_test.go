@@ -0,0 +1,238 @@
+package moderation_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/victornm/es-backend/pkg/moderation"
+	"github.com/victornm/es-backend/pkg/notification"
+	"github.com/victornm/es-backend/pkg/store"
+	"github.com/victornm/es-backend/pkg/store/memory"
+)
+
+const (
+	moderatorID = 1
+	aliceID     = 2
+	bobID       = 3
+	carolID     = 4
+)
+
+type mockNotifier struct {
+	notified []*notification.NotifyInput
+}
+
+func (n *mockNotifier) Notify(ctx context.Context, input *notification.NotifyInput) error {
+	n.notified = append(n.notified, input)
+	return nil
+}
+
+// commentTarget is a Hideable owned by alice
+type commentTarget struct {
+	mu     sync.Mutex
+	hidden []int
+}
+
+func (t *commentTarget) OwnerID(id int) (int, error) {
+	return aliceID, nil
+}
+
+func (t *commentTarget) Hide(id int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.hidden = append(t.hidden, id)
+	return nil
+}
+
+type fixture struct {
+	service  Service
+	users    *memory.UserGateway
+	notifier *mockNotifier
+	comments *commentTarget
+}
+
+func newFixture() *fixture {
+	users := memory.NewUserGateway()
+	users.Seed([]*store.UserRow{
+		{Email: "admin@es.com", IsSuperAdmin: true, IsActive: true},
+		{Email: "alice@es.com", IsActive: true},
+		{Email: "bob@es.com", IsActive: true},
+		{Email: "carol@es.com", IsActive: true},
+	})
+
+	f := &fixture{
+		users:    users,
+		notifier: &mockNotifier{},
+		comments: &commentTarget{},
+	}
+
+	f.service = New(&Config{
+		Repository:        memory.NewModerationGateway(),
+		UserRepository:    users,
+		Notifier:          f.notifier,
+		Targets:           map[string]Target{"comment": f.comments},
+		AutoHideThreshold: 1,
+	})
+
+	return f
+}
+
+func TestReport(t *testing.T) {
+	f := newFixture()
+
+	r, err := f.service.Report(bobID, &ReportInput{TargetType: TargetUser, TargetID: aliceID, Reason: "spam"})
+	require.NoError(t, err)
+	assert.Equal(t, StatusOpen, r.Status)
+
+	tests := map[string]struct {
+		reporterID int
+		input      *ReportInput
+		wantedErr  error
+	}{
+		"report twice": {
+			bobID, &ReportInput{TargetType: TargetUser, TargetID: aliceID, Reason: "harassment"}, ErrAlreadyReported,
+		},
+		"report yourself": {
+			aliceID, &ReportInput{TargetType: TargetUser, TargetID: aliceID, Reason: "spam"}, ErrInvalidInput,
+		},
+		"unknown reason": {
+			bobID, &ReportInput{TargetType: TargetUser, TargetID: carolID, Reason: "boring"}, ErrInvalidInput,
+		},
+		"unknown target type": {
+			bobID, &ReportInput{TargetType: "course", TargetID: 1, Reason: "spam"}, ErrTargetNotSupported,
+		},
+		"target not found": {
+			bobID, &ReportInput{TargetType: TargetUser, TargetID: 100, Reason: "spam"}, ErrTargetNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := f.service.Report(test.reporterID, test.input)
+			assertIsError(t, test.wantedErr, err)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Run("suspend", func(t *testing.T) {
+		f := newFixture()
+		first, _ := f.service.Report(bobID, &ReportInput{TargetType: TargetUser, TargetID: aliceID, Reason: "spam"})
+		_, _ = f.service.Report(carolID, &ReportInput{TargetType: TargetUser, TargetID: aliceID, Reason: "spam"})
+
+		require.NoError(t, f.service.Resolve(moderatorID, first.ID, &ResolveInput{Action: ActionSuspend, Note: "spammer"}))
+
+		u, _ := f.users.FindUserByID(aliceID)
+		assert.False(t, u.IsActive)
+
+		queue, err := f.service.GetQueue(moderatorID, "", 0, 10)
+		require.NoError(t, err)
+		assert.Empty(t, queue, "every report of the target should be resolved")
+
+		err = f.service.Resolve(moderatorID, first.ID, &ResolveInput{Action: ActionDismiss})
+		assertIsError(t, ErrReportAlreadyResolved, err)
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		f := newFixture()
+		r, _ := f.service.Report(bobID, &ReportInput{TargetType: "comment", TargetID: 10, Reason: "harassment"})
+
+		require.NoError(t, f.service.Resolve(moderatorID, r.ID, &ResolveInput{Action: ActionWarn, Note: "be nice"}))
+
+		require.Len(t, f.notifier.notified, 1)
+		assert.Equal(t, aliceID, f.notifier.notified[0].UserID)
+		assert.Equal(t, "be nice", f.notifier.notified[0].Body)
+	})
+
+	t.Run("users can not be hidden", func(t *testing.T) {
+		f := newFixture()
+		r, _ := f.service.Report(bobID, &ReportInput{TargetType: TargetUser, TargetID: aliceID, Reason: "spam"})
+
+		err := f.service.Resolve(moderatorID, r.ID, &ResolveInput{Action: ActionHide})
+		assertIsError(t, ErrActionNotSupported, err)
+	})
+
+	t.Run("moderators can not be suspended", func(t *testing.T) {
+		f := newFixture()
+		r, _ := f.service.Report(bobID, &ReportInput{TargetType: TargetUser, TargetID: moderatorID, Reason: "spam"})
+
+		err := f.service.Resolve(moderatorID, r.ID, &ResolveInput{Action: ActionSuspend})
+		assertIsError(t, ErrActionNotSupported, err)
+	})
+
+	t.Run("not a moderator", func(t *testing.T) {
+		f := newFixture()
+		r, _ := f.service.Report(bobID, &ReportInput{TargetType: TargetUser, TargetID: aliceID, Reason: "spam"})
+
+		err := f.service.Resolve(carolID, r.ID, &ResolveInput{Action: ActionSuspend})
+		assertIsError(t, ErrNotModerator, err)
+
+		_, err = f.service.GetQueue(carolID, "", 0, 10)
+		assertIsError(t, ErrNotModerator, err)
+	})
+}
+
+func TestAssign(t *testing.T) {
+	f := newFixture()
+	r, _ := f.service.Report(bobID, &ReportInput{TargetType: TargetUser, TargetID: aliceID, Reason: "spam"})
+
+	assertIsError(t, ErrInvalidInput, f.service.Assign(moderatorID, r.ID, bobID), "assign to a non moderator")
+	require.NoError(t, f.service.Assign(moderatorID, r.ID, moderatorID))
+
+	queue, _ := f.service.GetQueue(moderatorID, StatusAssigned, 0, 10)
+	require.Len(t, queue, 1)
+	assert.Equal(t, moderatorID, *queue[0].AssigneeID)
+}
+
+func TestAutoHide(t *testing.T) {
+	f := newFixture()
+
+	_, _ = f.service.Report(bobID, &ReportInput{TargetType: "comment", TargetID: 10, Reason: "spam"})
+	assert.Empty(t, f.comments.hidden)
+
+	_, _ = f.service.Report(carolID, &ReportInput{TargetType: "comment", TargetID: 10, Reason: "spam"})
+	assert.Equal(t, []int{10}, f.comments.hidden)
+
+	_, _ = f.service.Report(moderatorID, &ReportInput{TargetType: "comment", TargetID: 10, Reason: "spam"})
+	assert.Equal(t, []int{10}, f.comments.hidden, "should only be hidden once")
+
+	trail, err := f.service.GetAuditTrail(moderatorID, "comment", 10)
+	require.NoError(t, err)
+	require.Len(t, trail, 1)
+	assert.Equal(t, ActionAutoHide, trail[0].Action)
+	assert.Nil(t, trail[0].ModeratorID)
+
+	queue, _ := f.service.GetQueue(moderatorID, "", 0, 10)
+	assert.Len(t, queue, 3, "auto hidden reports should stay for review")
+}
+
+func TestAutoHide_ConcurrentReports(t *testing.T) {
+	f := newFixture()
+
+	wg := &sync.WaitGroup{}
+	for _, reporterID := range []int{moderatorID, bobID, carolID, bobID} {
+		wg.Add(1)
+		go func(reporterID int) {
+			defer wg.Done()
+			_, _ = f.service.Report(reporterID, &ReportInput{TargetType: "comment", TargetID: 10, Reason: "spam"})
+		}(reporterID)
+	}
+	wg.Wait()
+
+	assert.NotEmpty(t, f.comments.hidden, "should be hidden whatever the order of the reports")
+
+	queue, _ := f.service.GetQueue(moderatorID, "", 0, 10)
+	assert.Len(t, queue, 3, "should keep one report per reporter")
+}
+
+func assertIsError(t *testing.T, wanted, got error, msgAndArgs ...interface{}) {
+	t.Helper()
+	if !errors.Is(got, wanted) {
+		t.Errorf("Error %v is not an %v %v", got, wanted, msgAndArgs)
+	}
+}
@@ -0,0 +1,28 @@
+package moderation
+
+import (
+	"context"
+
+	"github.com/victornm/es-backend/pkg/notification"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type Repository interface {
+	CreateReport(r *store.ReportRow) (int, error)
+	FindReportByID(id int) (*store.ReportRow, error)
+	FindReportsByStatus(statuses []string, offset, limit int) ([]*store.ReportRow, error)
+	FindReportsByTarget(targetType string, targetID int, statuses []string) ([]*store.ReportRow, error)
+	UpdateReport(r *store.ReportRow) error
+
+	CreateModerationAction(a *store.ModerationActionRow) (int, error)
+	FindModerationActionsByTarget(targetType string, targetID int) ([]*store.ModerationActionRow, error)
+}
+
+type UserRepository interface {
+	FindUserByID(id int) (*store.UserRow, error)
+	UpdateUserActive(id int, isActive bool) error
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, input *notification.NotifyInput) error
+}
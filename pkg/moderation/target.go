@@ -0,0 +1,29 @@
+package moderation
+
+// Target is a kind of reportable item, registered by its type in Config.Targets.
+// Users are always reportable.
+type Target interface {
+	// OwnerID returns the user responsible for the item, who is warned or suspended.
+	// It returns an error if the item does not exist.
+	OwnerID(id int) (int, error)
+}
+
+// Hideable is a Target that can be hidden by moderators,
+// and automatically once enough users reported it
+type Hideable interface {
+	Target
+	Hide(id int) error
+}
+
+type userTarget struct {
+	users UserRepository
+}
+
+func (t *userTarget) OwnerID(id int) (int, error) {
+	u, err := t.users.FindUserByID(id)
+	if err != nil {
+		return 0, err
+	}
+
+	return u.ID, nil
+}
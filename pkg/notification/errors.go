@@ -0,0 +1,9 @@
+package notification
+
+import "errors"
+
+var (
+	ErrNotFound     = errors.New("notification not found")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrUnknown      = errors.New("unknown error")
+)
@@ -0,0 +1,75 @@
+package notification
+
+import (
+	"fmt"
+	"html/template"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type Mailer interface {
+	Send(subject string, tmpl string, data interface{}, to []string) error
+}
+
+const notificationTpl = `<!DOCTYPE html>
+<html>
+	<head>
+		<meta charset="UTF-8">
+		<title>{{ .Title }}</title>
+	</head>
+	<body>
+		<h3>{{ .Title }}</h3>
+		<p>{{ .Body }}</p>
+		{{ if .Link }}<a href="{{ .Link }}">View</a>{{ end }}
+	</body>
+</html>`
+
+const digestTpl = `<!DOCTYPE html>
+<html>
+	<head>
+		<meta charset="UTF-8">
+		<title>Your notifications</title>
+	</head>
+	<body>
+		{{ range .Notifications }}<div>
+			<h4>{{ .Title }}</h4>
+			<p>{{ .Body }}</p>
+			{{ if .Link }}<a href="{{ .Link }}">View</a>{{ end }}
+		</div>{{ end }}
+	</body>
+</html>`
+
+func (s *service) sendEmail(n *store.NotificationRow) error {
+	u, err := s.userFinder.FindUserByID(n.UserID)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{
+		"Title": n.Title,
+		"Body":  n.Body,
+		"Link":  template.URL(n.Link),
+	}
+
+	return s.mailer.Send(n.Title, notificationTpl, data, []string{u.Email})
+}
+
+func (s *service) sendDigestEmail(userID int, notifications []*store.NotificationRow) error {
+	u, err := s.userFinder.FindUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	items := make([]map[string]interface{}, len(notifications))
+	for i, n := range notifications {
+		items[i] = map[string]interface{}{
+			"Title": n.Title,
+			"Body":  n.Body,
+			"Link":  template.URL(n.Link),
+		}
+	}
+
+	subject := fmt.Sprintf("You have %d new notifications", len(notifications))
+
+	return s.mailer.Send(subject, digestTpl, map[string]interface{}{"Notifications": items}, []string{u.Email})
+}
@@ -0,0 +1,99 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+// Delivery channels a user can choose for each notification type.
+// Every notification is kept in the in-app notification center,
+// the channel only decides whether an email is sent as well.
+const (
+	ChannelInApp  = "in_app" // in-app only
+	ChannelEmail  = "email"  // in-app and an email right away
+	ChannelDigest = "digest" // in-app and batched into the next digest email
+)
+
+func validChannel(channel string) bool {
+	switch channel {
+	case ChannelInApp, ChannelEmail, ChannelDigest:
+		return true
+	}
+
+	return false
+}
+
+type NotifyInput struct {
+	UserID int    `validate:"required"`
+	Type   string `validate:"required,max=64"`
+	Title  string `validate:"required,max=255"`
+	Body   string
+	Link   string `validate:"max=255"`
+}
+
+func (i *NotifyInput) Valid() error {
+	return validator.New().Struct(i)
+}
+
+type PreferenceInput struct {
+	Type    string `json:"type" validate:"required,max=64"`
+	Channel string `json:"channel" validate:"required"`
+}
+
+func (i *PreferenceInput) Valid() error {
+	if !validChannel(i.Channel) {
+		return fmt.Errorf("channel %q not supported", i.Channel)
+	}
+
+	return validator.New().Struct(i)
+}
+
+type NotificationDTO struct {
+	ID        int        `json:"id"`
+	Type      string     `json:"type"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Link      string     `json:"link"`
+	IsRead    bool       `json:"is_read"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+// NotificationCreated is published on the bus after a notification is stored
+type NotificationCreated struct {
+	UserID       int
+	Notification *NotificationDTO
+}
+
+func (NotificationCreated) EventName() string { return "notification.created" }
+
+// NotificationsRead is published on the bus after notifications of a user are marked as read,
+// Count is the number of notifications which were unread
+type NotificationsRead struct {
+	UserID int
+	Count  int
+}
+
+func (NotificationsRead) EventName() string { return "notification.read" }
+
+type PreferenceDTO struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+}
+
+func toNotificationDTO(row *store.NotificationRow) *NotificationDTO {
+	return &NotificationDTO{
+		ID:        row.ID,
+		Type:      row.Type,
+		Title:     row.Title,
+		Body:      row.Body,
+		Link:      row.Link,
+		IsRead:    row.IsRead,
+		CreatedAt: row.CreatedAt,
+		ReadAt:    row.ReadAt,
+	}
+}
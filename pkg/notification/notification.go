@@ -0,0 +1,250 @@
+package notification
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+	"github.com/victornm/es-backend/pkg/event"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var _ Service = (*service)(nil)
+
+type Service interface {
+	// Notify stores a notification, NotificationCreated takes the actor and the correlation ID of ctx
+	Notify(ctx context.Context, input *NotifyInput) error
+
+	GetNotifications(userID, offset, limit int) ([]*NotificationDTO, error)
+	CountUnread(userID int) (int, error)
+	MarkAsRead(userID, notificationID int) error
+	MarkAllAsRead(userID int) error
+
+	GetPreferences(userID int) ([]*PreferenceDTO, error)
+	UpdatePreference(userID int, input *PreferenceInput) error
+
+	SendDigests() error
+}
+
+type Config struct {
+	Repository Repository
+	UserFinder UserFinder
+	Mailer     Mailer
+
+	// Bus receives a NotificationCreated for every notification, optional
+	Bus *event.Bus
+
+	// DefaultChannel is used for types the user has no preference for,
+	// ChannelInApp if empty
+	DefaultChannel string
+}
+
+type service struct {
+	repository     Repository
+	userFinder     UserFinder
+	mailer         Mailer
+	bus            *event.Bus
+	defaultChannel string
+}
+
+func New(config *Config) Service {
+	s := &service{
+		repository:     config.Repository,
+		userFinder:     config.UserFinder,
+		mailer:         config.Mailer,
+		bus:            config.Bus,
+		defaultChannel: config.DefaultChannel,
+	}
+
+	if !validChannel(s.defaultChannel) {
+		s.defaultChannel = ChannelInApp
+	}
+
+	return s
+}
+
+// Notify stores a notification for the user, then delivers it by email
+// if the user asked for it. Email failures are logged but not returned,
+// the notification is still available in the notification center.
+func (s *service) Notify(ctx context.Context, input *NotifyInput) error {
+	if err := validate(input); err != nil {
+		return errorutil.Wrap(ErrInvalidInput, err)
+	}
+
+	channel, err := s.channelFor(input.UserID, input.Type)
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	row := &store.NotificationRow{
+		UserID:    input.UserID,
+		Type:      input.Type,
+		Title:     input.Title,
+		Body:      input.Body,
+		Link:      input.Link,
+		InDigest:  channel == ChannelDigest,
+		CreatedAt: time.Now(),
+	}
+
+	id, err := s.repository.CreateNotification(row)
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+	row.ID = id
+
+	if s.bus != nil {
+		s.bus.PublishContext(ctx, NotificationCreated{UserID: row.UserID, Notification: toNotificationDTO(row)})
+	}
+
+	if channel == ChannelEmail {
+		if err := s.sendEmail(row); err != nil {
+			log.Printf("send notification %d by email failed: %v", row.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *service) channelFor(userID int, notificationType string) (string, error) {
+	prefs, err := s.repository.FindNotificationPreferences(userID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, p := range prefs {
+		if p.Type == notificationType {
+			return p.Channel, nil
+		}
+	}
+
+	return s.defaultChannel, nil
+}
+
+func (s *service) GetNotifications(userID, offset, limit int) ([]*NotificationDTO, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, errorutil.Wrap(ErrInvalidInput, "offset = %d, limit = %d", offset, limit)
+	}
+
+	rows, err := s.repository.FindNotificationsByUserID(userID, offset, limit)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	dtos := make([]*NotificationDTO, len(rows))
+	for i, row := range rows {
+		dtos[i] = toNotificationDTO(row)
+	}
+
+	return dtos, nil
+}
+
+func (s *service) CountUnread(userID int) (int, error) {
+	count, err := s.repository.CountUnreadNotifications(userID)
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return count, nil
+}
+
+func (s *service) MarkAsRead(userID, notificationID int) error {
+	wasUnread, err := s.repository.MarkNotificationAsRead(userID, notificationID, time.Now())
+	if err != nil {
+		return errorutil.Wrap(ErrNotFound, err)
+	}
+
+	if wasUnread {
+		s.publishRead(userID, 1)
+	}
+
+	return nil
+}
+
+func (s *service) MarkAllAsRead(userID int) error {
+	count, err := s.repository.MarkAllNotificationsAsRead(userID, time.Now())
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	if count > 0 {
+		s.publishRead(userID, count)
+	}
+
+	return nil
+}
+
+func (s *service) publishRead(userID, count int) {
+	if s.bus != nil {
+		s.bus.Publish(NotificationsRead{UserID: userID, Count: count})
+	}
+}
+
+func (s *service) GetPreferences(userID int) ([]*PreferenceDTO, error) {
+	rows, err := s.repository.FindNotificationPreferences(userID)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	dtos := make([]*PreferenceDTO, len(rows))
+	for i, row := range rows {
+		dtos[i] = &PreferenceDTO{Type: row.Type, Channel: row.Channel}
+	}
+
+	return dtos, nil
+}
+
+func (s *service) UpdatePreference(userID int, input *PreferenceInput) error {
+	if err := validate(input); err != nil {
+		return errorutil.Wrap(ErrInvalidInput, err)
+	}
+
+	err := s.repository.SaveNotificationPreference(&store.NotificationPreferenceRow{
+		UserID:  userID,
+		Type:    input.Type,
+		Channel: input.Channel,
+	})
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return nil
+}
+
+// SendDigests sends one email per user containing every notification
+// waiting for the digest, then removes them from the digest.
+// Users whose email could not be sent are kept for the next run.
+func (s *service) SendDigests() error {
+	rows, err := s.repository.FindDigestNotifications()
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	byUser := make(map[int][]*store.NotificationRow)
+	var userIDs []int
+	for _, row := range rows {
+		if _, ok := byUser[row.UserID]; !ok {
+			userIDs = append(userIDs, row.UserID)
+		}
+		byUser[row.UserID] = append(byUser[row.UserID], row)
+	}
+
+	for _, userID := range userIDs {
+		notifications := byUser[userID]
+		if err := s.sendDigestEmail(userID, notifications); err != nil {
+			log.Printf("send digest to user %d failed: %v", userID, err)
+			continue
+		}
+
+		ids := make([]int, len(notifications))
+		for i, n := range notifications {
+			ids[i] = n.ID
+		}
+
+		if err := s.repository.RemoveFromDigest(ids); err != nil {
+			return errorutil.Wrap(ErrUnknown, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package notification_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victornm/es-backend/pkg/event"
+	. "github.com/victornm/es-backend/pkg/notification"
+	"github.com/victornm/es-backend/pkg/store"
+	"github.com/victornm/es-backend/pkg/store/memory"
+)
+
+type mockMailer struct {
+	mu   sync.Mutex
+	sent []string // subjects
+	to   []string
+}
+
+func (m *mockMailer) Send(subject string, tmpl string, data interface{}, to []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, subject)
+	m.to = append(m.to, to...)
+	return nil
+}
+
+func newService(mailer Mailer) Service {
+	users := memory.NewUserGateway()
+	users.Seed([]*store.UserRow{
+		{Email: "victornm@es.com", Username: "victornm"},
+		{Email: "foo@bar.com", Username: "foo"},
+	})
+
+	return New(&Config{
+		Repository: memory.NewNotificationGateway(),
+		UserFinder: users,
+		Mailer:     mailer,
+	})
+}
+
+func TestNotify(t *testing.T) {
+	s := newService(&mockMailer{})
+
+	require.NoError(t, s.Notify(context.Background(), &NotifyInput{UserID: 1, Type: "reply", Title: "first"}))
+	require.NoError(t, s.Notify(context.Background(), &NotifyInput{UserID: 1, Type: "reply", Title: "second"}))
+	require.NoError(t, s.Notify(context.Background(), &NotifyInput{UserID: 2, Type: "reply", Title: "other user"}))
+
+	notifications, err := s.GetNotifications(1, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, notifications, 2)
+	assert.Equal(t, "second", notifications[0].Title, "newest should come first")
+
+	count, err := s.CountUnread(1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	t.Run("invalid input", func(t *testing.T) {
+		err := s.Notify(context.Background(), &NotifyInput{UserID: 1, Type: "reply"})
+		assertIsError(t, ErrInvalidInput, err)
+	})
+}
+
+func TestMarkAsRead(t *testing.T) {
+	s := newService(&mockMailer{})
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Notify(context.Background(), &NotifyInput{UserID: 1, Type: "reply", Title: "hello"}))
+	}
+
+	require.NoError(t, s.MarkAsRead(1, 1))
+	count, _ := s.CountUnread(1)
+	assert.Equal(t, 2, count)
+
+	assertIsError(t, ErrNotFound, s.MarkAsRead(2, 2), "mark other user's notification")
+	assertIsError(t, ErrNotFound, s.MarkAsRead(1, 100))
+
+	require.NoError(t, s.MarkAllAsRead(1))
+	count, _ = s.CountUnread(1)
+	assert.Equal(t, 0, count)
+}
+
+func TestUnreadCounts(t *testing.T) {
+	b := event.NewBus()
+	s := New(&Config{
+		Repository: memory.NewNotificationGateway(),
+		UserFinder: memory.NewUserGateway(),
+		Mailer:     &mockMailer{},
+		Bus:        b,
+	})
+
+	counts := NewUnreadCounts(memory.NewNotificationGateway())
+	_, err := b.Handle(counts.Handle, nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Notify(context.Background(), &NotifyInput{UserID: 1, Type: "reply", Title: "hello"}))
+	}
+	require.NoError(t, s.Notify(context.Background(), &NotifyInput{UserID: 2, Type: "reply", Title: "hello"}))
+	require.NoError(t, s.MarkAsRead(1, 1))
+	require.NoError(t, s.MarkAsRead(1, 1), "already read, should not be counted twice")
+	b.Close()
+
+	count, err := counts.Count(1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	require.NoError(t, counts.Handle(context.Background(), NotificationsRead{UserID: 1, Count: 2}))
+	count, _ = counts.Count(1)
+	assert.Equal(t, 0, count)
+
+	require.NoError(t, counts.Reset())
+	count, _ = counts.Count(2)
+	assert.Equal(t, 0, count)
+}
+
+func TestMarkAllAsRead_Event(t *testing.T) {
+	b := event.NewBus()
+	s := New(&Config{
+		Repository: memory.NewNotificationGateway(),
+		UserFinder: memory.NewUserGateway(),
+		Mailer:     &mockMailer{},
+		Bus:        b,
+	})
+
+	var read []NotificationsRead
+	_, _ = b.Handle(func(ctx context.Context, e NotificationsRead) error {
+		read = append(read, e)
+		return nil
+	}, nil)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, s.Notify(context.Background(), &NotifyInput{UserID: 1, Type: "reply", Title: "hello"}))
+	}
+	require.NoError(t, s.MarkAllAsRead(1))
+	require.NoError(t, s.MarkAllAsRead(1), "nothing left to read, no event")
+	b.Close()
+
+	assert.Equal(t, []NotificationsRead{{UserID: 1, Count: 2}}, read)
+}
+
+func TestNotify_Preferences(t *testing.T) {
+	mailer := &mockMailer{}
+	s := newService(mailer)
+
+	require.NoError(t, s.UpdatePreference(1, &PreferenceInput{Type: "reply", Channel: ChannelEmail}))
+	require.NoError(t, s.UpdatePreference(1, &PreferenceInput{Type: "new_lesson", Channel: ChannelDigest}))
+	require.NoError(t, s.UpdatePreference(2, &PreferenceInput{Type: "new_lesson", Channel: ChannelDigest}))
+	assertIsError(t, ErrInvalidInput, s.UpdatePreference(1, &PreferenceInput{Type: "reply", Channel: "sms"}))
+
+	require.NoError(t, s.Notify(context.Background(), &NotifyInput{UserID: 1, Type: "reply", Title: "someone replied"}))
+	require.NoError(t, s.Notify(context.Background(), &NotifyInput{UserID: 1, Type: "approved", Title: "in-app only"}))
+	assert.Equal(t, []string{"someone replied"}, mailer.sent)
+	assert.Equal(t, []string{"victornm@es.com"}, mailer.to)
+
+	require.NoError(t, s.Notify(context.Background(), &NotifyInput{UserID: 1, Type: "new_lesson", Title: "lesson 1"}))
+	require.NoError(t, s.Notify(context.Background(), &NotifyInput{UserID: 1, Type: "new_lesson", Title: "lesson 2"}))
+	require.NoError(t, s.Notify(context.Background(), &NotifyInput{UserID: 2, Type: "new_lesson", Title: "lesson 1"}))
+	assert.Len(t, mailer.sent, 1, "digest notifications should wait for SendDigests")
+
+	require.NoError(t, s.SendDigests())
+	assert.Equal(t, []string{
+		"someone replied",
+		"You have 2 new notifications",
+		"You have 1 new notifications",
+	}, mailer.sent)
+
+	require.NoError(t, s.SendDigests())
+	assert.Len(t, mailer.sent, 3, "sent digests should not be sent again")
+
+	prefs, err := s.GetPreferences(1)
+	require.NoError(t, err)
+	assert.Len(t, prefs, 2)
+}
+
+type courseApproved struct {
+	AuthorID int
+}
+
+func TestSubscribe(t *testing.T) {
+	b := event.NewBus()
+	s := newService(&mockMailer{})
+
+	sub, err := Subscribe(b, s, courseApproved{}, func(e interface{}) (*NotifyInput, error) {
+		if e.(courseApproved).AuthorID == 0 {
+			return nil, nil
+		}
+
+		return &NotifyInput{UserID: e.(courseApproved).AuthorID, Type: "course_approved", Title: "approved"}, nil
+	}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, sub)
+
+	var dead []*event.DeadLetter
+	b.OnDeadLetter(func(l *event.DeadLetter) {
+		dead = append(dead, l)
+	})
+
+	b.Publish(courseApproved{AuthorID: 1})
+	b.Publish(courseApproved{})
+	b.Publish(courseApproved{AuthorID: 1})
+
+	assert.Eventually(t, func() bool {
+		count, _ := s.CountUnread(1)
+		return count == 2
+	}, time.Second, 10*time.Millisecond)
+	b.Close()
+
+	assert.Empty(t, dead)
+}
+
+func TestSubscribe_Correlation(t *testing.T) {
+	b := event.NewBus()
+	s := New(&Config{
+		Repository: memory.NewNotificationGateway(),
+		UserFinder: memory.NewUserGateway(),
+		Mailer:     &mockMailer{},
+		Bus:        b,
+	})
+
+	created := make(chan *event.Envelope, 1)
+	_, _ = b.Handle(func(ctx context.Context, e NotificationCreated) error {
+		created <- event.EnvelopeFromContext(ctx)
+		return nil
+	}, nil)
+
+	_, err := Subscribe(b, s, courseApproved{}, func(e interface{}) (*NotifyInput, error) {
+		return &NotifyInput{UserID: e.(courseApproved).AuthorID, Type: "course_approved", Title: "approved"}, nil
+	}, nil)
+	require.NoError(t, err)
+
+	b.PublishContext(event.WithCorrelationID(context.Background(), "request-1"), courseApproved{AuthorID: 1})
+
+	select {
+	case env := <-created:
+		assert.Equal(t, "request-1", env.CorrelationID, "notifications should share the correlation ID of their cause")
+	case <-time.After(time.Second):
+		t.Fatal("no notification created")
+	}
+	b.Close()
+}
+
+func assertIsError(t *testing.T, wanted, got error, msgAndArgs ...interface{}) {
+	t.Helper()
+	if !errors.Is(got, wanted) {
+		t.Errorf("Error %v is not an %v %v", got, wanted, msgAndArgs)
+	}
+}
@@ -0,0 +1,36 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type Repository interface {
+	CreateNotification(n *store.NotificationRow) (int, error)
+	FindNotificationsByUserID(userID, offset, limit int) ([]*store.NotificationRow, error)
+	CountUnreadNotifications(userID int) (int, error)
+
+	// MarkNotificationAsRead returns whether the notification was unread
+	MarkNotificationAsRead(userID, id int, at time.Time) (bool, error)
+
+	// MarkAllNotificationsAsRead returns the number of notifications which were unread
+	MarkAllNotificationsAsRead(userID int, at time.Time) (int, error)
+
+	FindDigestNotifications() ([]*store.NotificationRow, error)
+	RemoveFromDigest(ids []int) error
+
+	FindNotificationPreferences(userID int) ([]*store.NotificationPreferenceRow, error)
+	SaveNotificationPreference(p *store.NotificationPreferenceRow) error
+}
+
+type UserFinder interface {
+	FindUserByID(id int) (*store.UserRow, error)
+}
+
+type UnreadCountRepository interface {
+	// AddUnreadCount adds delta to the count of the user, which starts at 0
+	AddUnreadCount(userID, delta int) error
+	FindUnreadCount(userID int) (int, error)
+	DeleteUnreadCounts() error
+}
@@ -0,0 +1,48 @@
+package notification
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/victornm/es-backend/pkg/event"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Subscribe handles events of the same type as e on the bus
+// and notifies the user returned by toInput.
+// toInput may return nil for events nobody should be notified about,
+// and an error to fail the event, which is then retried with the retry policy of opts.
+// The handler is named notification.<event name> unless opts has a name.
+func Subscribe(b *event.Bus, s Service, e interface{}, toInput func(e interface{}) (*NotifyInput, error), opts *event.Options) (*event.Subscription, error) {
+	notify := func(ctx context.Context, e interface{}) error {
+		input, err := toInput(e)
+		if err != nil || input == nil {
+			return err
+		}
+
+		return s.Notify(ctx, input)
+	}
+
+	// Bus.Handle subscribes to the type of the second parameter, so the handler is built for the type of e
+	fn := reflect.MakeFunc(
+		reflect.FuncOf([]reflect.Type{contextType, reflect.TypeOf(e)}, []reflect.Type{errorType}, false),
+		func(args []reflect.Value) []reflect.Value {
+			err := notify(args[0].Interface().(context.Context), args[1].Interface())
+			return []reflect.Value{reflect.ValueOf(&err).Elem()}
+		},
+	)
+
+	named := event.Options{}
+	if opts != nil {
+		named = *opts
+	}
+	if named.Name == "" {
+		named.Name = "notification." + event.Name(e)
+	}
+
+	return b.Handle(fn.Interface(), &named)
+}
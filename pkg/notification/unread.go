@@ -0,0 +1,58 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+)
+
+// UnreadCounts is a projection of the number of unread notifications of each user,
+// built from the NotificationCreated and NotificationsRead events.
+type UnreadCounts struct {
+	repository UnreadCountRepository
+}
+
+func NewUnreadCounts(repository UnreadCountRepository) *UnreadCounts {
+	return &UnreadCounts{repository: repository}
+}
+
+// Handle applies e to the counts, events of other types are ignored.
+// It handles every event of a bus:
+//
+//	bus.Handle(counts.Handle, nil)
+func (c *UnreadCounts) Handle(ctx context.Context, e interface{}) error {
+	var err error
+	switch e := e.(type) {
+	case NotificationCreated:
+		if !e.Notification.IsRead {
+			err = c.repository.AddUnreadCount(e.UserID, 1)
+		}
+
+	case NotificationsRead:
+		err = c.repository.AddUnreadCount(e.UserID, -e.Count)
+	}
+
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return nil
+}
+
+func (c *UnreadCounts) Count(userID int) (int, error) {
+	count, err := c.repository.FindUnreadCount(userID)
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return count, nil
+}
+
+// Reset removes every count, before the events are replayed from the start
+func (c *UnreadCounts) Reset() error {
+	if err := c.repository.DeleteUnreadCounts(); err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package outbox
+
+import "sync"
+
+// Deduper remembers the most recent idempotency keys received by a subscriber
+type Deduper struct {
+	mu    sync.Mutex
+	size  int
+	keys  map[string]struct{}
+	order []string
+}
+
+func NewDeduper(size int) *Deduper {
+	return &Deduper{
+		size: size,
+		keys: make(map[string]struct{}, size),
+	}
+}
+
+// Seen records key and returns true if it was already recorded.
+// Events without a key are never considered seen.
+func (d *Deduper) Seen(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.keys[key]; ok {
+		return true
+	}
+
+	if len(d.order) == d.size {
+		delete(d.keys, d.order[0])
+		d.order = d.order[1:]
+	}
+
+	d.keys[key] = struct{}{}
+	d.order = append(d.order, key)
+
+	return false
+}
+
+// Forget removes key, so the event is handled again when it is retried after a failure
+func (d *Deduper) Forget(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.keys[key]; !ok {
+		return
+	}
+
+	delete(d.keys, key)
+	for i, k := range d.order {
+		if k == key {
+			d.order = append(d.order[:i], d.order[i+1:]...)
+			break
+		}
+	}
+}
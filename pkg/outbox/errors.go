@@ -0,0 +1,8 @@
+package outbox
+
+import "errors"
+
+var (
+	ErrEventNotRegistered = errors.New("event type not registered")
+	ErrUnknown            = errors.New("unknown error")
+)
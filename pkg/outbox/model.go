@@ -0,0 +1,16 @@
+package outbox
+
+// Idempotent events receive the idempotency key of their outbox entry when they are relayed,
+// so subscribers can drop events delivered more than once
+type Idempotent interface {
+	SetIdempotencyKey(key string)
+}
+
+// Key implements Idempotent, embed it in events which need to be deduplicated
+type Key struct {
+	IdempotencyKey string `json:"-"`
+}
+
+func (k *Key) SetIdempotencyKey(key string) {
+	k.IdempotencyKey = key
+}
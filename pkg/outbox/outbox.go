@@ -0,0 +1,191 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+	"github.com/victornm/es-backend/pkg/event"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var _ Service = (*service)(nil)
+
+// Service relays stored events to the bus.
+// Relays of several processes can share the outbox, an entry is claimed by one of them at a time.
+// An event is published at least once: if the process stops between publishing and marking the entry,
+// it is published again once the claim expires.
+type Service interface {
+	// Relay publishes pending entries, oldest first, and returns the number published
+	Relay() (int, error)
+
+	// Cleanup deletes entries delivered longer than the retention ago
+	Cleanup() (int, error)
+}
+
+type Config struct {
+	Repository Repository
+	Bus        *event.Bus
+
+	// Events are zero values of every event type stored in the outbox
+	Events []interface{}
+
+	// BatchSize is the maximum number of entries published by a relay
+	BatchSize int
+
+	// MaxAttempts is the number of times an entry which can not be decoded is tried
+	MaxAttempts int
+
+	// ClaimTimeout is how long entries claimed by a relay are hidden from the others
+	ClaimTimeout time.Duration
+
+	// Retention is how long delivered entries are kept
+	Retention time.Duration
+}
+
+const (
+	defaultBatchSize    = 100
+	defaultMaxAttempts  = 5
+	defaultClaimTimeout = time.Minute
+	defaultRetention    = 7 * 24 * time.Hour
+)
+
+type service struct {
+	repository   Repository
+	bus          *event.Bus
+	registry     *event.Registry
+	batchSize    int
+	maxAttempts  int
+	claimTimeout time.Duration
+	retention    time.Duration
+}
+
+func New(config *Config) Service {
+	s := &service{
+		repository:   config.Repository,
+		bus:          config.Bus,
+		registry:     event.NewRegistry(config.Events...),
+		batchSize:    config.BatchSize,
+		maxAttempts:  config.MaxAttempts,
+		claimTimeout: config.ClaimTimeout,
+		retention:    config.Retention,
+	}
+
+	if s.batchSize <= 0 {
+		s.batchSize = defaultBatchSize
+	}
+
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = defaultMaxAttempts
+	}
+
+	if s.claimTimeout <= 0 {
+		s.claimTimeout = defaultClaimTimeout
+	}
+
+	if s.retention <= 0 {
+		s.retention = defaultRetention
+	}
+
+	return s
+}
+
+// Add stores e in the outbox and returns its idempotency key.
+// w should be bound to the transaction of the domain change, so e is only stored if the change is committed.
+// The envelope of e takes the actor and the correlation ID of ctx, see event.NewEnvelope, it is published as stored.
+func Add(ctx context.Context, w Writer, e interface{}) (string, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return "", errorutil.Wrap(ErrUnknown, err)
+	}
+
+	env := event.NewEnvelope(ctx, e)
+	row := &store.OutboxRow{
+		EventID:        env.ID,
+		EventType:      env.Type,
+		Version:        env.Version,
+		Payload:        payload,
+		ActorID:        env.ActorID,
+		CorrelationID:  env.CorrelationID,
+		OccurredAt:     env.OccurredAt,
+		IdempotencyKey: uuid.New().String(),
+	}
+
+	if err := w.CreateOutboxEntry(row); err != nil {
+		return "", errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return row.IdempotencyKey, nil
+}
+
+func (s *service) Relay() (int, error) {
+	now := time.Now()
+	rows, err := s.repository.ClaimOutboxEntries(s.maxAttempts, s.batchSize, now, now.Add(s.claimTimeout))
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	published := 0
+	for _, row := range rows {
+		env, err := s.decode(row)
+		if err != nil {
+			log.Printf("decode outbox entry %d failed: %v", row.ID, err)
+			if err := s.repository.MarkOutboxEntryFailed(row.ID, err.Error()); err != nil {
+				return published, errorutil.Wrap(ErrUnknown, err)
+			}
+			continue
+		}
+
+		s.bus.Publish(env)
+		published++
+
+		if err := s.repository.MarkOutboxEntryDelivered(row.ID); err != nil {
+			return published, errorutil.Wrap(ErrUnknown, err)
+		}
+	}
+
+	return published, nil
+}
+
+// decode returns the envelope stored in row, with the payload upcast to the current version of its type
+func (s *service) decode(row *store.OutboxRow) (*event.Envelope, error) {
+	e, err := s.registry.Decode(row.EventType, row.Version, row.Payload)
+	if errors.Is(err, event.ErrNotRegistered) {
+		return nil, errorutil.Wrap(ErrEventNotRegistered, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ptr := reflect.New(reflect.TypeOf(e))
+	ptr.Elem().Set(reflect.ValueOf(e))
+	if i, ok := ptr.Interface().(Idempotent); ok {
+		i.SetIdempotencyKey(row.IdempotencyKey)
+	}
+	e = ptr.Elem().Interface()
+
+	return &event.Envelope{
+		ID:            row.EventID,
+		Type:          event.Name(e),
+		Version:       event.Version(e),
+		OccurredAt:    row.OccurredAt,
+		ActorID:       row.ActorID,
+		CorrelationID: row.CorrelationID,
+		Payload:       e,
+	}, nil
+}
+
+func (s *service) Cleanup() (int, error) {
+	n, err := s.repository.DeleteDeliveredOutboxEntries(time.Now().Add(-s.retention))
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,230 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victornm/es-backend/pkg/event"
+	. "github.com/victornm/es-backend/pkg/outbox"
+	"github.com/victornm/es-backend/pkg/store/memory"
+)
+
+type courseApproved struct {
+	Key
+	CourseID int
+}
+
+type lessonPublished struct {
+	LessonID int
+}
+
+func TestRelay(t *testing.T) {
+	repository := memory.NewOutboxGateway()
+	bus := event.NewBus()
+	sub := bus.Subscribe(courseApproved{}, nil)
+
+	s := New(&Config{
+		Repository: repository,
+		Bus:        bus,
+		Events:     []interface{}{courseApproved{}},
+	})
+
+	key, err := Add(context.Background(), repository, courseApproved{CourseID: 1})
+	require.NoError(t, err)
+
+	published, err := s.Relay()
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	received := (<-sub.C()).(courseApproved)
+	assert.Equal(t, 1, received.CourseID)
+	assert.Equal(t, key, received.IdempotencyKey)
+
+	published, err = s.Relay()
+	require.NoError(t, err)
+	assert.Equal(t, 0, published, "delivered entries should not be relayed again")
+}
+
+func TestRelay_Envelope(t *testing.T) {
+	repository := memory.NewOutboxGateway()
+	bus := event.NewBus()
+	received := make(chan *event.Envelope, 1)
+	_, _ = bus.Handle(func(ctx context.Context, e courseApproved) error {
+		received <- event.EnvelopeFromContext(ctx)
+		return nil
+	}, nil)
+
+	s := New(&Config{
+		Repository: repository,
+		Bus:        bus,
+		Events:     []interface{}{courseApproved{}},
+	})
+
+	ctx := event.WithCorrelationID(event.WithActor(context.Background(), 7), "request-1")
+	_, err := Add(ctx, repository, courseApproved{CourseID: 1})
+	require.NoError(t, err)
+
+	rows, _ := repository.FindPendingOutboxEntries(100, 10)
+	require.Len(t, rows, 1)
+
+	_, err = s.Relay()
+	require.NoError(t, err)
+
+	env := <-received
+	assert.Equal(t, rows[0].EventID, env.ID, "the stored envelope should be published")
+	assert.Equal(t, 7, env.ActorID)
+	assert.Equal(t, "request-1", env.CorrelationID)
+	assert.Equal(t, rows[0].OccurredAt, env.OccurredAt)
+}
+
+func TestRelay_AtLeastOnce(t *testing.T) {
+	repository := &failingRepository{OutboxGateway: memory.NewOutboxGateway()}
+	bus := event.NewBus()
+	sub := bus.Subscribe(courseApproved{}, nil)
+
+	s := New(&Config{
+		Repository:   repository,
+		Bus:          bus,
+		Events:       []interface{}{courseApproved{}},
+		ClaimTimeout: 50 * time.Millisecond,
+	})
+
+	_, _ = Add(context.Background(), repository, courseApproved{CourseID: 1})
+
+	// the process stops after publishing, before the entry is marked
+	repository.failMark = true
+	_, err := s.Relay()
+	assertIsError(t, ErrUnknown, err)
+
+	repository.failMark = false
+	published, err := s.Relay()
+	require.NoError(t, err)
+	assert.Equal(t, 0, published, "the entry should stay claimed")
+
+	time.Sleep(100 * time.Millisecond)
+	published, err = s.Relay()
+	require.NoError(t, err)
+	assert.Equal(t, 1, published, "the entry should be relayed again once the claim expired")
+
+	deduper := NewDeduper(10)
+	first := (<-sub.C()).(courseApproved)
+	second := (<-sub.C()).(courseApproved)
+	assert.False(t, deduper.Seen(first.IdempotencyKey))
+	assert.True(t, deduper.Seen(second.IdempotencyKey), "the second delivery should be detected")
+}
+
+func TestRelay_NotRegistered(t *testing.T) {
+	repository := memory.NewOutboxGateway()
+	s := New(&Config{
+		Repository:  repository,
+		Bus:         event.NewBus(),
+		MaxAttempts: 2,
+	})
+
+	_, _ = Add(context.Background(), repository, lessonPublished{LessonID: 1})
+
+	for i := 0; i < 2; i++ {
+		published, err := s.Relay()
+		require.NoError(t, err)
+		assert.Equal(t, 0, published)
+	}
+
+	pending, _ := repository.FindPendingOutboxEntries(100, 10)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 2, pending[0].Attempts)
+	assert.Contains(t, pending[0].LastError, ErrEventNotRegistered.Error())
+
+	pending, _ = repository.FindPendingOutboxEntries(2, 10)
+	assert.Empty(t, pending, "entries should not be tried more than MaxAttempts")
+}
+
+func TestRelay_Claim(t *testing.T) {
+	repository := memory.NewOutboxGateway()
+	s := New(&Config{
+		Repository: repository,
+		Bus:        event.NewBus(),
+		Events:     []interface{}{courseApproved{}},
+	})
+
+	_, _ = Add(context.Background(), repository, courseApproved{CourseID: 1})
+	_, _ = Add(context.Background(), repository, courseApproved{CourseID: 2})
+
+	// claimed by the relay of another process
+	claimed, err := repository.ClaimOutboxEntries(5, 1, time.Now(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	published, err := s.Relay()
+	require.NoError(t, err)
+	assert.Equal(t, 1, published, "claimed entries should be skipped")
+
+	published, err = s.Relay()
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+}
+
+func TestCleanup(t *testing.T) {
+	repository := memory.NewOutboxGateway()
+	s := New(&Config{
+		Repository: repository,
+		Bus:        event.NewBus(),
+		Events:     []interface{}{courseApproved{}},
+		Retention:  10 * time.Millisecond,
+	})
+
+	_, _ = Add(context.Background(), repository, courseApproved{CourseID: 1})
+	_, _ = s.Relay()
+	_, _ = Add(context.Background(), repository, courseApproved{CourseID: 2})
+
+	time.Sleep(20 * time.Millisecond)
+
+	deleted, err := s.Cleanup()
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	pending, _ := repository.FindPendingOutboxEntries(100, 10)
+	assert.Len(t, pending, 1, "pending entries should be kept")
+}
+
+func TestDeduper(t *testing.T) {
+	d := NewDeduper(2)
+
+	assert.False(t, d.Seen("a"))
+	assert.True(t, d.Seen("a"))
+	assert.False(t, d.Seen(""))
+	assert.False(t, d.Seen(""))
+
+	assert.False(t, d.Seen("b"))
+	assert.False(t, d.Seen("c"))
+	assert.False(t, d.Seen("a"), "oldest keys should be forgotten")
+
+	d.Forget("a")
+	assert.False(t, d.Seen("a"), "forgotten keys should not be seen")
+}
+
+type failingRepository struct {
+	*memory.OutboxGateway
+	failMark bool
+}
+
+func (r *failingRepository) MarkOutboxEntryDelivered(id int64) error {
+	if r.failMark {
+		return errors.New("connection lost")
+	}
+
+	return r.OutboxGateway.MarkOutboxEntryDelivered(id)
+}
+
+var _ Repository = (*failingRepository)(nil)
+
+func assertIsError(t *testing.T, wanted, got error) {
+	t.Helper()
+	if !errors.Is(got, wanted) {
+		t.Errorf("Error %v is not an %v", got, wanted)
+	}
+}
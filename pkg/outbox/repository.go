@@ -0,0 +1,25 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+// Writer stores outbox entries, it should share the transaction of the domain change
+type Writer interface {
+	CreateOutboxEntry(e *store.OutboxRow) error
+}
+
+type Repository interface {
+	Writer
+
+	// ClaimOutboxEntries hides up to limit undelivered entries tried less than maxAttempts times until lockedUntil, oldest first.
+	// Entries are claimed when they are not hidden at now, so relays in several processes do not publish the same entry.
+	ClaimOutboxEntries(maxAttempts, limit int, now, lockedUntil time.Time) ([]*store.OutboxRow, error)
+	MarkOutboxEntryDelivered(id int64) error
+
+	// MarkOutboxEntryFailed counts a failed attempt and releases the claim on the entry
+	MarkOutboxEntryFailed(id int64, reason string) error
+	DeleteDeliveredOutboxEntries(before time.Time) (int, error)
+}
@@ -0,0 +1,8 @@
+package queue
+
+import "errors"
+
+var (
+	ErrInvalidInput = errors.New("invalid input")
+	ErrUnknown      = errors.New("unknown error")
+)
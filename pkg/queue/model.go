@@ -0,0 +1,9 @@
+package queue
+
+// Task statuses, tasks are deleted once they succeed
+const (
+	StatusQueued = "queued"
+
+	// StatusDead tasks failed MaxAttempts times and are kept for inspection
+	StatusDead = "dead"
+)
@@ -0,0 +1,387 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var _ Worker = (*worker)(nil)
+
+// Handler runs a task, payload is the JSON given to Enqueue.
+// ctx is cancelled when the worker gives up draining, the task is then run again by another worker.
+// A task may run more than once, if its worker stops before recording the result, so handlers should be idempotent.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+// Enqueue stores a task of the kind to run at runAt, or as soon as possible if runAt is zero.
+// payload is encoded as JSON.
+func Enqueue(w Writer, kind string, payload interface{}, runAt time.Time) (int, error) {
+	t, err := newTask(kind, payload, runAt)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := w.CreateTask(t)
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return id, nil
+}
+
+// EnqueueUnique is Enqueue, unless a queued task, waiting or running, has the key.
+// It returns 0 if the task was not stored.
+func EnqueueUnique(w Writer, kind, key string, payload interface{}, runAt time.Time) (int, error) {
+	if key == "" {
+		return 0, errorutil.Wrap(ErrInvalidInput, "key is required")
+	}
+
+	t, err := newTask(kind, payload, runAt)
+	if err != nil {
+		return 0, err
+	}
+	t.UniqueKey = key
+
+	created, err := w.CreateUniqueTask(t)
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	if !created {
+		return 0, nil
+	}
+
+	return t.ID, nil
+}
+
+func newTask(kind string, payload interface{}, runAt time.Time) (*store.TaskRow, error) {
+	if kind == "" {
+		return nil, errorutil.Wrap(ErrInvalidInput, "kind is required")
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrInvalidInput, err)
+	}
+
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+
+	return &store.TaskRow{
+		Kind:    kind,
+		Payload: data,
+		Status:  StatusQueued,
+		RunAt:   runAt,
+	}, nil
+}
+
+// Worker runs queued tasks. Workers in any number of processes can share a queue:
+// a claimed task is hidden from the others for the visibility timeout, which is extended while the task runs.
+// Tasks of a worker which stopped without finishing them are claimed again once the timeout expires.
+type Worker interface {
+	// Handle adds the handler of the tasks of the kind, it must be called before Run
+	Handle(kind string, h Handler)
+
+	// Run claims and runs tasks until ctx is done,
+	// then stops claiming and waits up to the drain timeout for the running tasks.
+	// Tasks still running after the drain timeout are cancelled and released to other workers.
+	Run(ctx context.Context)
+}
+
+type Config struct {
+	Repository Repository
+
+	// Concurrency is the number of tasks run at the same time
+	Concurrency int
+
+	// VisibilityTimeout is how long a claimed task is hidden from other workers without a heartbeat
+	VisibilityTimeout time.Duration
+
+	// PollInterval is how often the queue is checked while the worker is idle
+	PollInterval time.Duration
+
+	// DrainTimeout is the longest time Run waits for the running tasks after ctx is done
+	DrainTimeout time.Duration
+
+	// MaxAttempts is the number of times a task is tried before it is dead
+	MaxAttempts int
+
+	// RetryDelay is the delay before the first retry, it doubles with every attempt up to MaxRetryDelay
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+}
+
+const (
+	defaultConcurrency       = 4
+	defaultVisibilityTimeout = 5 * time.Minute
+	defaultPollInterval      = time.Second
+	defaultDrainTimeout      = 30 * time.Second
+	defaultMaxAttempts       = 5
+	defaultRetryDelay        = 10 * time.Second
+	defaultMaxRetryDelay     = time.Hour
+)
+
+type worker struct {
+	repository        Repository
+	concurrency       int
+	visibilityTimeout time.Duration
+	pollInterval      time.Duration
+	drainTimeout      time.Duration
+	maxAttempts       int
+	retryDelay        time.Duration
+	maxRetryDelay     time.Duration
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+
+	// wake is signaled when a task finishes, so the freed slot is filled without waiting for the next poll
+	wake chan struct{}
+	wg   sync.WaitGroup
+}
+
+func New(config *Config) Worker {
+	w := &worker{
+		repository:        config.Repository,
+		concurrency:       config.Concurrency,
+		visibilityTimeout: config.VisibilityTimeout,
+		pollInterval:      config.PollInterval,
+		drainTimeout:      config.DrainTimeout,
+		maxAttempts:       config.MaxAttempts,
+		retryDelay:        config.RetryDelay,
+		maxRetryDelay:     config.MaxRetryDelay,
+		handlers:          make(map[string]Handler),
+		wake:              make(chan struct{}, 1),
+	}
+
+	if w.concurrency <= 0 {
+		w.concurrency = defaultConcurrency
+	}
+
+	if w.visibilityTimeout <= 0 {
+		w.visibilityTimeout = defaultVisibilityTimeout
+	}
+
+	if w.pollInterval <= 0 {
+		w.pollInterval = defaultPollInterval
+	}
+
+	if w.drainTimeout <= 0 {
+		w.drainTimeout = defaultDrainTimeout
+	}
+
+	if w.maxAttempts <= 0 {
+		w.maxAttempts = defaultMaxAttempts
+	}
+
+	if w.retryDelay <= 0 {
+		w.retryDelay = defaultRetryDelay
+	}
+
+	if w.maxRetryDelay <= 0 {
+		w.maxRetryDelay = defaultMaxRetryDelay
+	}
+
+	return w
+}
+
+func (w *worker) Handle(kind string, h Handler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.handlers[kind] = h
+}
+
+func (w *worker) Run(ctx context.Context) {
+	w.mu.Lock()
+	kinds := make([]string, 0, len(w.handlers))
+	for kind := range w.handlers {
+		kinds = append(kinds, kind)
+	}
+	w.mu.Unlock()
+
+	// tasks are not cancelled with ctx, they are given the drain timeout to finish
+	taskCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	slots := make(chan struct{}, w.concurrency)
+	for {
+		if free := w.concurrency - len(slots); free > 0 && len(kinds) > 0 {
+			tasks, err := w.repository.ClaimTasks(StatusQueued, kinds, time.Now(), time.Now().Add(w.visibilityTimeout), free)
+			if err != nil {
+				log.Printf("claim tasks failed: %v", err)
+			}
+
+			for _, t := range tasks {
+				slots <- struct{}{}
+				w.wg.Add(1)
+				go func(t *store.TaskRow) {
+					defer func() { <-slots }()
+					w.process(taskCtx, t)
+				}(t)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			w.drain(cancel)
+			return
+
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// drain waits for the running tasks, cancelling them after the drain timeout
+func (w *worker) drain(cancel context.CancelFunc) {
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(w.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		log.Printf("tasks still running after %v, cancelling them", w.drainTimeout)
+		cancel()
+		<-done
+	}
+}
+
+func (w *worker) process(ctx context.Context, t *store.TaskRow) {
+	defer func() {
+		w.wg.Done()
+
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}()
+
+	// claiming counts an attempt, so a task which keeps crashing its worker ends up dead without running
+	if t.Attempts > w.maxAttempts {
+		w.fail(t, fmt.Errorf("abandoned by its worker %d times", t.Attempts-1))
+		return
+	}
+
+	w.mu.Lock()
+	h := w.handlers[t.Kind]
+	w.mu.Unlock()
+
+	stop := w.heartbeat(t)
+	err := call(ctx, h, t)
+	stop()
+
+	switch {
+	case err == nil:
+		if err := w.repository.DeleteTask(t.ID); err != nil {
+			log.Printf("delete task %d failed: %v", t.ID, err)
+		}
+
+	case ctx.Err() != nil:
+		// cancelled by the drain, this attempt does not count against the task
+		t.Attempts--
+		t.LockedUntil = nil
+		w.update(t)
+
+	default:
+		log.Printf("task %d (%s) failed: %v", t.ID, t.Kind, err)
+		w.fail(t, err)
+	}
+}
+
+// heartbeat extends the visibility timeout of the task until the returned func is called,
+// or until the task is claimed by another worker because a heartbeat came too late
+func (w *worker) heartbeat(t *store.TaskRow) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(w.visibilityTimeout / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				claimed, err := w.repository.ExtendTask(t.ID, t.Attempts, time.Now().Add(w.visibilityTimeout))
+				if err != nil {
+					log.Printf("extend task %d failed: %v", t.ID, err)
+					continue
+				}
+
+				if !claimed {
+					log.Printf("task %d was claimed by another worker, it may run twice", t.ID)
+					<-stop
+					return
+				}
+
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// fail schedules a retry of the task, or marks it dead after MaxAttempts
+func (w *worker) fail(t *store.TaskRow, err error) {
+	t.LastError = err.Error()
+	t.LockedUntil = nil
+
+	if t.Attempts >= w.maxAttempts {
+		t.Status = StatusDead
+	} else {
+		t.RunAt = time.Now().Add(w.backoff(t.Attempts))
+	}
+
+	w.update(t)
+}
+
+func (w *worker) update(t *store.TaskRow) {
+	if err := w.repository.UpdateTask(t); err != nil {
+		log.Printf("update task %d failed: %v", t.ID, err)
+	}
+}
+
+func (w *worker) backoff(attempts int) time.Duration {
+	delay := w.retryDelay
+	for i := 1; i < attempts && delay < w.maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > w.maxRetryDelay {
+		delay = w.maxRetryDelay
+	}
+
+	return delay
+}
+
+func call(ctx context.Context, h Handler, t *store.TaskRow) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return h(ctx, t.Payload)
+}
@@ -0,0 +1,313 @@
+package queue_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/victornm/es-backend/pkg/queue"
+	"github.com/victornm/es-backend/pkg/store"
+	"github.com/victornm/es-backend/pkg/store/memory"
+)
+
+func newWorker(repository Repository, config *Config) Worker {
+	config.Repository = repository
+	if config.PollInterval == 0 {
+		config.PollInterval = 10 * time.Millisecond
+	}
+
+	return New(config)
+}
+
+// start runs w until the returned func is called, which waits for Run to return
+func start(w Worker) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	repository := memory.NewTaskGateway()
+
+	id, err := Enqueue(repository, "email.send", map[string]string{"to": "foo@bar.com"}, time.Time{})
+	require.NoError(t, err)
+
+	task, err := repository.FindTaskByID(id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, task.Status)
+	assert.JSONEq(t, `{"to": "foo@bar.com"}`, string(task.Payload))
+	assert.False(t, task.RunAt.After(time.Now()))
+
+	_, err = Enqueue(repository, "", nil, time.Time{})
+	assertIsError(t, ErrInvalidInput, err)
+}
+
+func TestEnqueueUnique(t *testing.T) {
+	repository := memory.NewTaskGateway()
+
+	id, err := EnqueueUnique(repository, "report.build", "daily", nil, time.Time{})
+	require.NoError(t, err)
+	assert.NotZero(t, id)
+
+	again, err := EnqueueUnique(repository, "report.build", "daily", nil, time.Time{})
+	require.NoError(t, err)
+	assert.Zero(t, again, "a queued task has the key")
+
+	task, _ := repository.FindTaskByID(id)
+	task.Status = StatusDead
+	require.NoError(t, repository.UpdateTask(task))
+
+	again, err = EnqueueUnique(repository, "report.build", "daily", nil, time.Time{})
+	require.NoError(t, err)
+	assert.NotZero(t, again, "dead tasks should not keep their key")
+
+	_, err = EnqueueUnique(repository, "report.build", "", nil, time.Time{})
+	assertIsError(t, ErrInvalidInput, err)
+}
+
+func TestRun(t *testing.T) {
+	repository := memory.NewTaskGateway()
+	w := newWorker(repository, &Config{})
+
+	received := make(chan string, 10)
+	w.Handle("greet", func(ctx context.Context, payload json.RawMessage) error {
+		var name string
+		if err := json.Unmarshal(payload, &name); err != nil {
+			return err
+		}
+
+		received <- name
+		return nil
+	})
+
+	first, _ := Enqueue(repository, "greet", "foo", time.Time{})
+	later, _ := Enqueue(repository, "greet", "later", time.Now().Add(time.Hour))
+	other, _ := Enqueue(repository, "other", "bar", time.Time{})
+
+	stop := start(w)
+	assert.Equal(t, "foo", <-received)
+	stop()
+
+	assert.Empty(t, received, "tasks should wait for their time")
+
+	_, err := repository.FindTaskByID(first)
+	assert.Error(t, err, "done tasks should be deleted")
+
+	_, err = repository.FindTaskByID(later)
+	assert.NoError(t, err)
+
+	task, err := repository.FindTaskByID(other)
+	require.NoError(t, err)
+	assert.Equal(t, 0, task.Attempts, "tasks without a handler should not be claimed")
+}
+
+func TestRun_Retry(t *testing.T) {
+	repository := memory.NewTaskGateway()
+	w := newWorker(repository, &Config{
+		MaxAttempts:   3,
+		RetryDelay:    time.Millisecond,
+		MaxRetryDelay: time.Millisecond,
+	})
+
+	var calls int32
+	w.Handle("fail", func(ctx context.Context, payload json.RawMessage) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("smtp unavailable")
+	})
+
+	var panics int32
+	w.Handle("panic", func(ctx context.Context, payload json.RawMessage) error {
+		atomic.AddInt32(&panics, 1)
+		panic("boom")
+	})
+
+	failID, _ := Enqueue(repository, "fail", nil, time.Time{})
+	panicID, _ := Enqueue(repository, "panic", nil, time.Time{})
+
+	stop := start(w)
+	assert.Eventually(t, func() bool {
+		f, _ := repository.FindTaskByID(failID)
+		p, _ := repository.FindTaskByID(panicID)
+		return f.Status == StatusDead && p.Status == StatusDead
+	}, time.Second, 10*time.Millisecond)
+	stop()
+
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+	assert.EqualValues(t, 3, atomic.LoadInt32(&panics))
+
+	task, _ := repository.FindTaskByID(failID)
+	assert.Equal(t, "smtp unavailable", task.LastError)
+	assert.Nil(t, task.LockedUntil)
+}
+
+func TestRun_Concurrency(t *testing.T) {
+	repository := memory.NewTaskGateway()
+	w := newWorker(repository, &Config{Concurrency: 2})
+
+	var (
+		mu              sync.Mutex
+		running, maxRun int
+		done            int32
+	)
+	w.Handle("slow", func(ctx context.Context, payload json.RawMessage) error {
+		mu.Lock()
+		running++
+		if running > maxRun {
+			maxRun = running
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		atomic.AddInt32(&done, 1)
+		return nil
+	})
+
+	for i := 0; i < 6; i++ {
+		_, _ = Enqueue(repository, "slow", i, time.Time{})
+	}
+
+	stop := start(w)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&done) == 6
+	}, time.Second, 10*time.Millisecond)
+	stop()
+
+	assert.Equal(t, 2, maxRun)
+}
+
+func TestRun_VisibilityTimeout(t *testing.T) {
+	repository := memory.NewTaskGateway()
+
+	var calls int32
+	handler := func(ctx context.Context, payload json.RawMessage) error {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}
+
+	// the heartbeat keeps the task hidden from the other worker for longer than the timeout
+	config := Config{VisibilityTimeout: 30 * time.Millisecond}
+	w1 := newWorker(repository, &config)
+	w1.Handle("slow", handler)
+	w2 := newWorker(repository, &config)
+	w2.Handle("slow", handler)
+
+	id, _ := Enqueue(repository, "slow", nil, time.Time{})
+
+	stop1, stop2 := start(w1), start(w2)
+	assert.Eventually(t, func() bool {
+		_, err := repository.FindTaskByID(id)
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	stop1()
+	stop2()
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	t.Run("abandoned task", func(t *testing.T) {
+		// claimed by a worker which crashed
+		past := time.Now().Add(-time.Second)
+		id, _ := repository.CreateTask(&store.TaskRow{Kind: "slow", Status: StatusQueued, Attempts: 1, RunAt: past, LockedUntil: &past})
+
+		w := newWorker(repository, &Config{})
+		w.Handle("slow", handler)
+
+		stop := start(w)
+		assert.Eventually(t, func() bool {
+			_, err := repository.FindTaskByID(id)
+			return err != nil
+		}, time.Second, 10*time.Millisecond)
+		stop()
+	})
+
+	t.Run("claimed again", func(t *testing.T) {
+		id, _ := Enqueue(repository, "other", nil, time.Time{})
+
+		first, err := repository.ClaimTasks(StatusQueued, []string{"other"}, time.Now(), time.Now(), 1)
+		require.NoError(t, err)
+		require.Len(t, first, 1)
+
+		// the claim of the first worker expired before its heartbeat
+		second, err := repository.ClaimTasks(StatusQueued, []string{"other"}, time.Now(), time.Now().Add(time.Hour), 1)
+		require.NoError(t, err)
+		require.Len(t, second, 1)
+
+		claimed, err := repository.ExtendTask(id, first[0].Attempts, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.False(t, claimed, "the first worker should not extend the claim of the second")
+
+		claimed, err = repository.ExtendTask(id, second[0].Attempts, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.True(t, claimed)
+	})
+}
+
+func TestRun_Drain(t *testing.T) {
+	repository := memory.NewTaskGateway()
+	w := newWorker(repository, &Config{DrainTimeout: time.Second})
+
+	started := make(chan struct{})
+	w.Handle("slow", func(ctx context.Context, payload json.RawMessage) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+
+	id, _ := Enqueue(repository, "slow", nil, time.Time{})
+
+	stop := start(w)
+	<-started
+	stop()
+
+	_, err := repository.FindTaskByID(id)
+	assert.Error(t, err, "the running task should finish before Run returns")
+
+	t.Run("timeout", func(t *testing.T) {
+		w := newWorker(repository, &Config{DrainTimeout: 20 * time.Millisecond})
+
+		started := make(chan struct{})
+		w.Handle("stuck", func(ctx context.Context, payload json.RawMessage) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		id, _ := Enqueue(repository, "stuck", nil, time.Time{})
+
+		stop := start(w)
+		<-started
+		stop()
+
+		task, err := repository.FindTaskByID(id)
+		require.NoError(t, err)
+		assert.Equal(t, StatusQueued, task.Status)
+		assert.Equal(t, 0, task.Attempts, "cancelled attempts should not count")
+		assert.Nil(t, task.LockedUntil, "the task should be released to other workers")
+	})
+}
+
+func assertIsError(t *testing.T, wanted, got error, msgAndArgs ...interface{}) {
+	t.Helper()
+	if !errors.Is(got, wanted) {
+		t.Errorf("Error %v is not an %v %v", got, wanted, msgAndArgs)
+	}
+}
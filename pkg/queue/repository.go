@@ -0,0 +1,31 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+// Writer stores tasks, it may share the transaction of the change producing the task
+type Writer interface {
+	CreateTask(t *store.TaskRow) (int, error)
+
+	// CreateUniqueTask creates t unless a queued task has the same unique key, it returns true if t was created
+	CreateUniqueTask(t *store.TaskRow) (bool, error)
+}
+
+type Repository interface {
+	Writer
+
+	FindTaskByID(id int) (*store.TaskRow, error)
+
+	// ClaimTasks hides up to limit tasks with the status and one of the kinds until lockedUntil, and counts an attempt.
+	// Tasks are claimed when they are due at now and not hidden, the soonest first.
+	ClaimTasks(status string, kinds []string, now, lockedUntil time.Time, limit int) ([]*store.TaskRow, error)
+
+	// ExtendTask hides a task claimed with the attempts until lockedUntil.
+	// It returns false if the task was claimed again since, by another worker once the claim expired.
+	ExtendTask(id, attempts int, lockedUntil time.Time) (bool, error)
+	UpdateTask(t *store.TaskRow) error
+	DeleteTask(id int) error
+}
@@ -0,0 +1,20 @@
+package store
+
+import "time"
+
+type DeadLetterRow struct {
+	ID            int       `db:"id"`
+	Handler       string    `db:"handler"`
+	EventID       string    `db:"event_id"`
+	EventType     string    `db:"event_type"`
+	Version       int       `db:"version"`
+	Payload       []byte    `db:"payload"` // JSON encoded event
+	ActorID       int       `db:"actor_id"`
+	CorrelationID string    `db:"correlation_id"`
+	OccurredAt    time.Time `db:"occurred_at"`
+	Error         string    `db:"error"`
+	Attempts      int       `db:"attempts"`
+	Status        string    `db:"status"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
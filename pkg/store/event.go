@@ -0,0 +1,15 @@
+package store
+
+import "time"
+
+type EventRow struct {
+	Offset        int64     `db:"offset"`
+	EventID       string    `db:"event_id"`
+	EventType     string    `db:"event_type"`
+	Version       int       `db:"version"`
+	Payload       []byte    `db:"payload"` // JSON encoded event
+	ActorID       int       `db:"actor_id"`
+	CorrelationID string    `db:"correlation_id"`
+	OccurredAt    time.Time `db:"occurred_at"`
+	CreatedAt     time.Time `db:"created_at"`
+}
@@ -0,0 +1,9 @@
+package store
+
+import "time"
+
+type FollowRow struct {
+	FollowerID int       `db:"follower_id"`
+	FolloweeID int       `db:"followee_id"`
+	CreatedAt  time.Time `db:"created_at"`
+}
@@ -0,0 +1,24 @@
+package store
+
+import "time"
+
+type JobRow struct {
+	ID        int       `db:"id"`
+	Name      string    `db:"name"`
+	Schedule  string    `db:"schedule"` // cron expression of periodic jobs, empty for one-off jobs
+	Payload   []byte    `db:"payload"`  // JSON
+	RunAt     time.Time `db:"run_at"`
+	Status    string    `db:"status"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type JobRunRow struct {
+	ID         int        `db:"id"`
+	JobID      int        `db:"job_id"`
+	Name       string     `db:"name"`
+	Status     string     `db:"status"`
+	Error      string     `db:"error"`
+	StartedAt  time.Time  `db:"started_at"`
+	FinishedAt *time.Time `db:"finished_at"`
+}
@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var GlobalDeadLetterStore = NewDeadLetterGateway()
+
+type DeadLetterGateway struct {
+	mu          *sync.Mutex
+	currentID   int
+	deadLetters []*store.DeadLetterRow
+}
+
+func (gw *DeadLetterGateway) CreateDeadLetter(l *store.DeadLetterRow) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.currentID++
+	l.ID = gw.currentID
+	l.CreatedAt = time.Now()
+	l.UpdatedAt = l.CreatedAt
+	gw.deadLetters = append(gw.deadLetters, copyDeadLetter(l))
+
+	return l.ID, nil
+}
+
+func (gw *DeadLetterGateway) FindDeadLetterByID(id int) (*store.DeadLetterRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, l := range gw.deadLetters {
+		if l.ID == id {
+			return copyDeadLetter(l), nil
+		}
+	}
+
+	return nil, errors.New("dead letter not found")
+}
+
+// FindDeadLetters returns dead letters with the status, oldest first
+func (gw *DeadLetterGateway) FindDeadLetters(status string, offset, limit int) ([]*store.DeadLetterRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.DeadLetterRow
+	for _, l := range gw.deadLetters {
+		if l.Status != status {
+			continue
+		}
+
+		if offset > 0 {
+			offset--
+			continue
+		}
+
+		if len(rows) == limit {
+			break
+		}
+
+		rows = append(rows, copyDeadLetter(l))
+	}
+
+	return rows, nil
+}
+
+func (gw *DeadLetterGateway) ClaimDeadLetters(status, claimedStatus string, limit int) ([]*store.DeadLetterRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.DeadLetterRow
+	for _, l := range gw.deadLetters {
+		if len(rows) == limit {
+			break
+		}
+
+		if l.Status == status {
+			l.Status = claimedStatus
+			l.UpdatedAt = time.Now()
+			rows = append(rows, copyDeadLetter(l))
+		}
+	}
+
+	return rows, nil
+}
+
+func (gw *DeadLetterGateway) UpdateDeadLetter(l *store.DeadLetterRow) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, old := range gw.deadLetters {
+		if old.ID == l.ID {
+			l.UpdatedAt = time.Now()
+			gw.deadLetters[i] = copyDeadLetter(l)
+			return nil
+		}
+	}
+
+	return errors.New("dead letter not found")
+}
+
+func (gw *DeadLetterGateway) DeleteDeadLetter(id int) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, l := range gw.deadLetters {
+		if l.ID == id {
+			gw.deadLetters = append(gw.deadLetters[:i], gw.deadLetters[i+1:]...)
+			return nil
+		}
+	}
+
+	return errors.New("dead letter not found")
+}
+
+func (gw *DeadLetterGateway) Clear() {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.currentID = 0
+	gw.deadLetters = nil
+}
+
+func NewDeadLetterGateway() *DeadLetterGateway {
+	return &DeadLetterGateway{mu: new(sync.Mutex)}
+}
+
+func copyDeadLetter(l *store.DeadLetterRow) *store.DeadLetterRow {
+	row := *l
+	row.Payload = append([]byte(nil), l.Payload...)
+
+	return &row
+}
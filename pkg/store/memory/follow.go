@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var GlobalFollowStore = NewFollowGateway()
+
+type FollowGateway struct {
+	mu      *sync.Mutex
+	follows []*store.FollowRow
+}
+
+func (gw *FollowGateway) CreateFollow(f *store.FollowRow) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, row := range gw.follows {
+		if row.FollowerID == f.FollowerID && row.FolloweeID == f.FolloweeID {
+			return errors.New("follow existed")
+		}
+	}
+
+	f.CreatedAt = time.Now()
+	row := *f
+	gw.follows = append(gw.follows, &row)
+
+	return nil
+}
+
+func (gw *FollowGateway) DeleteFollow(followerID, followeeID int) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, row := range gw.follows {
+		if row.FollowerID == followerID && row.FolloweeID == followeeID {
+			gw.follows = append(gw.follows[:i], gw.follows[i+1:]...)
+			return nil
+		}
+	}
+
+	return errors.New("follow not found")
+}
+
+func (gw *FollowGateway) FindFollowers(userID, offset, limit int) ([]*store.FollowRow, error) {
+	return gw.find(func(f *store.FollowRow) bool { return f.FolloweeID == userID }, offset, limit), nil
+}
+
+func (gw *FollowGateway) FindFollowing(userID, offset, limit int) ([]*store.FollowRow, error) {
+	return gw.find(func(f *store.FollowRow) bool { return f.FollowerID == userID }, offset, limit), nil
+}
+
+// find returns matched rows, newest first
+func (gw *FollowGateway) find(match func(f *store.FollowRow) bool, offset, limit int) []*store.FollowRow {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.FollowRow
+	for i := len(gw.follows) - 1; i >= 0; i-- {
+		if match(gw.follows[i]) {
+			row := *gw.follows[i]
+			rows = append(rows, &row)
+		}
+	}
+
+	if offset >= len(rows) {
+		return nil
+	}
+	rows = rows[offset:]
+	if limit < len(rows) {
+		rows = rows[:limit]
+	}
+
+	return rows
+}
+
+func (gw *FollowGateway) CountFollowers(userID int) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	count := 0
+	for _, row := range gw.follows {
+		if row.FolloweeID == userID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (gw *FollowGateway) CountFollowing(userID int) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	count := 0
+	for _, row := range gw.follows {
+		if row.FollowerID == userID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (gw *FollowGateway) Clear() {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.follows = nil
+}
+
+func NewFollowGateway() *FollowGateway {
+	return &FollowGateway{mu: new(sync.Mutex)}
+}
@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var GlobalJobStore = NewJobGateway()
+
+type JobGateway struct {
+	mu           *sync.Mutex
+	currentJobID int
+	currentRunID int
+	jobs         []*store.JobRow
+	runs         []*store.JobRunRow
+}
+
+// CreatePeriodicJob creates j unless a periodic job with the same name exists, it returns true if j was created
+func (gw *JobGateway) CreatePeriodicJob(j *store.JobRow) (bool, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, old := range gw.jobs {
+		if old.Name == j.Name && old.Schedule != "" {
+			return false, nil
+		}
+	}
+
+	gw.create(j)
+
+	return true, nil
+}
+
+func (gw *JobGateway) CreateJob(j *store.JobRow) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.create(j)
+
+	return j.ID, nil
+}
+
+func (gw *JobGateway) create(j *store.JobRow) {
+	gw.currentJobID++
+	j.ID = gw.currentJobID
+	j.CreatedAt = time.Now()
+	j.UpdatedAt = j.CreatedAt
+	gw.jobs = append(gw.jobs, copyJob(j))
+}
+
+func (gw *JobGateway) FindPeriodicJob(name string) (*store.JobRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, j := range gw.jobs {
+		if j.Name == name && j.Schedule != "" {
+			return copyJob(j), nil
+		}
+	}
+
+	return nil, errors.New("job not found")
+}
+
+// FindJobs returns jobs with the status, the soonest first
+func (gw *JobGateway) FindJobs(status string) ([]*store.JobRow, error) {
+	return gw.FindDueJobs(status, time.Time{}, 0)
+}
+
+// FindDueJobs returns jobs with the status to run at or before now, the soonest first.
+// A zero now or limit is not used.
+func (gw *JobGateway) FindDueJobs(status string, now time.Time, limit int) ([]*store.JobRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.JobRow
+	for _, j := range gw.jobs {
+		if j.Status == status && (now.IsZero() || !j.RunAt.After(now)) {
+			rows = append(rows, copyJob(j))
+		}
+	}
+
+	sort.SliceStable(rows, func(i, k int) bool {
+		return rows[i].RunAt.Before(rows[k].RunAt)
+	})
+
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+
+	return rows, nil
+}
+
+func (gw *JobGateway) UpdateJob(j *store.JobRow) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, old := range gw.jobs {
+		if old.ID == j.ID {
+			j.UpdatedAt = time.Now()
+			gw.jobs[i] = copyJob(j)
+			return nil
+		}
+	}
+
+	return errors.New("job not found")
+}
+
+func (gw *JobGateway) CreateJobRun(r *store.JobRunRow) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.currentRunID++
+	r.ID = gw.currentRunID
+	gw.runs = append(gw.runs, copyJobRun(r))
+
+	return r.ID, nil
+}
+
+func (gw *JobGateway) UpdateJobRun(r *store.JobRunRow) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, old := range gw.runs {
+		if old.ID == r.ID {
+			gw.runs[i] = copyJobRun(r)
+			return nil
+		}
+	}
+
+	return errors.New("job run not found")
+}
+
+// FindJobRuns returns the runs of the named job, or of every job if name is empty, newest first
+func (gw *JobGateway) FindJobRuns(name string, offset, limit int) ([]*store.JobRunRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.JobRunRow
+	for i := len(gw.runs) - 1; i >= 0 && len(rows) < limit; i-- {
+		r := gw.runs[i]
+		if name != "" && r.Name != name {
+			continue
+		}
+
+		if offset > 0 {
+			offset--
+			continue
+		}
+
+		rows = append(rows, copyJobRun(r))
+	}
+
+	return rows, nil
+}
+
+func (gw *JobGateway) Clear() {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.currentJobID = 0
+	gw.currentRunID = 0
+	gw.jobs = nil
+	gw.runs = nil
+}
+
+func NewJobGateway() *JobGateway {
+	return &JobGateway{mu: new(sync.Mutex)}
+}
+
+func copyJob(j *store.JobRow) *store.JobRow {
+	row := *j
+	row.Payload = append([]byte(nil), j.Payload...)
+
+	return &row
+}
+
+func copyJobRun(r *store.JobRunRow) *store.JobRunRow {
+	row := *r
+	if r.FinishedAt != nil {
+		t := *r.FinishedAt
+		row.FinishedAt = &t
+	}
+
+	return &row
+}
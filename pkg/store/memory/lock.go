@@ -0,0 +1,46 @@
+package memory
+
+import "sync"
+
+// Lock stands in for a lock shared by replicas, such as postgres.AdvisoryLock.
+// Every Session is a different holder.
+type Lock struct {
+	mu     *sync.Mutex
+	holder *LockSession
+}
+
+type LockSession struct {
+	lock *Lock
+}
+
+func NewLock() *Lock {
+	return &Lock{mu: new(sync.Mutex)}
+}
+
+func (l *Lock) Session() *LockSession {
+	return &LockSession{lock: l}
+}
+
+// TryLock returns true if the lock is held by s, taking it if it is free
+func (s *LockSession) TryLock() (bool, error) {
+	s.lock.mu.Lock()
+	defer s.lock.mu.Unlock()
+
+	if s.lock.holder == nil {
+		s.lock.holder = s
+	}
+
+	return s.lock.holder == s, nil
+}
+
+// Unlock releases the lock if it is held by s
+func (s *LockSession) Unlock() error {
+	s.lock.mu.Lock()
+	defer s.lock.mu.Unlock()
+
+	if s.lock.holder == s {
+		s.lock.holder = nil
+	}
+
+	return nil
+}
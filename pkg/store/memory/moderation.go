@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var GlobalModerationStore = NewModerationGateway()
+
+type ModerationGateway struct {
+	mu              *sync.Mutex
+	currentReportID int
+	currentActionID int
+	reports         []*store.ReportRow
+	actions         []*store.ModerationActionRow
+}
+
+func (gw *ModerationGateway) CreateReport(r *store.ReportRow) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	// same as the unique index on the unresolved reports
+	for _, row := range gw.reports {
+		if row.Status != "resolved" && row.ReporterID == r.ReporterID && row.TargetType == r.TargetType && row.TargetID == r.TargetID {
+			return 0, errors.New("report already exists")
+		}
+	}
+
+	gw.currentReportID++
+	r.ID = gw.currentReportID
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = r.CreatedAt
+	row := *r
+	gw.reports = append(gw.reports, &row)
+
+	return r.ID, nil
+}
+
+func (gw *ModerationGateway) FindReportByID(id int) (*store.ReportRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, r := range gw.reports {
+		if r.ID == id {
+			row := *r
+			return &row, nil
+		}
+	}
+
+	return nil, errors.New("report not found")
+}
+
+func (gw *ModerationGateway) FindReportsByStatus(statuses []string, offset, limit int) ([]*store.ReportRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.ReportRow
+	for _, r := range gw.reports {
+		for _, status := range statuses {
+			if r.Status == status {
+				row := *r
+				rows = append(rows, &row)
+				break
+			}
+		}
+	}
+
+	// oldest first, as a queue
+	if offset >= len(rows) {
+		return nil, nil
+	}
+	rows = rows[offset:]
+	if limit < len(rows) {
+		rows = rows[:limit]
+	}
+
+	return rows, nil
+}
+
+func (gw *ModerationGateway) FindReportsByTarget(targetType string, targetID int, statuses []string) ([]*store.ReportRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.ReportRow
+	for _, r := range gw.reports {
+		if r.TargetType != targetType || r.TargetID != targetID {
+			continue
+		}
+
+		for _, status := range statuses {
+			if r.Status == status {
+				row := *r
+				rows = append(rows, &row)
+				break
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+func (gw *ModerationGateway) UpdateReport(r *store.ReportRow) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, row := range gw.reports {
+		if row.ID == r.ID {
+			r.UpdatedAt = time.Now()
+			updated := *r
+			gw.reports[i] = &updated
+			return nil
+		}
+	}
+
+	return errors.New("report not found")
+}
+
+func (gw *ModerationGateway) CreateModerationAction(a *store.ModerationActionRow) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.currentActionID++
+	a.ID = gw.currentActionID
+	a.CreatedAt = time.Now()
+	row := *a
+	gw.actions = append(gw.actions, &row)
+
+	return a.ID, nil
+}
+
+func (gw *ModerationGateway) FindModerationActionsByTarget(targetType string, targetID int) ([]*store.ModerationActionRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.ModerationActionRow
+	for _, a := range gw.actions {
+		if a.TargetType == targetType && a.TargetID == targetID {
+			row := *a
+			rows = append(rows, &row)
+		}
+	}
+
+	return rows, nil
+}
+
+func (gw *ModerationGateway) Clear() {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.currentReportID = 0
+	gw.currentActionID = 0
+	gw.reports = nil
+	gw.actions = nil
+}
+
+func NewModerationGateway() *ModerationGateway {
+	return &ModerationGateway{mu: new(sync.Mutex)}
+}
@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var GlobalNotificationStore = NewNotificationGateway()
+
+type NotificationGateway struct {
+	mu            *sync.Mutex
+	currentID     int
+	notifications []*store.NotificationRow
+	preferences   []*store.NotificationPreferenceRow
+	unreadCounts  map[int]int
+}
+
+func (gw *NotificationGateway) CreateNotification(n *store.NotificationRow) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.currentID++
+	n.ID = gw.currentID
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	gw.notifications = append(gw.notifications, n)
+
+	return n.ID, nil
+}
+
+func (gw *NotificationGateway) FindNotificationsByUserID(userID, offset, limit int) ([]*store.NotificationRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.NotificationRow
+	for _, n := range gw.notifications {
+		if n.UserID == userID {
+			rows = append(rows, n)
+		}
+	}
+
+	// newest first
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].ID > rows[j].ID
+	})
+
+	if offset >= len(rows) {
+		return nil, nil
+	}
+	rows = rows[offset:]
+	if limit < len(rows) {
+		rows = rows[:limit]
+	}
+
+	return rows, nil
+}
+
+func (gw *NotificationGateway) CountUnreadNotifications(userID int) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	count := 0
+	for _, n := range gw.notifications {
+		if n.UserID == userID && !n.IsRead {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (gw *NotificationGateway) MarkNotificationAsRead(userID, id int, at time.Time) (bool, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, n := range gw.notifications {
+		if n.ID == id && n.UserID == userID {
+			if n.IsRead {
+				return false, nil
+			}
+
+			n.IsRead = true
+			n.ReadAt = &at
+			return true, nil
+		}
+	}
+
+	return false, errors.New("notification not found")
+}
+
+func (gw *NotificationGateway) MarkAllNotificationsAsRead(userID int, at time.Time) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	count := 0
+	for _, n := range gw.notifications {
+		if n.UserID == userID && !n.IsRead {
+			n.IsRead = true
+			n.ReadAt = &at
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (gw *NotificationGateway) FindDigestNotifications() ([]*store.NotificationRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.NotificationRow
+	for _, n := range gw.notifications {
+		if n.InDigest {
+			rows = append(rows, n)
+		}
+	}
+
+	return rows, nil
+}
+
+func (gw *NotificationGateway) RemoveFromDigest(ids []int) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, id := range ids {
+		for _, n := range gw.notifications {
+			if n.ID == id {
+				n.InDigest = false
+			}
+		}
+	}
+
+	return nil
+}
+
+func (gw *NotificationGateway) FindNotificationPreferences(userID int) ([]*store.NotificationPreferenceRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.NotificationPreferenceRow
+	for _, p := range gw.preferences {
+		if p.UserID == userID {
+			rows = append(rows, p)
+		}
+	}
+
+	return rows, nil
+}
+
+func (gw *NotificationGateway) SaveNotificationPreference(p *store.NotificationPreferenceRow) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, row := range gw.preferences {
+		if row.UserID == p.UserID && row.Type == p.Type {
+			row.Channel = p.Channel
+			return nil
+		}
+	}
+
+	gw.preferences = append(gw.preferences, p)
+
+	return nil
+}
+
+func (gw *NotificationGateway) AddUnreadCount(userID, delta int) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.unreadCounts[userID] += delta
+
+	return nil
+}
+
+func (gw *NotificationGateway) FindUnreadCount(userID int) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	return gw.unreadCounts[userID], nil
+}
+
+func (gw *NotificationGateway) DeleteUnreadCounts() error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.unreadCounts = make(map[int]int)
+
+	return nil
+}
+
+func (gw *NotificationGateway) Clear() {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.currentID = 0
+	gw.notifications = nil
+	gw.preferences = nil
+	gw.unreadCounts = make(map[int]int)
+}
+
+func NewNotificationGateway() *NotificationGateway {
+	return &NotificationGateway{mu: new(sync.Mutex), unreadCounts: make(map[int]int)}
+}
@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var GlobalOutboxStore = NewOutboxGateway()
+
+type OutboxGateway struct {
+	mu      *sync.Mutex
+	lastID  int64
+	entries []*store.OutboxRow
+}
+
+func (gw *OutboxGateway) CreateOutboxEntry(e *store.OutboxRow) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, row := range gw.entries {
+		if row.IdempotencyKey == e.IdempotencyKey {
+			return errors.New("outbox entry existed")
+		}
+	}
+
+	gw.lastID++
+	e.ID = gw.lastID
+	e.CreatedAt = time.Now()
+	row := *e
+	gw.entries = append(gw.entries, &row)
+
+	return nil
+}
+
+func (gw *OutboxGateway) ClaimOutboxEntries(maxAttempts, limit int, now, lockedUntil time.Time) ([]*store.OutboxRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.OutboxRow
+	for _, row := range gw.entries {
+		if len(rows) == limit {
+			break
+		}
+
+		if row.DeliveredAt == nil && row.Attempts < maxAttempts && (row.LockedUntil == nil || !row.LockedUntil.After(now)) {
+			until := lockedUntil
+			row.LockedUntil = &until
+			e := *row
+			rows = append(rows, &e)
+		}
+	}
+
+	return rows, nil
+}
+
+// FindPendingOutboxEntries returns the undelivered entries tried less than maxAttempts times, claimed or not
+func (gw *OutboxGateway) FindPendingOutboxEntries(maxAttempts, limit int) ([]*store.OutboxRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.OutboxRow
+	for _, row := range gw.entries {
+		if len(rows) == limit {
+			break
+		}
+
+		if row.DeliveredAt == nil && row.Attempts < maxAttempts {
+			e := *row
+			rows = append(rows, &e)
+		}
+	}
+
+	return rows, nil
+}
+
+func (gw *OutboxGateway) MarkOutboxEntryDelivered(id int64) error {
+	return gw.update(id, func(row *store.OutboxRow) {
+		now := time.Now()
+		row.DeliveredAt = &now
+	})
+}
+
+func (gw *OutboxGateway) MarkOutboxEntryFailed(id int64, reason string) error {
+	return gw.update(id, func(row *store.OutboxRow) {
+		row.Attempts++
+		row.LastError = reason
+		row.LockedUntil = nil
+	})
+}
+
+func (gw *OutboxGateway) update(id int64, f func(row *store.OutboxRow)) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, row := range gw.entries {
+		if row.ID == id {
+			f(row)
+			return nil
+		}
+	}
+
+	return errors.New("outbox entry not found")
+}
+
+func (gw *OutboxGateway) DeleteDeliveredOutboxEntries(before time.Time) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	kept := gw.entries[:0]
+	for _, row := range gw.entries {
+		if row.DeliveredAt == nil || !row.DeliveredAt.Before(before) {
+			kept = append(kept, row)
+		}
+	}
+
+	n := len(gw.entries) - len(kept)
+	gw.entries = kept
+
+	return n, nil
+}
+
+func (gw *OutboxGateway) Clear() {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.lastID = 0
+	gw.entries = nil
+}
+
+func NewOutboxGateway() *OutboxGateway {
+	return &OutboxGateway{mu: new(sync.Mutex)}
+}
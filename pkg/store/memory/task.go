@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var GlobalTaskStore = NewTaskGateway()
+
+type TaskGateway struct {
+	mu        *sync.Mutex
+	currentID int
+	tasks     []*store.TaskRow
+}
+
+func (gw *TaskGateway) CreateTask(t *store.TaskRow) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.create(t)
+
+	return t.ID, nil
+}
+
+// CreateUniqueTask creates t unless a queued task has the same unique key, it returns true if t was created
+func (gw *TaskGateway) CreateUniqueTask(t *store.TaskRow) (bool, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, old := range gw.tasks {
+		if old.Status == "queued" && old.UniqueKey != "" && old.UniqueKey == t.UniqueKey {
+			return false, nil
+		}
+	}
+
+	gw.create(t)
+
+	return true, nil
+}
+
+func (gw *TaskGateway) create(t *store.TaskRow) {
+	gw.currentID++
+	t.ID = gw.currentID
+	t.CreatedAt = time.Now()
+	t.UpdatedAt = t.CreatedAt
+	gw.tasks = append(gw.tasks, copyTask(t))
+}
+
+// ClaimTasks hides up to limit tasks with the status and one of the kinds until lockedUntil, and counts an attempt.
+// Tasks are claimed when they are due at now and not hidden, the soonest first.
+func (gw *TaskGateway) ClaimTasks(status string, kinds []string, now, lockedUntil time.Time, limit int) ([]*store.TaskRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var claimed []*store.TaskRow
+	for _, t := range gw.tasks {
+		if t.Status == status && hasKind(kinds, t.Kind) && !t.RunAt.After(now) &&
+			(t.LockedUntil == nil || !t.LockedUntil.After(now)) {
+			claimed = append(claimed, t)
+		}
+	}
+
+	sort.SliceStable(claimed, func(i, k int) bool {
+		return claimed[i].RunAt.Before(claimed[k].RunAt)
+	})
+
+	if len(claimed) > limit {
+		claimed = claimed[:limit]
+	}
+
+	rows := make([]*store.TaskRow, 0, len(claimed))
+	for _, t := range claimed {
+		until := lockedUntil
+		t.Attempts++
+		t.LockedUntil = &until
+		t.UpdatedAt = now
+		rows = append(rows, copyTask(t))
+	}
+
+	return rows, nil
+}
+
+// ExtendTask hides a task claimed with the attempts until lockedUntil.
+// It returns false if the task was claimed again since, by another worker once the claim expired.
+func (gw *TaskGateway) ExtendTask(id, attempts int, lockedUntil time.Time) (bool, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, t := range gw.tasks {
+		if t.ID == id && t.Attempts == attempts {
+			t.LockedUntil = &lockedUntil
+			t.UpdatedAt = time.Now()
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (gw *TaskGateway) UpdateTask(t *store.TaskRow) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, old := range gw.tasks {
+		if old.ID == t.ID {
+			t.UpdatedAt = time.Now()
+
+			gw.tasks[i] = copyTask(t)
+			return nil
+		}
+	}
+
+	return errors.New("task not found")
+}
+
+func (gw *TaskGateway) DeleteTask(id int) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, t := range gw.tasks {
+		if t.ID == id {
+			gw.tasks = append(gw.tasks[:i], gw.tasks[i+1:]...)
+			return nil
+		}
+	}
+
+	return errors.New("task not found")
+}
+
+func (gw *TaskGateway) FindTaskByID(id int) (*store.TaskRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, t := range gw.tasks {
+		if t.ID == id {
+			return copyTask(t), nil
+		}
+	}
+
+	return nil, errors.New("task not found")
+}
+
+func (gw *TaskGateway) Clear() {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.currentID = 0
+	gw.tasks = nil
+}
+
+func NewTaskGateway() *TaskGateway {
+	return &TaskGateway{mu: new(sync.Mutex)}
+}
+
+func hasKind(kinds []string, kind string) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+func copyTask(t *store.TaskRow) *store.TaskRow {
+	row := *t
+	row.Payload = append([]byte(nil), t.Payload...)
+	if t.LockedUntil != nil {
+		until := *t.LockedUntil
+		row.LockedUntil = &until
+	}
+
+	return &row
+}
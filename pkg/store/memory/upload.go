@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var GlobalUploadStore = NewUploadGateway()
+
+type UploadGateway struct {
+	mu      *sync.Mutex
+	uploads []*store.UploadRow
+}
+
+func (gw *UploadGateway) CreateUpload(u *store.UploadRow) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	return gw.create(u)
+}
+
+func (gw *UploadGateway) CreateUploadWithinQuota(u *store.UploadRow, quota int64) (bool, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	if gw.sum(u.UserID)+u.Size > quota {
+		return false, nil
+	}
+
+	return true, gw.create(u)
+}
+
+// create must be called with gw.mu held
+func (gw *UploadGateway) create(u *store.UploadRow) error {
+	for _, row := range gw.uploads {
+		if row.ID == u.ID {
+			return errors.New("upload existed")
+		}
+	}
+
+	now := time.Now()
+	u.CreatedAt = now
+	u.UpdatedAt = now
+	row := *u
+	gw.uploads = append(gw.uploads, &row)
+
+	return nil
+}
+
+func (gw *UploadGateway) FindUploadByID(id string) (*store.UploadRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, row := range gw.uploads {
+		if row.ID == id {
+			u := *row
+			return &u, nil
+		}
+	}
+
+	return nil, errors.New("upload not found")
+}
+
+func (gw *UploadGateway) UpdateUpload(u *store.UploadRow) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, row := range gw.uploads {
+		if row.ID == u.ID {
+			u.UpdatedAt = time.Now()
+			updated := *u
+			gw.uploads[i] = &updated
+			return nil
+		}
+	}
+
+	return errors.New("upload not found")
+}
+
+func (gw *UploadGateway) DeleteUpload(id string) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, row := range gw.uploads {
+		if row.ID == id {
+			gw.uploads = append(gw.uploads[:i], gw.uploads[i+1:]...)
+			return nil
+		}
+	}
+
+	return errors.New("upload not found")
+}
+
+func (gw *UploadGateway) SumUploadSizeByUserID(userID int) (int64, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	return gw.sum(userID), nil
+}
+
+// sum must be called with gw.mu held
+func (gw *UploadGateway) sum(userID int) int64 {
+	var sum int64
+	for _, row := range gw.uploads {
+		if row.UserID == userID {
+			sum += row.Size
+		}
+	}
+
+	return sum
+}
+
+func (gw *UploadGateway) FindStaleUploads(statuses []string, updatedBefore time.Time) ([]*store.UploadRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.UploadRow
+	for _, row := range gw.uploads {
+		if !row.UpdatedAt.Before(updatedBefore) {
+			continue
+		}
+
+		for _, status := range statuses {
+			if row.Status == status {
+				u := *row
+				rows = append(rows, &u)
+				break
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+func (gw *UploadGateway) FindExistingUploadIDs(ids []string) ([]string, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var existing []string
+	for _, id := range ids {
+		for _, row := range gw.uploads {
+			if row.ID == id {
+				existing = append(existing, id)
+				break
+			}
+		}
+	}
+
+	return existing, nil
+}
+
+func (gw *UploadGateway) Clear() {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.uploads = nil
+}
+
+func NewUploadGateway() *UploadGateway {
+	return &UploadGateway{mu: new(sync.Mutex)}
+}
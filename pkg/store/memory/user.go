@@ -106,6 +106,20 @@ func (gw *UserGateway) CreateUser(u *store.UserRow) (int, error) {
 	return u.ID, nil
 }
 
+func (gw *UserGateway) UpdateUserActive(id int, isActive bool) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, u := range gw.users {
+		if u.ID == id {
+			u.IsActive = isActive
+			u.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return errors.New("user not found")
+}
+
 func (gw *UserGateway) Seed(users []*store.UserRow) {
 	for _, u := range users {
 		_, err := gw.CreateUser(u)
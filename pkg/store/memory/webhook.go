@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var GlobalWebhookStore = NewWebhookGateway()
+
+type WebhookGateway struct {
+	mu                *sync.Mutex
+	currentWebhookID  int
+	currentDeliveryID int
+	webhooks          []*store.WebhookRow
+	deliveries        []*store.WebhookDeliveryRow
+}
+
+func (gw *WebhookGateway) CreateWebhook(w *store.WebhookRow) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.currentWebhookID++
+	w.ID = gw.currentWebhookID
+	w.CreatedAt = time.Now()
+	w.UpdatedAt = w.CreatedAt
+	gw.webhooks = append(gw.webhooks, copyWebhook(w))
+
+	return w.ID, nil
+}
+
+func (gw *WebhookGateway) FindWebhookByID(id int) (*store.WebhookRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for _, w := range gw.webhooks {
+		if w.ID == id {
+			return copyWebhook(w), nil
+		}
+	}
+
+	return nil, errors.New("webhook not found")
+}
+
+func (gw *WebhookGateway) FindWebhooks() ([]*store.WebhookRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	rows := make([]*store.WebhookRow, len(gw.webhooks))
+	for i, w := range gw.webhooks {
+		rows[i] = copyWebhook(w)
+	}
+
+	return rows, nil
+}
+
+func (gw *WebhookGateway) UpdateWebhook(w *store.WebhookRow) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, row := range gw.webhooks {
+		if row.ID == w.ID {
+			w.UpdatedAt = time.Now()
+			gw.webhooks[i] = copyWebhook(w)
+			return nil
+		}
+	}
+
+	return errors.New("webhook not found")
+}
+
+func (gw *WebhookGateway) DeleteWebhook(id int) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, row := range gw.webhooks {
+		if row.ID == id {
+			gw.webhooks = append(gw.webhooks[:i], gw.webhooks[i+1:]...)
+
+			// deliveries are deleted with the webhook
+			deliveries := gw.deliveries[:0]
+			for _, d := range gw.deliveries {
+				if d.WebhookID != id {
+					deliveries = append(deliveries, d)
+				}
+			}
+			gw.deliveries = deliveries
+
+			return nil
+		}
+	}
+
+	return errors.New("webhook not found")
+}
+
+func (gw *WebhookGateway) CreateWebhookDelivery(d *store.WebhookDeliveryRow) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.currentDeliveryID++
+	d.ID = gw.currentDeliveryID
+	d.CreatedAt = time.Now()
+	d.UpdatedAt = d.CreatedAt
+	row := *d
+	gw.deliveries = append(gw.deliveries, &row)
+
+	return d.ID, nil
+}
+
+func (gw *WebhookGateway) FindDueWebhookDeliveries(status string, now time.Time, limit int) ([]*store.WebhookDeliveryRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.WebhookDeliveryRow
+	for _, d := range gw.deliveries {
+		if len(rows) == limit {
+			break
+		}
+
+		if d.Status == status && !d.NextAttemptAt.After(now) {
+			row := *d
+			rows = append(rows, &row)
+		}
+	}
+
+	return rows, nil
+}
+
+// FindWebhookDeliveries returns deliveries of the webhook, newest first
+func (gw *WebhookGateway) FindWebhookDeliveries(webhookID, offset, limit int) ([]*store.WebhookDeliveryRow, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	var rows []*store.WebhookDeliveryRow
+	for i := len(gw.deliveries) - 1; i >= 0; i-- {
+		if gw.deliveries[i].WebhookID == webhookID {
+			row := *gw.deliveries[i]
+			rows = append(rows, &row)
+		}
+	}
+
+	if offset >= len(rows) {
+		return nil, nil
+	}
+	rows = rows[offset:]
+	if limit < len(rows) {
+		rows = rows[:limit]
+	}
+
+	return rows, nil
+}
+
+func (gw *WebhookGateway) UpdateWebhookDelivery(d *store.WebhookDeliveryRow) error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	for i, row := range gw.deliveries {
+		if row.ID == d.ID {
+			d.UpdatedAt = time.Now()
+			updated := *d
+			gw.deliveries[i] = &updated
+			return nil
+		}
+	}
+
+	return errors.New("webhook delivery not found")
+}
+
+func (gw *WebhookGateway) Clear() {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.currentWebhookID = 0
+	gw.currentDeliveryID = 0
+	gw.webhooks = nil
+	gw.deliveries = nil
+}
+
+func NewWebhookGateway() *WebhookGateway {
+	return &WebhookGateway{mu: new(sync.Mutex)}
+}
+
+func copyWebhook(w *store.WebhookRow) *store.WebhookRow {
+	row := *w
+	row.EventTypes = append([]string(nil), w.EventTypes...)
+
+	return &row
+}
@@ -0,0 +1,29 @@
+package store
+
+import "time"
+
+type ReportRow struct {
+	ID         int       `db:"id"`
+	ReporterID int       `db:"reporter_id"`
+	TargetType string    `db:"target_type"`
+	TargetID   int       `db:"target_id"`
+	Reason     string    `db:"reason"`
+	Comment    string    `db:"comment"`
+	Status     string    `db:"status"`
+	AssigneeID *int      `db:"assignee_id"`
+	Resolution string    `db:"resolution"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+// ModerationActionRow is an entry of the moderation audit trail
+type ModerationActionRow struct {
+	ID          int       `db:"id"`
+	ModeratorID *int      `db:"moderator_id"` // nil for automatic actions
+	ReportID    *int      `db:"report_id"`
+	TargetType  string    `db:"target_type"`
+	TargetID    int       `db:"target_id"`
+	Action      string    `db:"action"`
+	Note        string    `db:"note"`
+	CreatedAt   time.Time `db:"created_at"`
+}
@@ -0,0 +1,22 @@
+package store
+
+import "time"
+
+type NotificationRow struct {
+	ID        int        `db:"id"`
+	UserID    int        `db:"user_id"`
+	Type      string     `db:"type"`
+	Title     string     `db:"title"`
+	Body      string     `db:"body"`
+	Link      string     `db:"link"`
+	IsRead    bool       `db:"is_read"`
+	InDigest  bool       `db:"in_digest"` // waiting to be sent in the next digest email
+	CreatedAt time.Time  `db:"created_at"`
+	ReadAt    *time.Time `db:"read_at"`
+}
+
+type NotificationPreferenceRow struct {
+	UserID  int    `db:"user_id"`
+	Type    string `db:"type"`
+	Channel string `db:"channel"`
+}
@@ -0,0 +1,20 @@
+package store
+
+import "time"
+
+type OutboxRow struct {
+	ID             int64      `db:"id"`
+	EventID        string     `db:"event_id"`
+	EventType      string     `db:"event_type"`
+	Version        int        `db:"version"`
+	Payload        []byte     `db:"payload"` // JSON encoded event
+	ActorID        int        `db:"actor_id"`
+	CorrelationID  string     `db:"correlation_id"`
+	OccurredAt     time.Time  `db:"occurred_at"`
+	IdempotencyKey string     `db:"idempotency_key"`
+	Attempts       int        `db:"attempts"`
+	LastError      string     `db:"last_error"`
+	LockedUntil    *time.Time `db:"locked_until"` // the entry is hidden from other relays until then
+	CreatedAt      time.Time  `db:"created_at"`
+	DeliveredAt    *time.Time `db:"delivered_at"`
+}
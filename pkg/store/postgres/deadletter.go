@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type DeadLetterGateway struct {
+	db DB
+}
+
+func NewDeadLetterGateway(db DB) *DeadLetterGateway {
+	return &DeadLetterGateway{db: db}
+}
+
+func (gw *DeadLetterGateway) CreateDeadLetter(l *store.DeadLetterRow) (int, error) {
+	l.CreatedAt = time.Now()
+	l.UpdatedAt = l.CreatedAt
+
+	stmt, err := gw.db.PrepareNamed(
+		`INSERT INTO dead_letters (handler, event_id, event_type, version, payload, actor_id, correlation_id, occurred_at,
+			error, attempts, status, created_at, updated_at)
+		VALUES (:handler, :event_id, :event_type, :version, :payload, :actor_id, :correlation_id, :occurred_at,
+			:error, :attempts, :status, :created_at, :updated_at) RETURNING id;`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := stmt.Get(&id, l); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (gw *DeadLetterGateway) FindDeadLetterByID(id int) (*store.DeadLetterRow, error) {
+	var l store.DeadLetterRow
+	if err := gw.db.Get(&l, `SELECT * FROM dead_letters WHERE id = $1;`, id); err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+func (gw *DeadLetterGateway) FindDeadLetters(status string, offset, limit int) ([]*store.DeadLetterRow, error) {
+	var rows []*store.DeadLetterRow
+	err := gw.db.Select(&rows,
+		`SELECT * FROM dead_letters WHERE status = $1 ORDER BY id OFFSET $2 LIMIT $3;`,
+		status, offset, limit,
+	)
+
+	return rows, err
+}
+
+// ClaimDeadLetters sets the status of up to limit dead letters with the status to claimedStatus, oldest first.
+// Rows being claimed by another process are skipped, so a dead letter is never claimed twice.
+func (gw *DeadLetterGateway) ClaimDeadLetters(status, claimedStatus string, limit int) ([]*store.DeadLetterRow, error) {
+	var rows []*store.DeadLetterRow
+	err := gw.db.Select(&rows,
+		`UPDATE dead_letters SET status = $1, updated_at = $2
+		WHERE id IN (
+			SELECT id FROM dead_letters
+			WHERE status = $3
+			ORDER BY id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *;`,
+		claimedStatus, time.Now(), status, limit,
+	)
+
+	// RETURNING does not keep the order of the subquery
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ID < rows[j].ID })
+
+	return rows, err
+}
+
+func (gw *DeadLetterGateway) UpdateDeadLetter(l *store.DeadLetterRow) error {
+	l.UpdatedAt = time.Now()
+
+	res, err := gw.db.NamedExec(
+		`UPDATE dead_letters SET error = :error, attempts = :attempts, status = :status, updated_at = :updated_at
+		WHERE id = :id;`,
+		l,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("dead letter not found")
+	}
+
+	return nil
+}
+
+func (gw *DeadLetterGateway) DeleteDeadLetter(id int) error {
+	res, err := gw.db.Exec(`DELETE FROM dead_letters WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("dead letter not found")
+	}
+
+	return nil
+}
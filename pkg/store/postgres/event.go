@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type EventGateway struct {
+	db DB
+}
+
+func NewEventGateway(db DB) *EventGateway {
+	return &EventGateway{db: db}
+}
+
+func (gw *EventGateway) AppendEvent(e *store.EventRow) error {
+	e.CreatedAt = time.Now()
+
+	stmt, err := gw.db.PrepareNamed(
+		`INSERT INTO event_log (event_id, event_type, version, payload, actor_id, correlation_id, occurred_at, created_at)
+		VALUES (:event_id, :event_type, :version, :payload, :actor_id, :correlation_id, :occurred_at, :created_at) RETURNING "offset";`,
+	)
+	if err != nil {
+		return err
+	}
+
+	return stmt.Get(&e.Offset, e)
+}
+
+// FindEvents returns events from the offset, in order. Zero since or until are not used.
+func (gw *EventGateway) FindEvents(fromOffset int64, since, until time.Time, limit int) ([]*store.EventRow, error) {
+	conditions := []string{`"offset" >= $1`}
+	args := []interface{}{fromOffset}
+
+	if !since.IsZero() {
+		args = append(args, since)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	if !until.IsZero() {
+		args = append(args, until)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	args = append(args, limit)
+	query := fmt.Sprintf(`SELECT * FROM event_log WHERE %s ORDER BY "offset" LIMIT $%d;`, strings.Join(conditions, " AND "), len(args))
+
+	var rows []*store.EventRow
+	err := gw.db.Select(&rows, query, args...)
+
+	return rows, err
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type FollowGateway struct {
+	db DB
+}
+
+func NewFollowGateway(db DB) *FollowGateway {
+	return &FollowGateway{db: db}
+}
+
+func (gw *FollowGateway) CreateFollow(f *store.FollowRow) error {
+	f.CreatedAt = time.Now()
+
+	_, err := gw.db.NamedExec(
+		`INSERT INTO follows (follower_id, followee_id, created_at) VALUES (:follower_id, :followee_id, :created_at);`,
+		f,
+	)
+
+	return err
+}
+
+func (gw *FollowGateway) DeleteFollow(followerID, followeeID int) error {
+	res, err := gw.db.Exec(`DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2;`, followerID, followeeID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("follow not found")
+	}
+
+	return nil
+}
+
+func (gw *FollowGateway) FindFollowers(userID, offset, limit int) ([]*store.FollowRow, error) {
+	var rows []*store.FollowRow
+	err := gw.db.Select(&rows,
+		`SELECT * FROM follows WHERE followee_id = $1 ORDER BY created_at DESC OFFSET $2 LIMIT $3;`,
+		userID, offset, limit,
+	)
+
+	return rows, err
+}
+
+func (gw *FollowGateway) FindFollowing(userID, offset, limit int) ([]*store.FollowRow, error) {
+	var rows []*store.FollowRow
+	err := gw.db.Select(&rows,
+		`SELECT * FROM follows WHERE follower_id = $1 ORDER BY created_at DESC OFFSET $2 LIMIT $3;`,
+		userID, offset, limit,
+	)
+
+	return rows, err
+}
+
+func (gw *FollowGateway) CountFollowers(userID int) (int, error) {
+	var count int
+	err := gw.db.Get(&count, `SELECT count(*) FROM follows WHERE followee_id = $1;`, userID)
+
+	return count, err
+}
+
+func (gw *FollowGateway) CountFollowing(userID int) (int, error) {
+	var count int
+	err := gw.db.Get(&count, `SELECT count(*) FROM follows WHERE follower_id = $1;`, userID)
+
+	return count, err
+}
@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type JobGateway struct {
+	db DB
+}
+
+func NewJobGateway(db DB) *JobGateway {
+	return &JobGateway{db: db}
+}
+
+// CreatePeriodicJob creates j unless a periodic job with the same name exists, it returns true if j was created
+func (gw *JobGateway) CreatePeriodicJob(j *store.JobRow) (bool, error) {
+	_, err := gw.insert(j, `ON CONFLICT (name) WHERE schedule <> '' DO NOTHING`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (gw *JobGateway) CreateJob(j *store.JobRow) (int, error) {
+	return gw.insert(j, "")
+}
+
+func (gw *JobGateway) insert(j *store.JobRow, onConflict string) (int, error) {
+	j.CreatedAt = time.Now()
+	j.UpdatedAt = j.CreatedAt
+	if j.Payload == nil {
+		j.Payload = []byte("null")
+	}
+
+	stmt, err := gw.db.PrepareNamed(
+		`INSERT INTO jobs (name, schedule, payload, run_at, status, created_at, updated_at)
+		VALUES (:name, :schedule, :payload, :run_at, :status, :created_at, :updated_at) ` + onConflict + ` RETURNING id;`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := stmt.Get(&id, j); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (gw *JobGateway) FindPeriodicJob(name string) (*store.JobRow, error) {
+	var j store.JobRow
+	if err := gw.db.Get(&j, `SELECT * FROM jobs WHERE name = $1 AND schedule <> '';`, name); err != nil {
+		return nil, err
+	}
+
+	return &j, nil
+}
+
+func (gw *JobGateway) FindJobs(status string) ([]*store.JobRow, error) {
+	var rows []*store.JobRow
+	err := gw.db.Select(&rows, `SELECT * FROM jobs WHERE status = $1 ORDER BY run_at;`, status)
+
+	return rows, err
+}
+
+func (gw *JobGateway) FindDueJobs(status string, now time.Time, limit int) ([]*store.JobRow, error) {
+	var rows []*store.JobRow
+	err := gw.db.Select(&rows,
+		`SELECT * FROM jobs WHERE status = $1 AND run_at <= $2 ORDER BY run_at LIMIT $3;`,
+		status, now, limit,
+	)
+
+	return rows, err
+}
+
+func (gw *JobGateway) UpdateJob(j *store.JobRow) error {
+	j.UpdatedAt = time.Now()
+
+	res, err := gw.db.NamedExec(
+		`UPDATE jobs SET schedule = :schedule, run_at = :run_at, status = :status, updated_at = :updated_at
+		WHERE id = :id;`,
+		j,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("job not found")
+	}
+
+	return nil
+}
+
+func (gw *JobGateway) CreateJobRun(r *store.JobRunRow) (int, error) {
+	stmt, err := gw.db.PrepareNamed(
+		`INSERT INTO job_runs (job_id, name, status, error, started_at, finished_at)
+		VALUES (:job_id, :name, :status, :error, :started_at, :finished_at) RETURNING id;`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := stmt.Get(&id, r); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (gw *JobGateway) UpdateJobRun(r *store.JobRunRow) error {
+	res, err := gw.db.NamedExec(
+		`UPDATE job_runs SET status = :status, error = :error, finished_at = :finished_at WHERE id = :id;`,
+		r,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("job run not found")
+	}
+
+	return nil
+}
+
+// FindJobRuns returns the runs of the named job, or of every job if name is empty, newest first
+func (gw *JobGateway) FindJobRuns(name string, offset, limit int) ([]*store.JobRunRow, error) {
+	var rows []*store.JobRunRow
+	err := gw.db.Select(&rows,
+		`SELECT * FROM job_runs WHERE $1 = '' OR name = $1 ORDER BY id DESC OFFSET $2 LIMIT $3;`,
+		name, offset, limit,
+	)
+
+	return rows, err
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AdvisoryLock is a session level advisory lock, held by a connection taken out of the pool.
+// The lock is released when the connection closes, so a crashed process does not keep it.
+type AdvisoryLock struct {
+	db  *sqlx.DB
+	key int64
+
+	mu   sync.Mutex
+	conn *sql.Conn // set while the lock is held
+}
+
+func NewAdvisoryLock(db *sqlx.DB, key int64) *AdvisoryLock {
+	return &AdvisoryLock{db: db, key: key}
+}
+
+// TryLock returns true if the lock is held by l, taking it if it is free
+func (l *AdvisoryLock) TryLock() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ctx := context.Background()
+	if l.conn != nil {
+		// the lock is held as long as the connection is alive
+		if _, err := l.conn.ExecContext(ctx, `SELECT 1;`); err == nil {
+			return true, nil
+		}
+
+		_ = l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1);`, l.key).Scan(&locked); err != nil || !locked {
+		_ = conn.Close()
+		return false, err
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+// Unlock releases the lock if it is held
+func (l *AdvisoryLock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	_, err := l.conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, l.key)
+	if cerr := l.conn.Close(); err == nil {
+		err = cerr
+	}
+	l.conn = nil
+
+	return err
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type ModerationGateway struct {
+	db DB
+}
+
+func NewModerationGateway(db DB) *ModerationGateway {
+	return &ModerationGateway{db: db}
+}
+
+func (gw *ModerationGateway) CreateReport(r *store.ReportRow) (int, error) {
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = r.CreatedAt
+
+	stmt, err := gw.db.PrepareNamed(
+		`INSERT INTO reports (reporter_id, target_type, target_id, reason, comment, status, assignee_id, resolution, created_at, updated_at)
+		VALUES (:reporter_id, :target_type, :target_id, :reason, :comment, :status, :assignee_id, :resolution, :created_at, :updated_at)
+		RETURNING id;`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := stmt.Get(&id, r); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (gw *ModerationGateway) FindReportByID(id int) (*store.ReportRow, error) {
+	var r store.ReportRow
+	if err := gw.db.Get(&r, `SELECT * FROM reports WHERE id = $1;`, id); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+func (gw *ModerationGateway) FindReportsByStatus(statuses []string, offset, limit int) ([]*store.ReportRow, error) {
+	var rows []*store.ReportRow
+	err := gw.db.Select(&rows,
+		`SELECT * FROM reports WHERE status = ANY($1) ORDER BY id OFFSET $2 LIMIT $3;`,
+		pq.Array(statuses), offset, limit,
+	)
+
+	return rows, err
+}
+
+func (gw *ModerationGateway) FindReportsByTarget(targetType string, targetID int, statuses []string) ([]*store.ReportRow, error) {
+	var rows []*store.ReportRow
+	err := gw.db.Select(&rows,
+		`SELECT * FROM reports WHERE target_type = $1 AND target_id = $2 AND status = ANY($3) ORDER BY id;`,
+		targetType, targetID, pq.Array(statuses),
+	)
+
+	return rows, err
+}
+
+func (gw *ModerationGateway) UpdateReport(r *store.ReportRow) error {
+	r.UpdatedAt = time.Now()
+
+	res, err := gw.db.NamedExec(
+		`UPDATE reports SET status = :status, assignee_id = :assignee_id, resolution = :resolution, updated_at = :updated_at
+		WHERE id = :id;`,
+		r,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("report not found")
+	}
+
+	return nil
+}
+
+func (gw *ModerationGateway) CreateModerationAction(a *store.ModerationActionRow) (int, error) {
+	a.CreatedAt = time.Now()
+
+	stmt, err := gw.db.PrepareNamed(
+		`INSERT INTO moderation_actions (moderator_id, report_id, target_type, target_id, action, note, created_at)
+		VALUES (:moderator_id, :report_id, :target_type, :target_id, :action, :note, :created_at) RETURNING id;`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := stmt.Get(&id, a); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (gw *ModerationGateway) FindModerationActionsByTarget(targetType string, targetID int) ([]*store.ModerationActionRow, error) {
+	var rows []*store.ModerationActionRow
+	err := gw.db.Select(&rows,
+		`SELECT * FROM moderation_actions WHERE target_type = $1 AND target_id = $2 ORDER BY id;`,
+		targetType, targetID,
+	)
+
+	return rows, err
+}
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type NotificationGateway struct {
+	db DB
+}
+
+func NewNotificationGateway(db DB) *NotificationGateway {
+	return &NotificationGateway{db: db}
+}
+
+func (gw *NotificationGateway) CreateNotification(n *store.NotificationRow) (int, error) {
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+
+	stmt, err := gw.db.PrepareNamed(
+		`INSERT INTO notifications (user_id, type, title, body, link, is_read, in_digest, created_at)
+		VALUES (:user_id, :type, :title, :body, :link, :is_read, :in_digest, :created_at) RETURNING id;`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = stmt.Get(&id, n)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (gw *NotificationGateway) FindNotificationsByUserID(userID, offset, limit int) ([]*store.NotificationRow, error) {
+	var rows []*store.NotificationRow
+	err := gw.db.Select(&rows,
+		`SELECT * FROM notifications WHERE user_id = $1 ORDER BY id DESC OFFSET $2 LIMIT $3;`,
+		userID, offset, limit,
+	)
+
+	return rows, err
+}
+
+func (gw *NotificationGateway) CountUnreadNotifications(userID int) (int, error) {
+	var count int
+	err := gw.db.Get(&count, `SELECT count(*) FROM notifications WHERE user_id = $1 AND NOT is_read;`, userID)
+
+	return count, err
+}
+
+func (gw *NotificationGateway) MarkNotificationAsRead(userID, id int, at time.Time) (bool, error) {
+	res, err := gw.db.Exec(
+		`UPDATE notifications SET is_read = true, read_at = $3 WHERE id = $1 AND user_id = $2 AND NOT is_read;`,
+		id, userID, at,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n > 0 {
+		return true, nil
+	}
+
+	// already read, or not found
+	var count int
+	if err := gw.db.Get(&count, `SELECT count(*) FROM notifications WHERE id = $1 AND user_id = $2;`, id, userID); err != nil {
+		return false, err
+	}
+	if count == 0 {
+		return false, errors.New("notification not found")
+	}
+
+	return false, nil
+}
+
+func (gw *NotificationGateway) MarkAllNotificationsAsRead(userID int, at time.Time) (int, error) {
+	res, err := gw.db.Exec(
+		`UPDATE notifications SET is_read = true, read_at = $2 WHERE user_id = $1 AND NOT is_read;`,
+		userID, at,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return int(n), err
+}
+
+func (gw *NotificationGateway) FindDigestNotifications() ([]*store.NotificationRow, error) {
+	var rows []*store.NotificationRow
+	err := gw.db.Select(&rows, `SELECT * FROM notifications WHERE in_digest ORDER BY id;`)
+
+	return rows, err
+}
+
+func (gw *NotificationGateway) RemoveFromDigest(ids []int) error {
+	_, err := gw.db.Exec(`UPDATE notifications SET in_digest = false WHERE id = ANY($1);`, pq.Array(ids))
+
+	return err
+}
+
+func (gw *NotificationGateway) FindNotificationPreferences(userID int) ([]*store.NotificationPreferenceRow, error) {
+	var rows []*store.NotificationPreferenceRow
+	err := gw.db.Select(&rows, `SELECT * FROM notification_preferences WHERE user_id = $1;`, userID)
+
+	return rows, err
+}
+
+func (gw *NotificationGateway) SaveNotificationPreference(p *store.NotificationPreferenceRow) error {
+	_, err := gw.db.NamedExec(
+		`INSERT INTO notification_preferences (user_id, type, channel) VALUES (:user_id, :type, :channel)
+		ON CONFLICT (user_id, type) DO UPDATE SET channel = EXCLUDED.channel;`,
+		p,
+	)
+
+	return err
+}
+
+func (gw *NotificationGateway) AddUnreadCount(userID, delta int) error {
+	_, err := gw.db.Exec(
+		`INSERT INTO notification_unread_counts (user_id, unread) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET unread = notification_unread_counts.unread + EXCLUDED.unread;`,
+		userID, delta,
+	)
+
+	return err
+}
+
+func (gw *NotificationGateway) FindUnreadCount(userID int) (int, error) {
+	var count int
+	err := gw.db.Get(&count, `SELECT unread FROM notification_unread_counts WHERE user_id = $1;`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	return count, err
+}
+
+func (gw *NotificationGateway) DeleteUnreadCounts() error {
+	_, err := gw.db.Exec(`DELETE FROM notification_unread_counts;`)
+
+	return err
+}
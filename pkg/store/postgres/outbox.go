@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+// OutboxGateway stores events to publish.
+// Create it with the transaction of the domain change, so the event is only stored if the change is committed.
+type OutboxGateway struct {
+	db DB
+}
+
+func NewOutboxGateway(db DB) *OutboxGateway {
+	return &OutboxGateway{db: db}
+}
+
+func (gw *OutboxGateway) CreateOutboxEntry(e *store.OutboxRow) error {
+	e.CreatedAt = time.Now()
+
+	stmt, err := gw.db.PrepareNamed(
+		`INSERT INTO outbox (event_id, event_type, version, payload, actor_id, correlation_id, occurred_at,
+			idempotency_key, attempts, last_error, created_at)
+		VALUES (:event_id, :event_type, :version, :payload, :actor_id, :correlation_id, :occurred_at,
+			:idempotency_key, :attempts, :last_error, :created_at) RETURNING id;`,
+	)
+	if err != nil {
+		return err
+	}
+
+	return stmt.Get(&e.ID, e)
+}
+
+// ClaimOutboxEntries hides up to limit undelivered entries tried less than maxAttempts times until lockedUntil, oldest first.
+// Entries are claimed when they are not hidden at now.
+// Rows being claimed by another relay are skipped, so an entry is never claimed twice.
+func (gw *OutboxGateway) ClaimOutboxEntries(maxAttempts, limit int, now, lockedUntil time.Time) ([]*store.OutboxRow, error) {
+	var rows []*store.OutboxRow
+	err := gw.db.Select(&rows,
+		`UPDATE outbox SET locked_until = $1
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE delivered_at IS NULL AND attempts < $2 AND (locked_until IS NULL OR locked_until <= $3)
+			ORDER BY id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *;`,
+		lockedUntil, maxAttempts, now, limit,
+	)
+
+	// RETURNING does not keep the order of the subquery
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ID < rows[j].ID })
+
+	return rows, err
+}
+
+func (gw *OutboxGateway) MarkOutboxEntryDelivered(id int64) error {
+	res, err := gw.db.Exec(`UPDATE outbox SET delivered_at = $1 WHERE id = $2;`, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return checkOutboxEntryUpdated(res.RowsAffected())
+}
+
+func (gw *OutboxGateway) MarkOutboxEntryFailed(id int64, reason string) error {
+	res, err := gw.db.Exec(`UPDATE outbox SET attempts = attempts + 1, last_error = $1, locked_until = NULL WHERE id = $2;`, reason, id)
+	if err != nil {
+		return err
+	}
+
+	return checkOutboxEntryUpdated(res.RowsAffected())
+}
+
+func (gw *OutboxGateway) DeleteDeliveredOutboxEntries(before time.Time) (int, error) {
+	res, err := gw.db.Exec(`DELETE FROM outbox WHERE delivered_at < $1;`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func checkOutboxEntryUpdated(n int64, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return errors.New("outbox entry not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type TaskGateway struct {
+	db DB
+}
+
+func NewTaskGateway(db DB) *TaskGateway {
+	return &TaskGateway{db: db}
+}
+
+func (gw *TaskGateway) CreateTask(t *store.TaskRow) (int, error) {
+	return gw.insert(t, "")
+}
+
+// CreateUniqueTask creates t unless a queued task has the same unique key, it returns true if t was created
+func (gw *TaskGateway) CreateUniqueTask(t *store.TaskRow) (bool, error) {
+	id, err := gw.insert(t, `ON CONFLICT (unique_key) WHERE status = 'queued' AND unique_key <> '' DO NOTHING`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	t.ID = id
+
+	return err == nil, err
+}
+
+func (gw *TaskGateway) insert(t *store.TaskRow, onConflict string) (int, error) {
+	t.CreatedAt = time.Now()
+	t.UpdatedAt = t.CreatedAt
+	if t.Payload == nil {
+		t.Payload = []byte("null")
+	}
+
+	stmt, err := gw.db.PrepareNamed(
+		`INSERT INTO tasks (kind, unique_key, payload, status, attempts, last_error, run_at, locked_until, created_at, updated_at)
+		VALUES (:kind, :unique_key, :payload, :status, :attempts, :last_error, :run_at, :locked_until, :created_at, :updated_at) ` +
+			onConflict + ` RETURNING id;`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := stmt.Get(&id, t); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (gw *TaskGateway) FindTaskByID(id int) (*store.TaskRow, error) {
+	var t store.TaskRow
+	if err := gw.db.Get(&t, `SELECT * FROM tasks WHERE id = $1;`, id); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// ClaimTasks hides up to limit tasks with the status and one of the kinds until lockedUntil, and counts an attempt.
+// Tasks are claimed when they are due at now and not hidden, the soonest first.
+// Rows being claimed by another worker are skipped, so a task is never claimed twice.
+func (gw *TaskGateway) ClaimTasks(status string, kinds []string, now, lockedUntil time.Time, limit int) ([]*store.TaskRow, error) {
+	var rows []*store.TaskRow
+	err := gw.db.Select(&rows,
+		`UPDATE tasks SET attempts = attempts + 1, locked_until = $1, updated_at = $2
+		WHERE id IN (
+			SELECT id FROM tasks
+			WHERE status = $3 AND kind = ANY($4) AND run_at <= $2 AND (locked_until IS NULL OR locked_until <= $2)
+			ORDER BY run_at
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *;`,
+		lockedUntil, now, status, pq.Array(kinds), limit,
+	)
+
+	return rows, err
+}
+
+// ExtendTask hides a task claimed with the attempts until lockedUntil.
+// It returns false if the task was claimed again since, by another worker once the claim expired.
+func (gw *TaskGateway) ExtendTask(id, attempts int, lockedUntil time.Time) (bool, error) {
+	res, err := gw.db.Exec(
+		`UPDATE tasks SET locked_until = $1, updated_at = $2 WHERE id = $3 AND attempts = $4;`,
+		lockedUntil, time.Now(), id, attempts,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return n > 0, err
+}
+
+func (gw *TaskGateway) UpdateTask(t *store.TaskRow) error {
+	t.UpdatedAt = time.Now()
+
+	res, err := gw.db.NamedExec(
+		`UPDATE tasks SET status = :status, attempts = :attempts, last_error = :last_error, run_at = :run_at, locked_until = :locked_until, updated_at = :updated_at
+		WHERE id = :id;`,
+		t,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("task not found")
+	}
+
+	return nil
+}
+
+func (gw *TaskGateway) DeleteTask(id int) error {
+	res, err := gw.db.Exec(`DELETE FROM tasks WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("task not found")
+	}
+
+	return nil
+}
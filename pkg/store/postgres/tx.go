@@ -0,0 +1,34 @@
+package postgres
+
+import "github.com/jmoiron/sqlx"
+
+// Transaction runs f in a transaction, which is committed if f returns nil and rolled back otherwise.
+// Gateways created with tx inside f share the transaction, for example:
+//
+//	err := Transaction(db, func(tx DB) error {
+//		if err := NewFollowGateway(tx).CreateFollow(f); err != nil {
+//			return err
+//		}
+//		_, err := outbox.Add(ctx, NewOutboxGateway(tx), follow.Followed{FollowerID: f.FollowerID, FolloweeID: f.FolloweeID})
+//		return err
+//	})
+func Transaction(db *sqlx.DB, f func(tx DB) error) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := f(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
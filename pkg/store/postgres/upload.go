@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type UploadGateway struct {
+	db DB
+}
+
+func NewUploadGateway(db DB) *UploadGateway {
+	return &UploadGateway{db: db}
+}
+
+func (gw *UploadGateway) CreateUpload(u *store.UploadRow) error {
+	now := time.Now()
+	u.CreatedAt = now
+	u.UpdatedAt = now
+
+	_, err := gw.db.NamedExec(
+		`INSERT INTO uploads (id, user_id, filename, content_type, size, "offset", checksum, status, created_at, updated_at)
+		VALUES (:id, :user_id, :filename, :content_type, :size, :offset, :checksum, :status, :created_at, :updated_at);`,
+		u,
+	)
+
+	return err
+}
+
+// uploadQuotaLockSpace is the first key of the advisory locks taken per user while checking their upload quota
+const uploadQuotaLockSpace = 0x75706c64
+
+// CreateUploadWithinQuota locks the uploads of the user until u is created,
+// so the sum of concurrent uploads is checked one at a time. The gateway must not be bound to a transaction.
+func (gw *UploadGateway) CreateUploadWithinQuota(u *store.UploadRow, quota int64) (bool, error) {
+	db, ok := gw.db.(*sqlx.DB)
+	if !ok {
+		return false, errors.New("upload gateway is bound to a transaction")
+	}
+
+	created := false
+	err := Transaction(db, func(tx DB) error {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2);`, uploadQuotaLockSpace, u.UserID); err != nil {
+			return err
+		}
+
+		used, err := NewUploadGateway(tx).SumUploadSizeByUserID(u.UserID)
+		if err != nil {
+			return err
+		}
+
+		if used+u.Size > quota {
+			return nil
+		}
+
+		if err := NewUploadGateway(tx).CreateUpload(u); err != nil {
+			return err
+		}
+
+		created = true
+		return nil
+	})
+
+	return created, err
+}
+
+func (gw *UploadGateway) FindUploadByID(id string) (*store.UploadRow, error) {
+	var u store.UploadRow
+	err := gw.db.Get(&u, `SELECT * FROM uploads WHERE id = $1;`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+func (gw *UploadGateway) UpdateUpload(u *store.UploadRow) error {
+	u.UpdatedAt = time.Now()
+
+	res, err := gw.db.NamedExec(
+		`UPDATE uploads SET content_type = :content_type, "offset" = :offset, status = :status, updated_at = :updated_at
+		WHERE id = :id;`,
+		u,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("upload not found")
+	}
+
+	return nil
+}
+
+func (gw *UploadGateway) DeleteUpload(id string) error {
+	_, err := gw.db.Exec(`DELETE FROM uploads WHERE id = $1;`, id)
+
+	return err
+}
+
+func (gw *UploadGateway) SumUploadSizeByUserID(userID int) (int64, error) {
+	var sum int64
+	err := gw.db.Get(&sum, `SELECT COALESCE(SUM(size), 0) FROM uploads WHERE user_id = $1;`, userID)
+
+	return sum, err
+}
+
+func (gw *UploadGateway) FindStaleUploads(statuses []string, updatedBefore time.Time) ([]*store.UploadRow, error) {
+	var rows []*store.UploadRow
+	err := gw.db.Select(&rows,
+		`SELECT * FROM uploads WHERE status = ANY($1) AND updated_at < $2;`,
+		pq.Array(statuses), updatedBefore,
+	)
+
+	return rows, err
+}
+
+func (gw *UploadGateway) FindExistingUploadIDs(ids []string) ([]string, error) {
+	var existing []string
+	err := gw.db.Select(&existing, `SELECT id FROM uploads WHERE id::text = ANY($1);`, pq.Array(ids))
+
+	return existing, err
+}
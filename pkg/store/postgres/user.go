@@ -1,14 +1,22 @@
 package postgres
 
 import (
+	"database/sql"
+	"errors"
+
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/victornm/es-backend/pkg/store"
 	"time"
 )
 
+// DB is satisfied by both *sqlx.DB and *sqlx.Tx
 type DB interface {
 	PrepareNamed(query string) (*sqlx.NamedStmt, error)
+	NamedExec(query string, arg interface{}) (sql.Result, error)
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Get(dest interface{}, query string, args ...interface{}) error
+	Select(dest interface{}, query string, args ...interface{}) error
 }
 
 type UserGateway struct {
@@ -38,3 +46,16 @@ func (gw *UserGateway) CreateUser(u *store.UserRow) (int, error) {
 
 	return int(id), nil
 }
+
+func (gw *UserGateway) UpdateUserActive(id int, isActive bool) error {
+	res, err := gw.db.Exec(`UPDATE users SET is_active = $2, updated_at = $3 WHERE id = $1;`, id, isActive, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
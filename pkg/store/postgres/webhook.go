@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type WebhookGateway struct {
+	db DB
+}
+
+func NewWebhookGateway(db DB) *WebhookGateway {
+	return &WebhookGateway{db: db}
+}
+
+func (gw *WebhookGateway) CreateWebhook(w *store.WebhookRow) (int, error) {
+	w.CreatedAt = time.Now()
+	w.UpdatedAt = w.CreatedAt
+
+	stmt, err := gw.db.PrepareNamed(
+		`INSERT INTO webhooks (user_id, url, secret, event_types, is_active, created_at, updated_at)
+		VALUES (:user_id, :url, :secret, :event_types, :is_active, :created_at, :updated_at) RETURNING id;`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := stmt.Get(&id, w); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (gw *WebhookGateway) FindWebhookByID(id int) (*store.WebhookRow, error) {
+	var w store.WebhookRow
+	if err := gw.db.Get(&w, `SELECT * FROM webhooks WHERE id = $1;`, id); err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+func (gw *WebhookGateway) FindWebhooks() ([]*store.WebhookRow, error) {
+	var rows []*store.WebhookRow
+	err := gw.db.Select(&rows, `SELECT * FROM webhooks ORDER BY id;`)
+
+	return rows, err
+}
+
+func (gw *WebhookGateway) UpdateWebhook(w *store.WebhookRow) error {
+	w.UpdatedAt = time.Now()
+
+	res, err := gw.db.NamedExec(
+		`UPDATE webhooks SET url = :url, secret = :secret, event_types = :event_types, is_active = :is_active, updated_at = :updated_at
+		WHERE id = :id;`,
+		w,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("webhook not found")
+	}
+
+	return nil
+}
+
+func (gw *WebhookGateway) DeleteWebhook(id int) error {
+	res, err := gw.db.Exec(`DELETE FROM webhooks WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("webhook not found")
+	}
+
+	return nil
+}
+
+func (gw *WebhookGateway) CreateWebhookDelivery(d *store.WebhookDeliveryRow) (int, error) {
+	d.CreatedAt = time.Now()
+	d.UpdatedAt = d.CreatedAt
+
+	stmt, err := gw.db.PrepareNamed(
+		`INSERT INTO webhook_deliveries (webhook_id, event_type, payload, status, attempts, response_code, error, next_attempt_at, created_at, updated_at)
+		VALUES (:webhook_id, :event_type, :payload, :status, :attempts, :response_code, :error, :next_attempt_at, :created_at, :updated_at)
+		RETURNING id;`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := stmt.Get(&id, d); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (gw *WebhookGateway) FindDueWebhookDeliveries(status string, now time.Time, limit int) ([]*store.WebhookDeliveryRow, error) {
+	var rows []*store.WebhookDeliveryRow
+	err := gw.db.Select(&rows,
+		`SELECT * FROM webhook_deliveries WHERE status = $1 AND next_attempt_at <= $2 ORDER BY next_attempt_at LIMIT $3;`,
+		status, now, limit,
+	)
+
+	return rows, err
+}
+
+func (gw *WebhookGateway) FindWebhookDeliveries(webhookID, offset, limit int) ([]*store.WebhookDeliveryRow, error) {
+	var rows []*store.WebhookDeliveryRow
+	err := gw.db.Select(&rows,
+		`SELECT * FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC OFFSET $2 LIMIT $3;`,
+		webhookID, offset, limit,
+	)
+
+	return rows, err
+}
+
+func (gw *WebhookGateway) UpdateWebhookDelivery(d *store.WebhookDeliveryRow) error {
+	d.UpdatedAt = time.Now()
+
+	res, err := gw.db.NamedExec(
+		`UPDATE webhook_deliveries SET status = :status, attempts = :attempts, response_code = :response_code, error = :error,
+		next_attempt_at = :next_attempt_at, updated_at = :updated_at
+		WHERE id = :id;`,
+		d,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("webhook delivery not found")
+	}
+
+	return nil
+}
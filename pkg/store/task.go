@@ -0,0 +1,17 @@
+package store
+
+import "time"
+
+type TaskRow struct {
+	ID          int        `db:"id"`
+	Kind        string     `db:"kind"`
+	UniqueKey   string     `db:"unique_key"` // if set, no other queued task has the key
+	Payload     []byte     `db:"payload"`    // JSON
+	Status      string     `db:"status"`
+	Attempts    int        `db:"attempts"`
+	LastError   string     `db:"last_error"`
+	RunAt       time.Time  `db:"run_at"`
+	LockedUntil *time.Time `db:"locked_until"` // the task is hidden from other workers until then
+	CreatedAt   time.Time  `db:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package store
+
+import "time"
+
+type UploadRow struct {
+	ID          string    `db:"id"`
+	UserID      int       `db:"user_id"`
+	Filename    string    `db:"filename"`
+	ContentType string    `db:"content_type"`
+	Size        int64     `db:"size"`
+	Offset      int64     `db:"offset"`
+	Checksum    string    `db:"checksum"` // hex encoded SHA-256 given by the client
+	Status      string    `db:"status"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRow struct {
+	ID         int            `db:"id"`
+	UserID     int            `db:"user_id"` // the admin who created the webhook
+	URL        string         `db:"url"`
+	Secret     string         `db:"secret"`
+	EventTypes pq.StringArray `db:"event_types"`
+	IsActive   bool           `db:"is_active"`
+	CreatedAt  time.Time      `db:"created_at"`
+	UpdatedAt  time.Time      `db:"updated_at"`
+}
+
+// WebhookDeliveryRow is a single event sent to a webhook, with the result of its last attempt
+type WebhookDeliveryRow struct {
+	ID            int       `db:"id"`
+	WebhookID     int       `db:"webhook_id"`
+	EventType     string    `db:"event_type"`
+	Payload       []byte    `db:"payload"`
+	Status        string    `db:"status"`
+	Attempts      int       `db:"attempts"`
+	ResponseCode  int       `db:"response_code"` // 0 if no response was received
+	Error         string    `db:"error"`
+	NextAttemptAt time.Time `db:"next_attempt_at"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
@@ -0,0 +1,247 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrClosed = errors.New("stream closed")
+)
+
+// Message is a single event delivered to a user.
+// ID increases across every user of a Hub,
+// so clients can resume after reconnecting by sending the last ID they received.
+type Message struct {
+	ID   int64           `json:"id"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type Config struct {
+	// ReplaySize is the number of recent messages kept per user for resuming
+	ReplaySize int
+
+	// BufferSize is the number of messages a connection can fall behind
+	// before it is dropped. Dropped clients are expected to reconnect
+	// and resume from the replay buffer.
+	BufferSize int
+
+	// ReplayTTL is how long the messages of a user without connections are kept for resuming,
+	// the user is then forgotten
+	ReplayTTL time.Duration
+}
+
+const (
+	defaultReplaySize = 100
+	defaultBufferSize = 32
+	defaultReplayTTL  = 10 * time.Minute
+)
+
+// Hub fans out messages to every connection of a user
+type Hub struct {
+	mu     sync.Mutex
+	lastID int64
+	users  map[int]*userStream
+	closed bool
+
+	replaySize int
+	bufferSize int
+	replayTTL  time.Duration
+	evictedAt  time.Time
+}
+
+type userStream struct {
+	replay        []*Message
+	subscriptions map[*Subscription]struct{}
+	// updatedAt is when the last message was published or the last connection ended
+	updatedAt time.Time
+}
+
+func NewHub(config *Config) *Hub {
+	h := &Hub{
+		users:      make(map[int]*userStream),
+		replaySize: config.ReplaySize,
+		bufferSize: config.BufferSize,
+		replayTTL:  config.ReplayTTL,
+		evictedAt:  time.Now(),
+	}
+
+	if h.replaySize <= 0 {
+		h.replaySize = defaultReplaySize
+	}
+
+	if h.bufferSize <= 0 {
+		h.bufferSize = defaultBufferSize
+	}
+
+	if h.replayTTL <= 0 {
+		h.replayTTL = defaultReplayTTL
+	}
+
+	return h
+}
+
+// Publish sends a message to every connection of the user and keeps it for replay.
+// It never blocks: connections which can not keep up are dropped.
+func (h *Hub) Publish(userID int, messageType string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return ErrClosed
+	}
+
+	now := time.Now()
+	h.evict(now)
+
+	h.lastID++
+	m := &Message{ID: h.lastID, Type: messageType, Data: raw}
+
+	u := h.user(userID)
+	u.updatedAt = now
+	u.replay = append(u.replay, m)
+	if len(u.replay) > h.replaySize {
+		u.replay = u.replay[len(u.replay)-h.replaySize:]
+	}
+
+	for sub := range u.subscriptions {
+		select {
+		case sub.c <- m:
+		default:
+			// slow consumer
+			h.remove(sub)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe returns a subscription receiving every message published to the user from now on,
+// preceded by the messages in the replay buffer with an ID greater than lastID.
+// Use lastID = 0 to skip the replay.
+func (h *Hub) Subscribe(userID int, lastID int64) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	h.evict(time.Now())
+	u := h.user(userID)
+
+	var missed []*Message
+	if lastID > 0 {
+		for _, m := range u.replay {
+			if m.ID > lastID {
+				missed = append(missed, m)
+			}
+		}
+	}
+
+	sub := &Subscription{
+		hub:    h,
+		userID: userID,
+		c:      make(chan *Message, h.bufferSize+len(missed)),
+	}
+	for _, m := range missed {
+		sub.c <- m
+	}
+
+	u.subscriptions[sub] = struct{}{}
+
+	return sub, nil
+}
+
+// Close ends every subscription and rejects new ones
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+
+	for _, u := range h.users {
+		for sub := range u.subscriptions {
+			h.remove(sub)
+		}
+	}
+}
+
+func (h *Hub) user(userID int) *userStream {
+	u, ok := h.users[userID]
+	if !ok {
+		u = &userStream{subscriptions: make(map[*Subscription]struct{})}
+		h.users[userID] = u
+	}
+
+	return u
+}
+
+// evict forgets the users without connections whose messages are older than the replay TTL.
+// Users are scanned at most once per TTL, it must be called with h.mu held.
+func (h *Hub) evict(now time.Time) {
+	if now.Sub(h.evictedAt) < h.replayTTL {
+		return
+	}
+	h.evictedAt = now
+
+	for id, u := range h.users {
+		if len(u.subscriptions) == 0 && now.Sub(u.updatedAt) >= h.replayTTL {
+			delete(h.users, id)
+		}
+	}
+}
+
+// remove must be called with h.mu held
+func (h *Hub) remove(sub *Subscription) {
+	u, ok := h.users[sub.userID]
+	if !ok {
+		return
+	}
+
+	if _, ok := u.subscriptions[sub]; !ok {
+		return
+	}
+
+	delete(u.subscriptions, sub)
+	close(sub.c)
+
+	if len(u.subscriptions) == 0 {
+		u.updatedAt = time.Now()
+
+		// nothing to resume
+		if len(u.replay) == 0 {
+			delete(h.users, sub.userID)
+		}
+	}
+}
+
+type Subscription struct {
+	hub    *Hub
+	userID int
+	c      chan *Message
+}
+
+// C is closed when the subscription ends,
+// either by calling Close, closing the Hub or falling too far behind
+func (s *Subscription) C() <-chan *Message {
+	return s.c
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.remove(s)
+}
@@ -0,0 +1,227 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, sub *Subscription) *Message {
+	t.Helper()
+
+	select {
+	case m, ok := <-sub.C():
+		require.True(t, ok, "subscription closed")
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return nil
+	}
+}
+
+func TestHub(t *testing.T) {
+	t.Run("deliver to every connection of the user only", func(t *testing.T) {
+		h := NewHub(&Config{})
+		first, _ := h.Subscribe(1, 0)
+		second, _ := h.Subscribe(1, 0)
+		other, _ := h.Subscribe(2, 0)
+
+		require.NoError(t, h.Publish(1, "notification", map[string]int{"id": 1}))
+
+		assert.JSONEq(t, `{"id":1}`, string(receive(t, first).Data))
+		assert.JSONEq(t, `{"id":1}`, string(receive(t, second).Data))
+		assert.Len(t, other.C(), 0)
+	})
+
+	t.Run("resume from last event ID", func(t *testing.T) {
+		h := NewHub(&Config{ReplaySize: 2})
+		for i := 0; i < 3; i++ {
+			require.NoError(t, h.Publish(1, "notification", i))
+		}
+
+		sub, _ := h.Subscribe(1, 1)
+		assert.Equal(t, int64(2), receive(t, sub).ID)
+		assert.Equal(t, int64(3), receive(t, sub).ID)
+
+		sub, _ = h.Subscribe(1, 0)
+		assert.Len(t, sub.C(), 0, "no replay without last event ID")
+	})
+
+	t.Run("drop slow connection", func(t *testing.T) {
+		h := NewHub(&Config{BufferSize: 1})
+		sub, _ := h.Subscribe(1, 0)
+
+		require.NoError(t, h.Publish(1, "notification", 1))
+		require.NoError(t, h.Publish(1, "notification", 2))
+
+		receive(t, sub)
+		_, ok := <-sub.C()
+		assert.False(t, ok)
+	})
+
+	t.Run("evict users without connections", func(t *testing.T) {
+		h := NewHub(&Config{ReplayTTL: 20 * time.Millisecond})
+
+		sub, _ := h.Subscribe(1, 0)
+		sub.Close()
+		assert.NotContains(t, h.users, 1, "nothing to resume")
+
+		require.NoError(t, h.Publish(2, "notification", 1))
+		connected, _ := h.Subscribe(3, 0)
+		time.Sleep(30 * time.Millisecond)
+
+		require.NoError(t, h.Publish(4, "notification", 1))
+		assert.NotContains(t, h.users, 2)
+		assert.Contains(t, h.users, 3)
+		assert.Contains(t, h.users, 4)
+		connected.Close()
+	})
+
+	t.Run("close", func(t *testing.T) {
+		h := NewHub(&Config{})
+		sub, _ := h.Subscribe(1, 0)
+		sub.Close()
+		sub.Close()
+
+		sub, _ = h.Subscribe(1, 0)
+		h.Close()
+
+		_, ok := <-sub.C()
+		assert.False(t, ok)
+		assert.Equal(t, ErrClosed, h.Publish(1, "notification", 1))
+
+		_, err := h.Subscribe(1, 0)
+		assert.Equal(t, ErrClosed, err)
+	})
+}
+
+func newTestServer(h *Hub, serve func(w http.ResponseWriter, r *http.Request, sub *Subscription, heartbeat time.Duration) error) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sub, err := h.Subscribe(1, LastEventID(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		defer sub.Close()
+
+		_ = serve(w, r, sub, 20*time.Millisecond)
+	}))
+}
+
+func TestServeSSE(t *testing.T) {
+	h := NewHub(&Config{})
+	require.NoError(t, h.Publish(1, "notification", "missed"))
+
+	srv := newTestServer(h, ServeSSE)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	require.NoError(t, h.Publish(1, "notification", "hello"))
+
+	r := bufio.NewReader(res.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSpace(line)
+		if line == "" || line == ": ping" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	assert.Equal(t, []string{"id: 2", "event: notification", `data: "hello"`}, lines)
+
+	h.Close()
+}
+
+func TestServeWebSocket(t *testing.T) {
+	h := NewHub(&Config{})
+	require.NoError(t, h.Publish(1, "notification", "missed"))
+
+	srv := newTestServer(h, ServeWebSocket)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET /?last_event_id=0 HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"))
+	require.NoError(t, err)
+
+	r := bufio.NewReader(conn)
+	res, err := http.ReadResponse(r, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", res.Header.Get("Sec-WebSocket-Accept"))
+
+	require.NoError(t, h.Publish(1, "notification", "hello"))
+
+	for {
+		op, payload, err := readServerFrame(r)
+		require.NoError(t, err)
+
+		if op == opPing {
+			continue
+		}
+
+		require.Equal(t, byte(opText), op)
+
+		var m Message
+		require.NoError(t, json.Unmarshal(payload, &m))
+		assert.Equal(t, int64(2), m.ID)
+		assert.Equal(t, "notification", m.Type)
+		assert.JSONEq(t, `"hello"`, string(m.Data))
+		break
+	}
+
+	h.Close()
+	op, _, err := readServerFrame(r)
+	require.NoError(t, err)
+	assert.Equal(t, byte(opClose), op)
+}
+
+// readServerFrame reads an unmasked frame, readFrame only accepts masked client frames
+func readServerFrame(r io.Reader) (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	n := int(header[1] & 0x7F)
+	if n == 126 {
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return header[0] & 0x0F, payload, nil
+}
@@ -0,0 +1,27 @@
+package stream
+
+import (
+	"log"
+
+	"github.com/victornm/es-backend/pkg/event"
+)
+
+// Forward listens for events of the same type as e on the bus
+// and publishes them to the user returned by toMessage as messageType.
+// toMessage returns userID = 0 for events that should not be streamed.
+func Forward(b *event.Bus, h *Hub, e interface{}, messageType string, toMessage func(e interface{}) (userID int, data interface{})) {
+	sub := b.Subscribe(e, nil)
+
+	go func() {
+		for received := range sub.C() {
+			userID, data := toMessage(received)
+			if userID == 0 {
+				continue
+			}
+
+			if err := h.Publish(userID, messageType, data); err != nil {
+				log.Printf("stream %s to user %d failed: %v", messageType, userID, err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,253 @@
+package stream
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A minimal server side WebSocket (RFC 6455), only what the stream needs:
+// the server pushes text frames and pings, the client may only send control frames.
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+const (
+	maxControlPayload = 125
+	maxClientPayload  = 4096
+	writeTimeout      = 10 * time.Second
+)
+
+var errProtocol = errors.New("websocket protocol error")
+
+// IsWebSocket reports whether r asks for a WebSocket upgrade
+func IsWebSocket(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") &&
+		headerContains(r.Header, "Upgrade", "websocket")
+}
+
+func headerContains(h http.Header, key, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(key)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ServeWebSocket upgrades the connection and writes messages from sub as JSON text frames,
+// sending a ping every heartbeat.
+// It returns when the client closes the connection or the subscription ends.
+func ServeWebSocket(w http.ResponseWriter, r *http.Request, sub *Subscription, heartbeat time.Duration) error {
+	conn, rw, err := upgrade(w, r)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// the client may only send control frames, reading them in the background
+	// lets us answer pings and notice when the client goes away
+	pings := make(chan []byte, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			op, payload, err := readFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+
+			switch op {
+			case opClose:
+				return
+			case opPing:
+				select {
+				case pings <- payload:
+				default:
+				}
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			_ = writeFrame(conn, rw.Writer, opClose, nil)
+			return nil
+
+		case payload := <-pings:
+			if err := writeFrame(conn, rw.Writer, opPong, payload); err != nil {
+				return err
+			}
+
+		case <-ticker.C:
+			if err := writeFrame(conn, rw.Writer, opPing, nil); err != nil {
+				return err
+			}
+
+		case m, ok := <-sub.C():
+			if !ok {
+				_ = writeFrame(conn, rw.Writer, opClose, nil)
+				return nil
+			}
+
+			b, err := json.Marshal(m)
+			if err != nil {
+				return err
+			}
+
+			if err := writeFrame(conn, rw.Writer, opText, b); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func upgrade(w http.ResponseWriter, r *http.Request) (net.Conn, *bufio.ReadWriter, error) {
+	if r.Method != http.MethodGet || !IsWebSocket(r) {
+		http.Error(w, "not a websocket handshake", http.StatusBadRequest)
+		return nil, nil, errProtocol
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, nil, errProtocol
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, nil, errProtocol
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, nil, errors.New("response writer can not be hijacked")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err == nil {
+		err = rw.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, rw, nil
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func writeFrame(conn net.Conn, w *bufio.Writer, op byte, payload []byte) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	header := []byte{0x80 | op} // FIN, server frames are never masked
+	switch n := len(payload); {
+	case n <= 125:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	op := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	n := uint64(header[1] & 0x7F)
+
+	// clients must mask every frame
+	if !masked {
+		return 0, nil, errProtocol
+	}
+
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if n > maxClientPayload || (op >= opClose && n > maxControlPayload) {
+		return 0, nil, errProtocol
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return op, payload, nil
+}
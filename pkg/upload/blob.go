@@ -0,0 +1,130 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Blob is an opened stored file
+type Blob interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// BlobStore keeps the content of uploads.
+// Content is written in chunks, each chunk must start where the previous one ended.
+type BlobStore interface {
+	// Append writes r at the end of the blob, creating it if needed.
+	// It fails if the current size of the blob is not offset.
+	// Returns the number of bytes written.
+	Append(key string, offset int64, r io.Reader) (int64, error)
+	Open(key string) (Blob, error)
+	Delete(key string) error
+
+	// Keys returns the keys of every blob
+	Keys() ([]string, error)
+}
+
+var ErrOffsetMismatch = errors.New("offset mismatch")
+
+var validKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// LocalStore keeps blobs as files in a directory
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &LocalStore{dir: dir}, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if !validKey.MatchString(key) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+
+	return filepath.Join(s.dir, key), nil
+}
+
+func (s *LocalStore) Append(key string, offset int64, r io.Reader) (int64, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if info.Size() != offset {
+		return 0, ErrOffsetMismatch
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		// drop the partial chunk so the client can retry from the same offset
+		_ = f.Truncate(offset)
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (s *LocalStore) Open(key string) (Blob, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(p)
+}
+
+func (s *LocalStore) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *LocalStore) Keys() ([]string, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, f := range files {
+		if f.Mode().IsRegular() && validKey.MatchString(f.Name()) {
+			keys = append(keys, f.Name())
+		}
+	}
+
+	return keys, nil
+}
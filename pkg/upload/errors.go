@@ -0,0 +1,15 @@
+package upload
+
+import "errors"
+
+var (
+	ErrNotFound              = errors.New("upload not found")
+	ErrQuotaExceeded         = errors.New("upload quota exceeded")
+	ErrTooLarge              = errors.New("file too large")
+	ErrChecksumMismatch      = errors.New("checksum mismatch")
+	ErrContentTypeNotAllowed = errors.New("content type not allowed")
+	ErrNotCompleted          = errors.New("upload not completed")
+	ErrInvalidSignature      = errors.New("invalid or expired signature")
+	ErrInvalidInput          = errors.New("invalid input")
+	ErrUnknown               = errors.New("unknown error")
+)
@@ -0,0 +1,83 @@
+package upload
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+const (
+	StatusPending   = "pending"   // waiting for more chunks
+	StatusCompleted = "completed" // every byte received and checksum verified
+	StatusFailed    = "failed"    // rejected after the last chunk, waiting for garbage collection
+)
+
+// allowedContentTypes are sniffed from the content, not trusted from the client.
+// Office slides (pptx, odp) are zip archives.
+var allowedContentTypes = []string{
+	"application/pdf",
+	"application/zip",
+	"image/",
+	"video/mp4",
+	"video/webm",
+}
+
+func allowedContentType(contentType string) bool {
+	for _, allowed := range allowedContentTypes {
+		if strings.HasPrefix(contentType, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type CreateInput struct {
+	Filename string `json:"filename" validate:"required,max=255"`
+	Size     int64  `json:"size" validate:"required,gt=0"`
+	Checksum string `json:"checksum" validate:"required,len=64"` // hex encoded SHA-256 of the whole file
+}
+
+func (i *CreateInput) Valid() error {
+	if _, err := hex.DecodeString(i.Checksum); err != nil {
+		return fmt.Errorf("checksum is not hex encoded: %v", err)
+	}
+
+	return validator.New().Struct(i)
+}
+
+type UploadDTO struct {
+	ID          string    `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Offset      int64     `json:"offset"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// File is the content of a completed upload
+type File struct {
+	Blob
+	Filename    string
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+func toUploadDTO(row *store.UploadRow) *UploadDTO {
+	return &UploadDTO{
+		ID:          row.ID,
+		Filename:    row.Filename,
+		ContentType: row.ContentType,
+		Size:        row.Size,
+		Offset:      row.Offset,
+		Status:      row.Status,
+		CreatedAt:   row.CreatedAt,
+	}
+}
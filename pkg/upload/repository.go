@@ -0,0 +1,22 @@
+package upload
+
+import (
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type Repository interface {
+	// CreateUploadWithinQuota creates u unless the uploads of its user would be larger than quota, it returns false then.
+	// Checking the quota and creating u are atomic, so concurrent uploads can not exceed the quota.
+	CreateUploadWithinQuota(u *store.UploadRow, quota int64) (bool, error)
+	FindUploadByID(id string) (*store.UploadRow, error)
+	UpdateUpload(u *store.UploadRow) error
+	DeleteUpload(id string) error
+
+	SumUploadSizeByUserID(userID int) (int64, error)
+	FindStaleUploads(statuses []string, updatedBefore time.Time) ([]*store.UploadRow, error)
+
+	// FindExistingUploadIDs returns the IDs of ids which have an upload
+	FindExistingUploadIDs(ids []string) ([]string, error)
+}
@@ -0,0 +1,431 @@
+package upload
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var _ Service = (*service)(nil)
+
+// Service implements resumable uploads:
+// the client creates an upload with the size and checksum of the file,
+// then sends the content in chunks, each starting at the offset returned by the previous one.
+// After a failure, the client gets the upload to know where to resume.
+type Service interface {
+	Create(userID int, input *CreateInput) (*UploadDTO, error)
+	WriteChunk(userID int, id string, offset int64, chunk io.Reader) (*UploadDTO, error)
+	Get(userID int, id string) (*UploadDTO, error)
+
+	// Delete removes an upload and its content, which frees its size from the quota of the user
+	Delete(userID int, id string) error
+
+	// SignURL returns an expiring URL to download a completed upload without authentication
+	SignURL(userID int, id string) (string, error)
+	// Open returns the content of a completed upload if the signature from SignURL is valid
+	Open(id string, expires int64, signature string) (*File, error)
+
+	// CollectGarbage removes pending uploads abandoned for too long, failed uploads,
+	// and, if Config.RemoveOrphanBlobs is set, blobs left without an upload, for example when the uploads were lost by a restart
+	CollectGarbage() (int, error)
+}
+
+type Config struct {
+	Repository Repository
+	BlobStore  BlobStore
+
+	MaxFileSize int64 // bytes
+	UserQuota   int64 // total bytes of every upload of a user
+
+	Secret      string        // key for signing download URLs
+	DownloadURL string        // absolute URL of the download endpoint, the upload ID is appended
+	URLExpiry   time.Duration // lifetime of signed URLs
+
+	PendingTTL time.Duration // pending uploads not updated for this long are collected
+
+	// RemoveOrphanBlobs lets CollectGarbage remove the blobs without an upload.
+	// Only set it if Repository holds the uploads of every process using BlobStore,
+	// otherwise the blobs of the other processes look orphaned.
+	RemoveOrphanBlobs bool
+}
+
+type service struct {
+	repository Repository
+	blobs      BlobStore
+	locks      *keyedMutex
+
+	maxFileSize int64
+	userQuota   int64
+
+	secret      []byte
+	downloadURL string
+	urlExpiry   time.Duration
+
+	pendingTTL        time.Duration
+	removeOrphanBlobs bool
+}
+
+func New(config *Config) Service {
+	return &service{
+		repository:  config.Repository,
+		blobs:       config.BlobStore,
+		locks:       newKeyedMutex(),
+		maxFileSize: config.MaxFileSize,
+		userQuota:   config.UserQuota,
+		secret:      []byte(config.Secret),
+		downloadURL: config.DownloadURL,
+		urlExpiry:   config.URLExpiry,
+		pendingTTL:  config.PendingTTL,
+
+		removeOrphanBlobs: config.RemoveOrphanBlobs,
+	}
+}
+
+func (s *service) Create(userID int, input *CreateInput) (*UploadDTO, error) {
+	if err := validate(input); err != nil {
+		return nil, errorutil.Wrap(ErrInvalidInput, err)
+	}
+
+	if input.Size > s.maxFileSize {
+		return nil, errorutil.Wrap(ErrTooLarge, "max %d bytes", s.maxFileSize)
+	}
+
+	row := &store.UploadRow{
+		ID:       uuid.New().String(),
+		UserID:   userID,
+		Filename: input.Filename,
+		Size:     input.Size,
+		Checksum: strings.ToLower(input.Checksum), // compared with the lowercase hex of the content
+		Status:   StatusPending,
+	}
+
+	created, err := s.repository.CreateUploadWithinQuota(row, s.userQuota)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	if !created {
+		used, _ := s.repository.SumUploadSizeByUserID(userID)
+		return nil, errorutil.Wrap(ErrQuotaExceeded, "used %d of %d bytes", used, s.userQuota)
+	}
+
+	return toUploadDTO(row), nil
+}
+
+func (s *service) WriteChunk(userID int, id string, offset int64, chunk io.Reader) (*UploadDTO, error) {
+	s.locks.Lock(id)
+	defer s.locks.Unlock(id)
+
+	row, err := s.find(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if row.Status != StatusPending {
+		return nil, errorutil.Wrap(ErrInvalidInput, "upload is %s", row.Status)
+	}
+
+	if offset != row.Offset {
+		return nil, errorutil.Wrap(ErrOffsetMismatch, "expected %d, got %d", row.Offset, offset)
+	}
+
+	// read one more byte than allowed to detect chunks going past the declared size
+	remaining := row.Size - row.Offset
+	n, err := s.blobs.Append(row.ID, row.Offset, io.LimitReader(chunk, remaining+1))
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	if n > remaining {
+		// the extra byte is already written, drop the whole upload
+		row.Status = StatusFailed
+		_ = s.repository.UpdateUpload(row)
+		return nil, errorutil.Wrap(ErrTooLarge, "upload is %d bytes", row.Size)
+	}
+
+	row.Offset += n
+	if row.Offset == row.Size {
+		if err := s.complete(row); err != nil {
+			row.Status = StatusFailed
+			_ = s.repository.UpdateUpload(row)
+			return nil, err
+		}
+	}
+
+	if err := s.repository.UpdateUpload(row); err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return toUploadDTO(row), nil
+}
+
+// complete verifies the checksum and sniffs the content type of a fully received upload
+func (s *service) complete(row *store.UploadRow) error {
+	blob, err := s.blobs.Open(row.ID)
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+	defer blob.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(blob, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	if !allowedContentType(contentType) {
+		return errorutil.Wrap(ErrContentTypeNotAllowed, contentType)
+	}
+
+	if _, err := blob.Seek(0, io.SeekStart); err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, blob); err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != row.Checksum {
+		return errorutil.Wrap(ErrChecksumMismatch, "got %s", sum)
+	}
+
+	row.ContentType = contentType
+	row.Status = StatusCompleted
+
+	return nil
+}
+
+func (s *service) Get(userID int, id string) (*UploadDTO, error) {
+	row, err := s.find(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toUploadDTO(row), nil
+}
+
+func (s *service) Delete(userID int, id string) error {
+	if _, err := s.find(userID, id); err != nil {
+		return err
+	}
+
+	if err := s.remove(id); err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return nil
+}
+
+func (s *service) find(userID int, id string) (*store.UploadRow, error) {
+	row, err := s.repository.FindUploadByID(id)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrNotFound, err)
+	}
+
+	if row.UserID != userID {
+		return nil, errorutil.Wrap(ErrNotFound, id)
+	}
+
+	return row, nil
+}
+
+func (s *service) SignURL(userID int, id string) (string, error) {
+	row, err := s.find(userID, id)
+	if err != nil {
+		return "", err
+	}
+
+	if row.Status != StatusCompleted {
+		return "", errorutil.Wrap(ErrNotCompleted, id)
+	}
+
+	expires := time.Now().Add(s.urlExpiry).Unix()
+
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("signature", s.sign(id, expires))
+
+	return s.downloadURL + "/" + url.PathEscape(id) + "?" + q.Encode(), nil
+}
+
+func (s *service) Open(id string, expires int64, signature string) (*File, error) {
+	expected := s.sign(id, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) || time.Now().Unix() > expires {
+		return nil, errorutil.Wrap(ErrInvalidSignature)
+	}
+
+	row, err := s.repository.FindUploadByID(id)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrNotFound, err)
+	}
+
+	if row.Status != StatusCompleted {
+		return nil, errorutil.Wrap(ErrNotCompleted, id)
+	}
+
+	blob, err := s.blobs.Open(row.ID)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return &File{
+		Blob:        blob,
+		Filename:    row.Filename,
+		ContentType: row.ContentType,
+		Size:        row.Size,
+		ModTime:     row.UpdatedAt,
+	}, nil
+}
+
+func (s *service) sign(id string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	_, _ = fmt.Fprintf(mac, "%s\n%d", id, expires)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *service) CollectGarbage() (int, error) {
+	var stale []*store.UploadRow
+
+	pending, err := s.repository.FindStaleUploads([]string{StatusPending}, time.Now().Add(-s.pendingTTL))
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+	stale = append(stale, pending...)
+
+	failed, err := s.repository.FindStaleUploads([]string{StatusFailed}, time.Now())
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+	stale = append(stale, failed...)
+
+	removed := 0
+	for _, row := range stale {
+		if err := s.remove(row.ID); err != nil {
+			log.Printf("remove upload %s failed: %v", row.ID, err)
+			continue
+		}
+		removed++
+	}
+
+	if !s.removeOrphanBlobs {
+		return removed, nil
+	}
+
+	orphans, err := s.removeOrphans()
+	if err != nil {
+		return removed, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return removed + orphans, nil
+}
+
+// removeOrphans deletes the blobs without an upload.
+// Uploads are created before their blob, so a blob without an upload is never written again.
+func (s *service) removeOrphans() (int, error) {
+	keys, err := s.blobs.Keys()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	ids, err := s.repository.FindExistingUploadIDs(keys)
+	if err != nil {
+		return 0, err
+	}
+
+	existing := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		existing[id] = true
+	}
+
+	removed := 0
+	for _, key := range keys {
+		if existing[key] {
+			continue
+		}
+
+		if err := s.blobs.Delete(key); err != nil {
+			log.Printf("remove orphaned blob %s failed: %v", key, err)
+			continue
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+func (s *service) remove(id string) error {
+	s.locks.Lock(id)
+	defer s.locks.Unlock(id)
+
+	if err := s.blobs.Delete(id); err != nil {
+		return err
+	}
+
+	return s.repository.DeleteUpload(id)
+}
+
+// keyedMutex serializes chunk writes to the same upload
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	waiting int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: make(map[string]*keyedLock)}
+}
+
+func (m *keyedMutex) Lock(key string) {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.waiting++
+	m.mu.Unlock()
+
+	l.Lock()
+}
+
+func (m *keyedMutex) Unlock(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.locks[key]
+	if !ok {
+		panic(errors.New("unlock of unlocked key " + key))
+	}
+
+	l.waiting--
+	if l.waiting == 0 {
+		delete(m.locks, key)
+	}
+	l.Unlock()
+}
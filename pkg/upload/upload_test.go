@@ -0,0 +1,294 @@
+package upload_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victornm/es-backend/pkg/store/memory"
+	. "github.com/victornm/es-backend/pkg/upload"
+)
+
+// a minimal PDF, enough for content sniffing
+var pdf = []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\ntrailer << /Root 1 0 R >>\n%%EOF\n")
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// newService returns the service and a func removing its blobs
+func newService(t *testing.T, pendingTTL time.Duration) (Service, func()) {
+	s, _, cleanup := newServiceWithBlobs(t, pendingTTL, true)
+	return s, cleanup
+}
+
+func newServiceWithBlobs(t *testing.T, pendingTTL time.Duration, removeOrphanBlobs bool) (Service, BlobStore, func()) {
+	dir, err := ioutil.TempDir("", "upload")
+	require.NoError(t, err)
+
+	blobs, err := NewLocalStore(dir)
+	require.NoError(t, err)
+
+	return New(&Config{
+		Repository:  memory.NewUploadGateway(),
+		BlobStore:   blobs,
+		MaxFileSize: 1024,
+		UserQuota:   2048,
+		Secret:      "#12345",
+		DownloadURL: "http://localhost/api/files",
+		URLExpiry:   time.Minute,
+		PendingTTL:  pendingTTL,
+
+		RemoveOrphanBlobs: removeOrphanBlobs,
+	}), blobs, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestUpload(t *testing.T) {
+	s, cleanup := newService(t, time.Hour)
+	defer cleanup()
+
+	u, err := s.Create(1, &CreateInput{Filename: "slides.pdf", Size: int64(len(pdf)), Checksum: checksum(pdf)})
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, u.Status)
+
+	// resume after the first chunk
+	u, err = s.WriteChunk(1, u.ID, 0, bytes.NewReader(pdf[:10]))
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), u.Offset)
+
+	_, err = s.WriteChunk(1, u.ID, 0, bytes.NewReader(pdf[:10]))
+	assertIsError(t, ErrOffsetMismatch, err)
+
+	_, err = s.Get(2, u.ID)
+	assertIsError(t, ErrNotFound, err, "other user's upload")
+
+	u, err = s.Get(1, u.ID)
+	require.NoError(t, err)
+
+	u, err = s.WriteChunk(1, u.ID, u.Offset, bytes.NewReader(pdf[u.Offset:]))
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, u.Status)
+	assert.Equal(t, "application/pdf", u.ContentType)
+
+	t.Run("download with signed URL", func(t *testing.T) {
+		signed, err := s.SignURL(1, u.ID)
+		require.NoError(t, err)
+
+		parsed, err := url.Parse(signed)
+		require.NoError(t, err)
+		assert.Equal(t, "/api/files/"+u.ID, parsed.Path)
+
+		expires, _ := strconv.ParseInt(parsed.Query().Get("expires"), 10, 64)
+		signature := parsed.Query().Get("signature")
+
+		f, err := s.Open(u.ID, expires, signature)
+		require.NoError(t, err)
+		defer f.Close()
+
+		content, err := ioutil.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, pdf, content)
+
+		_, err = s.Open(u.ID, expires+1, signature)
+		assertIsError(t, ErrInvalidSignature, err, "tampered expiry")
+
+		_, err = s.Open(u.ID, time.Now().Add(-time.Second).Unix(), signature)
+		assertIsError(t, ErrInvalidSignature, err, "expired")
+	})
+
+	t.Run("uppercase checksum", func(t *testing.T) {
+		u, err := s.Create(1, &CreateInput{Filename: "slides.pdf", Size: int64(len(pdf)), Checksum: strings.ToUpper(checksum(pdf))})
+		require.NoError(t, err)
+
+		u, err = s.WriteChunk(1, u.ID, 0, bytes.NewReader(pdf))
+		require.NoError(t, err)
+		assert.Equal(t, StatusCompleted, u.Status)
+	})
+}
+
+func TestUpload_Rejected(t *testing.T) {
+	t.Run("checksum mismatch", func(t *testing.T) {
+		s, cleanup := newService(t, time.Hour)
+		defer cleanup()
+		u, err := s.Create(1, &CreateInput{Filename: "slides.pdf", Size: int64(len(pdf)), Checksum: checksum([]byte("other"))})
+		require.NoError(t, err)
+
+		_, err = s.WriteChunk(1, u.ID, 0, bytes.NewReader(pdf))
+		assertIsError(t, ErrChecksumMismatch, err)
+
+		u, _ = s.Get(1, u.ID)
+		assert.Equal(t, StatusFailed, u.Status)
+	})
+
+	t.Run("content type not allowed", func(t *testing.T) {
+		s, cleanup := newService(t, time.Hour)
+		defer cleanup()
+		script := []byte("#!/bin/sh\necho hello\n")
+		u, err := s.Create(1, &CreateInput{Filename: "slides.pdf", Size: int64(len(script)), Checksum: checksum(script)})
+		require.NoError(t, err)
+
+		_, err = s.WriteChunk(1, u.ID, 0, bytes.NewReader(script))
+		assertIsError(t, ErrContentTypeNotAllowed, err)
+	})
+
+	t.Run("chunk larger than declared size", func(t *testing.T) {
+		s, cleanup := newService(t, time.Hour)
+		defer cleanup()
+		u, err := s.Create(1, &CreateInput{Filename: "slides.pdf", Size: 10, Checksum: checksum(pdf[:10])})
+		require.NoError(t, err)
+
+		_, err = s.WriteChunk(1, u.ID, 0, bytes.NewReader(pdf))
+		assertIsError(t, ErrTooLarge, err)
+	})
+
+	t.Run("file too large", func(t *testing.T) {
+		s, cleanup := newService(t, time.Hour)
+		defer cleanup()
+		_, err := s.Create(1, &CreateInput{Filename: "video.mp4", Size: 1025, Checksum: checksum(pdf)})
+		assertIsError(t, ErrTooLarge, err)
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		s, cleanup := newService(t, time.Hour)
+		defer cleanup()
+		for i := 0; i < 2; i++ {
+			_, err := s.Create(1, &CreateInput{Filename: "video.mp4", Size: 1024, Checksum: checksum(pdf)})
+			require.NoError(t, err)
+		}
+
+		_, err := s.Create(1, &CreateInput{Filename: "video.mp4", Size: 1, Checksum: checksum(pdf)})
+		assertIsError(t, ErrQuotaExceeded, err)
+
+		_, err = s.Create(2, &CreateInput{Filename: "video.mp4", Size: 1, Checksum: checksum(pdf)})
+		assert.NoError(t, err, "quota is per user")
+	})
+
+	t.Run("delete frees quota", func(t *testing.T) {
+		s, cleanup := newService(t, time.Hour)
+		defer cleanup()
+
+		var ids []string
+		for i := 0; i < 2; i++ {
+			u, err := s.Create(1, &CreateInput{Filename: "video.mp4", Size: 1024, Checksum: checksum(pdf)})
+			require.NoError(t, err)
+			ids = append(ids, u.ID)
+		}
+
+		assertIsError(t, ErrNotFound, s.Delete(2, ids[0]), "other user's upload")
+		require.NoError(t, s.Delete(1, ids[0]))
+		assertIsError(t, ErrNotFound, s.Delete(1, ids[0]))
+
+		_, err := s.Create(1, &CreateInput{Filename: "video.mp4", Size: 1024, Checksum: checksum(pdf)})
+		assert.NoError(t, err)
+	})
+
+	t.Run("quota exceeded concurrently", func(t *testing.T) {
+		s, cleanup := newService(t, time.Hour)
+		defer cleanup()
+
+		var mu sync.Mutex
+		created := 0
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := s.Create(1, &CreateInput{Filename: "video.mp4", Size: 1000, Checksum: checksum(pdf)}); err == nil {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 2, created)
+	})
+
+	t.Run("sign incomplete upload", func(t *testing.T) {
+		s, cleanup := newService(t, time.Hour)
+		defer cleanup()
+		u, err := s.Create(1, &CreateInput{Filename: "slides.pdf", Size: int64(len(pdf)), Checksum: checksum(pdf)})
+		require.NoError(t, err)
+
+		_, err = s.SignURL(1, u.ID)
+		assertIsError(t, ErrNotCompleted, err)
+	})
+}
+
+func TestCollectGarbage(t *testing.T) {
+	s, blobs, cleanup := newServiceWithBlobs(t, 50*time.Millisecond, true)
+	defer cleanup()
+
+	// left by an upload lost on a restart
+	_, err := blobs.Append("orphan", 0, bytes.NewReader(pdf))
+	require.NoError(t, err)
+
+	abandoned, _ := s.Create(1, &CreateInput{Filename: "a.pdf", Size: int64(len(pdf)), Checksum: checksum(pdf)})
+	_, _ = s.WriteChunk(1, abandoned.ID, 0, bytes.NewReader(pdf[:10]))
+
+	time.Sleep(100 * time.Millisecond)
+
+	failed, _ := s.Create(1, &CreateInput{Filename: "b.pdf", Size: int64(len(pdf)), Checksum: checksum([]byte("other"))})
+	_, _ = s.WriteChunk(1, failed.ID, 0, bytes.NewReader(pdf))
+
+	completed, _ := s.Create(1, &CreateInput{Filename: "c.pdf", Size: int64(len(pdf)), Checksum: checksum(pdf)})
+	_, _ = s.WriteChunk(1, completed.ID, 0, bytes.NewReader(pdf))
+
+	active, _ := s.Create(1, &CreateInput{Filename: "d.pdf", Size: int64(len(pdf)), Checksum: checksum(pdf)})
+
+	removed, err := s.CollectGarbage()
+	require.NoError(t, err)
+	assert.Equal(t, 3, removed)
+
+	keys, err := blobs.Keys()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{completed.ID}, keys, "only the blob of the completed upload should be left")
+
+	for _, id := range []string{abandoned.ID, failed.ID} {
+		_, err = s.Get(1, id)
+		assertIsError(t, ErrNotFound, err)
+	}
+
+	for _, id := range []string{completed.ID, active.ID} {
+		_, err = s.Get(1, id)
+		assert.NoError(t, err)
+	}
+}
+
+func TestCollectGarbage_KeepOrphanBlobs(t *testing.T) {
+	s, blobs, cleanup := newServiceWithBlobs(t, time.Hour, false)
+	defer cleanup()
+
+	// may belong to an upload of another process sharing the directory
+	_, err := blobs.Append("orphan", 0, bytes.NewReader(pdf))
+	require.NoError(t, err)
+
+	removed, err := s.CollectGarbage()
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+
+	keys, err := blobs.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orphan"}, keys)
+}
+
+func assertIsError(t *testing.T, wanted, got error, msgAndArgs ...interface{}) {
+	t.Helper()
+	if !errors.Is(got, wanted) {
+		t.Errorf("Error %v is not an %v %v", got, wanted, msgAndArgs)
+	}
+}
@@ -57,3 +57,63 @@ func NewQueryService(finder Finder) *queryService {
 type Finder interface {
 	FindUserByID(id int) (*store.UserRow, error)
 }
+
+/*
+ * PUBLIC PROFILE
+ */
+type GetPublicProfileQuery interface {
+	GetPublicProfile(id int) (*PublicProfileDTO, error)
+}
+
+// PublicProfileDTO is the profile visible to other users, without contact information
+type PublicProfileDTO struct {
+	ID             int    `json:"id"`
+	Username       string `json:"username"`
+	FullName       string `json:"full_name"`
+	Country        string `json:"country"`
+	FollowersCount int    `json:"followers_count"`
+	FollowingCount int    `json:"following_count"`
+}
+
+type publicQueryService struct {
+	finder  Finder
+	counter FollowCounter
+}
+
+func (s *publicQueryService) GetPublicProfile(id int) (*PublicProfileDTO, error) {
+	u, err := s.finder.FindUserByID(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	followers, err := s.counter.CountFollowers(id)
+	if err != nil {
+		return nil, err
+	}
+
+	following, err := s.counter.CountFollowing(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublicProfileDTO{
+		ID:             u.ID,
+		Username:       u.Username,
+		FullName:       u.FullName,
+		Country:        u.Country,
+		FollowersCount: followers,
+		FollowingCount: following,
+	}, nil
+}
+
+func NewPublicQueryService(finder Finder, counter FollowCounter) *publicQueryService {
+	return &publicQueryService{
+		finder:  finder,
+		counter: counter,
+	}
+}
+
+type FollowCounter interface {
+	CountFollowers(userID int) (int, error)
+	CountFollowing(userID int) (int, error)
+}
@@ -87,3 +87,32 @@ func (dao *mockUserDAO) CreateUser(u *store.UserRow) (int, error) {
 
 	return u.ID, nil
 }
+
+func TestGetPublicProfile(t *testing.T) {
+	dao := newMockUserDao()
+	dao.seed([]*store.UserRow{{Email: "alice@es.com", Username: "alice"}})
+
+	query := NewPublicQueryService(dao, mockFollowCounter{followers: 3, following: 1})
+
+	p, err := query.GetPublicProfile(1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "alice", p.Username)
+	assert.Equal(t, 3, p.FollowersCount)
+	assert.Equal(t, 1, p.FollowingCount)
+
+	_, err = query.GetPublicProfile(10)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+type mockFollowCounter struct {
+	followers int
+	following int
+}
+
+func (c mockFollowCounter) CountFollowers(userID int) (int, error) {
+	return c.followers, nil
+}
+
+func (c mockFollowCounter) CountFollowing(userID int) (int, error) {
+	return c.following, nil
+}
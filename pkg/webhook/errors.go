@@ -0,0 +1,10 @@
+package webhook
+
+import "errors"
+
+var (
+	ErrNotAdmin        = errors.New("not an admin")
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrInvalidInput    = errors.New("invalid input")
+	ErrUnknown         = errors.New("unknown error")
+)
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+
+	// StatusDead is the dead-letter state of deliveries which failed every attempt
+	StatusDead = "dead"
+)
+
+// TestEventType is sent by SendTest, webhooks do not need to subscribe to it
+const TestEventType = "webhook.test"
+
+// Topic is an event type webhooks can subscribe to
+type Topic struct {
+	// Event is the zero value of the event published on the bus
+	Event interface{}
+
+	// Payload returns the data sent to receivers for an event, a struct with json tags.
+	// Receivers are outside of the system, it must leave out personal data such as email addresses.
+	Payload func(e interface{}) interface{}
+}
+
+type WebhookInput struct {
+	URL        string   `json:"url" validate:"required,url,max=2048"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,required"`
+
+	// IsActive defaults to true when creating a webhook
+	IsActive *bool `json:"is_active"`
+}
+
+func (i *WebhookInput) Valid() error {
+	return validator.New().Struct(i)
+}
+
+// Payload is the JSON body posted to webhooks
+type Payload struct {
+	ID        int             `json:"id"` // the delivery ID, the same for every attempt
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type WebhookDTO struct {
+	ID         int       `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	IsActive   bool      `json:"is_active"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Secret is only returned when the webhook is created
+	Secret string `json:"secret,omitempty"`
+}
+
+type DeliveryDTO struct {
+	ID            int       `json:"id"`
+	EventType     string    `json:"event_type"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	ResponseCode  int       `json:"response_code"`
+	Error         string    `json:"error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func toWebhookDTO(row *store.WebhookRow) *WebhookDTO {
+	return &WebhookDTO{
+		ID:         row.ID,
+		URL:        row.URL,
+		EventTypes: row.EventTypes,
+		IsActive:   row.IsActive,
+		CreatedAt:  row.CreatedAt,
+	}
+}
+
+func toDeliveryDTO(row *store.WebhookDeliveryRow) *DeliveryDTO {
+	return &DeliveryDTO{
+		ID:            row.ID,
+		EventType:     row.EventType,
+		Status:        row.Status,
+		Attempts:      row.Attempts,
+		ResponseCode:  row.ResponseCode,
+		Error:         row.Error,
+		NextAttemptAt: row.NextAttemptAt,
+		CreatedAt:     row.CreatedAt,
+	}
+}
@@ -0,0 +1,24 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+type Repository interface {
+	CreateWebhook(w *store.WebhookRow) (int, error)
+	FindWebhookByID(id int) (*store.WebhookRow, error)
+	FindWebhooks() ([]*store.WebhookRow, error)
+	UpdateWebhook(w *store.WebhookRow) error
+	DeleteWebhook(id int) error
+
+	CreateWebhookDelivery(d *store.WebhookDeliveryRow) (int, error)
+	FindDueWebhookDeliveries(status string, now time.Time, limit int) ([]*store.WebhookDeliveryRow, error)
+	FindWebhookDeliveries(webhookID, offset, limit int) ([]*store.WebhookDeliveryRow, error)
+	UpdateWebhookDelivery(d *store.WebhookDeliveryRow) error
+}
+
+type UserFinder interface {
+	FindUserByID(id int) (*store.UserRow, error)
+}
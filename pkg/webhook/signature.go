@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Sign returns the signature header value of body sent at t: "t=<unix time>,v1=<hex HMAC-SHA256>".
+// The timestamp is signed with the body so receivers can reject old requests.
+func Sign(secret string, t time.Time, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), hex.EncodeToString(mac(secret, t.Unix(), body)))
+}
+
+// Verify checks a signature header value made by Sign, and that it is not older than tolerance
+func Verify(secret, signature string, body []byte, tolerance time.Duration) bool {
+	var timestamp int64
+	var sum []byte
+	for _, part := range strings.Split(signature, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return false
+		}
+
+		switch kv[0] {
+		case "t":
+			timestamp, _ = strconv.ParseInt(kv[1], 10, 64)
+		case "v1":
+			sum, _ = hex.DecodeString(kv[1])
+		}
+	}
+
+	if timestamp == 0 || time.Since(time.Unix(timestamp, 0)) > tolerance {
+		return false
+	}
+
+	return hmac.Equal(sum, mac(secret, timestamp, body))
+}
+
+func mac(secret string, timestamp int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(h, "%d.", timestamp)
+	_, _ = h.Write(body)
+
+	return h.Sum(nil)
+}
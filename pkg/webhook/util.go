@@ -0,0 +1,11 @@
+package webhook
+
+func validate(o interface{}) error {
+	if i, ok := o.(interface {
+		Valid() error
+	}); ok {
+		return i.Valid()
+	}
+
+	return nil
+}
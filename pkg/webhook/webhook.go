@@ -0,0 +1,496 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/victornm/es-backend/pkg/errorutil"
+	"github.com/victornm/es-backend/pkg/store"
+)
+
+var _ Service = (*service)(nil)
+
+type Service interface {
+	// Management is for admins only
+	Create(userID int, input *WebhookInput) (*WebhookDTO, error)
+	GetWebhooks(userID int) ([]*WebhookDTO, error)
+	Update(userID, id int, input *WebhookInput) error
+	Delete(userID, id int) error
+	GetDeliveries(userID, id, offset, limit int) ([]*DeliveryDTO, error)
+
+	// SendTest delivers a test event right away and returns the result, it is not retried
+	SendTest(userID, id int) (*DeliveryDTO, error)
+
+	// HandleEvent queues a delivery of e to every active webhook subscribed to its type,
+	// it handles every event of a bus:
+	//
+	//	bus.Handle(service.HandleEvent, nil)
+	HandleEvent(ctx context.Context, e interface{}) error
+
+	// Deliver attempts the deliveries which are due and returns the number attempted
+	Deliver() (int, error)
+}
+
+type Config struct {
+	Repository Repository
+	UserFinder UserFinder
+
+	// Topics are the event types webhooks can subscribe to, by their name such as "user.registered"
+	Topics map[string]*Topic
+
+	// Client sends the requests, a client with a 10 seconds timeout is used if nil
+	Client *http.Client
+
+	// MaxAttempts is the number of attempts before a delivery is dead
+	MaxAttempts int
+
+	// RetryDelay is the delay before the first retry, it doubles on each retry up to MaxRetryDelay
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+}
+
+const (
+	defaultMaxAttempts   = 8
+	defaultRetryDelay    = time.Minute
+	defaultMaxRetryDelay = 6 * time.Hour
+	defaultClientTimeout = 10 * time.Second
+
+	deliverBatchSize   = 100
+	deliverConcurrency = 8
+	maxResponseSize    = 64 << 10
+)
+
+type namedTopic struct {
+	*Topic
+	name string
+}
+
+type service struct {
+	repository Repository
+	userFinder UserFinder
+	topics     map[reflect.Type]*namedTopic
+	client     *http.Client
+
+	maxAttempts   int
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
+}
+
+func New(config *Config) Service {
+	s := &service{
+		repository:    config.Repository,
+		userFinder:    config.UserFinder,
+		topics:        make(map[reflect.Type]*namedTopic),
+		client:        config.Client,
+		maxAttempts:   config.MaxAttempts,
+		retryDelay:    config.RetryDelay,
+		maxRetryDelay: config.MaxRetryDelay,
+	}
+
+	for name, t := range config.Topics {
+		s.topics[reflect.TypeOf(t.Event)] = &namedTopic{Topic: t, name: name}
+	}
+
+	if s.client == nil {
+		s.client = &http.Client{Timeout: defaultClientTimeout}
+	}
+
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = defaultMaxAttempts
+	}
+
+	if s.retryDelay <= 0 {
+		s.retryDelay = defaultRetryDelay
+	}
+
+	if s.maxRetryDelay <= 0 {
+		s.maxRetryDelay = defaultMaxRetryDelay
+	}
+
+	return s
+}
+
+func (s *service) Create(userID int, input *WebhookInput) (*WebhookDTO, error) {
+	if err := s.checkAdmin(userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.validate(input); err != nil {
+		return nil, err
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	row := &store.WebhookRow{
+		UserID:     userID,
+		URL:        input.URL,
+		Secret:     secret,
+		EventTypes: input.EventTypes,
+		IsActive:   input.IsActive == nil || *input.IsActive,
+	}
+
+	id, err := s.repository.CreateWebhook(row)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+	row.ID = id
+
+	dto := toWebhookDTO(row)
+	dto.Secret = row.Secret
+
+	return dto, nil
+}
+
+func (s *service) GetWebhooks(userID int) ([]*WebhookDTO, error) {
+	if err := s.checkAdmin(userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.repository.FindWebhooks()
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	dtos := make([]*WebhookDTO, len(rows))
+	for i, row := range rows {
+		dtos[i] = toWebhookDTO(row)
+	}
+
+	return dtos, nil
+}
+
+func (s *service) Update(userID, id int, input *WebhookInput) error {
+	if err := s.checkAdmin(userID); err != nil {
+		return err
+	}
+
+	if err := s.validate(input); err != nil {
+		return err
+	}
+
+	row, err := s.repository.FindWebhookByID(id)
+	if err != nil {
+		return errorutil.Wrap(ErrWebhookNotFound, err)
+	}
+
+	row.URL = input.URL
+	row.EventTypes = input.EventTypes
+	if input.IsActive != nil {
+		row.IsActive = *input.IsActive
+	}
+
+	if err := s.repository.UpdateWebhook(row); err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return nil
+}
+
+func (s *service) Delete(userID, id int) error {
+	if err := s.checkAdmin(userID); err != nil {
+		return err
+	}
+
+	if err := s.repository.DeleteWebhook(id); err != nil {
+		return errorutil.Wrap(ErrWebhookNotFound, err)
+	}
+
+	return nil
+}
+
+func (s *service) GetDeliveries(userID, id, offset, limit int) ([]*DeliveryDTO, error) {
+	if err := s.checkAdmin(userID); err != nil {
+		return nil, err
+	}
+
+	if offset < 0 || limit <= 0 {
+		return nil, errorutil.Wrap(ErrInvalidInput, "offset = %d, limit = %d", offset, limit)
+	}
+
+	if _, err := s.repository.FindWebhookByID(id); err != nil {
+		return nil, errorutil.Wrap(ErrWebhookNotFound, err)
+	}
+
+	rows, err := s.repository.FindWebhookDeliveries(id, offset, limit)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	dtos := make([]*DeliveryDTO, len(rows))
+	for i, row := range rows {
+		dtos[i] = toDeliveryDTO(row)
+	}
+
+	return dtos, nil
+}
+
+func (s *service) SendTest(userID, id int) (*DeliveryDTO, error) {
+	if err := s.checkAdmin(userID); err != nil {
+		return nil, err
+	}
+
+	w, err := s.repository.FindWebhookByID(id)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrWebhookNotFound, err)
+	}
+
+	d := &store.WebhookDeliveryRow{
+		WebhookID:     w.ID,
+		EventType:     TestEventType,
+		Payload:       []byte(fmt.Sprintf(`{"webhook_id":%d}`, w.ID)),
+		Status:        StatusPending,
+		NextAttemptAt: time.Now(),
+	}
+
+	d.ID, err = s.repository.CreateWebhookDelivery(d)
+	if err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	code, err := s.send(w, d)
+	d.Attempts = 1
+	d.ResponseCode = code
+	d.Status = StatusSucceeded
+	if err != nil {
+		d.Error = err.Error()
+		d.Status = StatusDead
+	}
+
+	if err := s.repository.UpdateWebhookDelivery(d); err != nil {
+		return nil, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return toDeliveryDTO(d), nil
+}
+
+func (s *service) HandleEvent(ctx context.Context, e interface{}) error {
+	topic, ok := s.topics[reflect.TypeOf(e)]
+	if !ok {
+		return nil
+	}
+
+	webhooks, err := s.repository.FindWebhooks()
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	data, err := json.Marshal(topic.Payload(e))
+	if err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	now := time.Now()
+	for _, w := range webhooks {
+		if !w.IsActive || !subscribed(w, topic.name) {
+			continue
+		}
+
+		_, err := s.repository.CreateWebhookDelivery(&store.WebhookDeliveryRow{
+			WebhookID:     w.ID,
+			EventType:     topic.name,
+			Payload:       data,
+			Status:        StatusPending,
+			NextAttemptAt: now,
+		})
+		if err != nil {
+			return errorutil.Wrap(ErrUnknown, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *service) Deliver() (int, error) {
+	deliveries, err := s.repository.FindDueWebhookDeliveries(StatusPending, time.Now(), deliverBatchSize)
+	if err != nil {
+		return 0, errorutil.Wrap(ErrUnknown, err)
+	}
+
+	var mu sync.Mutex
+	var firstErr error
+
+	wg := &sync.WaitGroup{}
+	sem := make(chan struct{}, deliverConcurrency)
+	for _, d := range deliveries {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(d *store.WebhookDeliveryRow) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := s.attempt(d); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(d)
+	}
+	wg.Wait()
+
+	return len(deliveries), firstErr
+}
+
+// attempt sends d once and records the result.
+// Failed deliveries are retried with an exponential backoff until they are dead.
+func (s *service) attempt(d *store.WebhookDeliveryRow) error {
+	w, err := s.repository.FindWebhookByID(d.WebhookID)
+	if err != nil {
+		return errorutil.Wrap(ErrWebhookNotFound, err)
+	}
+
+	if !w.IsActive {
+		d.Status = StatusDead
+		d.Error = "webhook disabled"
+		if err := s.repository.UpdateWebhookDelivery(d); err != nil {
+			return errorutil.Wrap(ErrUnknown, err)
+		}
+
+		return nil
+	}
+
+	code, err := s.send(w, d)
+	d.Attempts++
+	d.ResponseCode = code
+	d.Error = ""
+
+	switch {
+	case err == nil:
+		d.Status = StatusSucceeded
+	case d.Attempts >= s.maxAttempts:
+		d.Status = StatusDead
+		d.Error = err.Error()
+	default:
+		d.Error = err.Error()
+		d.NextAttemptAt = time.Now().Add(s.backoff(d.Attempts))
+	}
+
+	if err := s.repository.UpdateWebhookDelivery(d); err != nil {
+		return errorutil.Wrap(ErrUnknown, err)
+	}
+
+	return nil
+}
+
+// backoff returns the delay after the given number of failed attempts
+func (s *service) backoff(attempts int) time.Duration {
+	delay := s.retryDelay
+	for i := 1; i < attempts && delay < s.maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > s.maxRetryDelay {
+		return s.maxRetryDelay
+	}
+
+	return delay
+}
+
+// send posts d to the webhook and returns the response code, 0 if there was no response
+func (s *service) send(w *store.WebhookRow, d *store.WebhookDeliveryRow) (int, error) {
+	body, err := json.Marshal(&Payload{
+		ID:        d.ID,
+		Type:      d.EventType,
+		CreatedAt: d.CreatedAt,
+		Data:      d.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(w.Secret, time.Now(), body))
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(d.ID))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// read some of the body so the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxResponseSize))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (s *service) validate(input *WebhookInput) error {
+	if err := validate(input); err != nil {
+		return errorutil.Wrap(ErrInvalidInput, err)
+	}
+
+	for _, t := range input.EventTypes {
+		if !s.hasTopic(t) {
+			return errorutil.Wrap(ErrInvalidInput, "unknown event type %s", t)
+		}
+	}
+
+	return nil
+}
+
+func (s *service) hasTopic(topic string) bool {
+	for _, t := range s.topics {
+		if t.name == topic {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkAdmin returns nil if the user is an admin
+func (s *service) checkAdmin(userID int) error {
+	u, err := s.userFinder.FindUserByID(userID)
+	if err != nil || !u.IsSuperAdmin {
+		return errorutil.Wrap(ErrNotAdmin, "user %d", userID)
+	}
+
+	return nil
+}
+
+func subscribed(w *store.WebhookRow, topic string) bool {
+	for _, t := range w.EventTypes {
+		if t == topic {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
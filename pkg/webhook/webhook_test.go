@@ -0,0 +1,233 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/victornm/es-backend/pkg/store"
+	"github.com/victornm/es-backend/pkg/store/memory"
+	. "github.com/victornm/es-backend/pkg/webhook"
+)
+
+type userRegistered struct {
+	UserID int
+	Email  string
+}
+
+type userFollowed struct {
+	UserID int
+}
+
+type userPayload struct {
+	UserID int `json:"user_id"`
+}
+
+// receiver records the requests posted by the service and answers with status
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	w.WriteHeader(r.status)
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.requests)
+}
+
+func newService() Service {
+	users := memory.NewUserGateway()
+	users.Seed([]*store.UserRow{
+		{Email: "admin@es.com", IsSuperAdmin: true, IsActive: true},
+		{Email: "foo@bar.com", IsActive: true},
+	})
+
+	return New(&Config{
+		Repository: memory.NewWebhookGateway(),
+		UserFinder: users,
+		Topics: map[string]*Topic{
+			"user.registered": {
+				Event: userRegistered{},
+				Payload: func(e interface{}) interface{} {
+					return userPayload{UserID: e.(userRegistered).UserID}
+				},
+			},
+			"user.followed": {
+				Event: userFollowed{},
+				Payload: func(e interface{}) interface{} {
+					return userPayload{UserID: e.(userFollowed).UserID}
+				},
+			},
+		},
+		MaxAttempts:   3,
+		RetryDelay:    time.Millisecond,
+		MaxRetryDelay: time.Millisecond,
+	})
+}
+
+func TestManageWebhooks(t *testing.T) {
+	s := newService()
+	input := &WebhookInput{URL: "https://example.com/hook", EventTypes: []string{"user.registered"}}
+
+	_, err := s.Create(2, input)
+	assertIsError(t, ErrNotAdmin, err)
+
+	_, err = s.Create(1, &WebhookInput{URL: "https://example.com/hook", EventTypes: []string{"course.completed"}})
+	assertIsError(t, ErrInvalidInput, err, "unknown event type")
+
+	_, err = s.Create(1, &WebhookInput{URL: "not a url", EventTypes: []string{"user.registered"}})
+	assertIsError(t, ErrInvalidInput, err)
+
+	created, err := s.Create(1, input)
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.Secret)
+	assert.True(t, created.IsActive)
+
+	inactive := false
+	require.NoError(t, s.Update(1, created.ID, &WebhookInput{
+		URL:        "https://example.com/other",
+		EventTypes: []string{"user.registered", "user.followed"},
+		IsActive:   &inactive,
+	}))
+	assertIsError(t, ErrWebhookNotFound, s.Update(1, 100, input))
+
+	webhooks, err := s.GetWebhooks(1)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, "https://example.com/other", webhooks[0].URL)
+	assert.False(t, webhooks[0].IsActive)
+	assert.Empty(t, webhooks[0].Secret, "secret is only returned on create")
+
+	assertIsError(t, ErrNotAdmin, s.Delete(2, created.ID))
+	require.NoError(t, s.Delete(1, created.ID))
+	assertIsError(t, ErrWebhookNotFound, s.Delete(1, created.ID))
+}
+
+func TestDeliver(t *testing.T) {
+	r := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	s := newService()
+	registered, err := s.Create(1, &WebhookInput{URL: server.URL, EventTypes: []string{"user.registered"}})
+	require.NoError(t, err)
+	_, err = s.Create(1, &WebhookInput{URL: server.URL, EventTypes: []string{"user.followed"}})
+	require.NoError(t, err)
+
+	require.NoError(t, s.HandleEvent(context.Background(), userRegistered{UserID: 7, Email: "foo@bar.com"}))
+	require.NoError(t, s.HandleEvent(context.Background(), "not a topic"))
+
+	n, err := s.Deliver()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "only the subscribed webhook should receive the event")
+	require.Equal(t, 1, r.count())
+
+	req, body := r.requests[0], r.bodies[0]
+	assert.Equal(t, "user.registered", req.Header.Get(EventHeader))
+	assert.True(t, Verify(registered.Secret, req.Header.Get(SignatureHeader), body, time.Minute))
+	assert.False(t, Verify("wrong secret", req.Header.Get(SignatureHeader), body, time.Minute))
+	assert.JSONEq(t, `{"user_id":7}`, string(mustPayload(t, body).Data), "only the payload of the topic is sent")
+
+	n, err = s.Deliver()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "succeeded deliveries should not be sent again")
+
+	deliveries, err := s.GetDeliveries(1, registered.ID, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, StatusSucceeded, deliveries[0].Status)
+	assert.Equal(t, http.StatusOK, deliveries[0].ResponseCode)
+}
+
+func TestDeliver_Retry(t *testing.T) {
+	r := &receiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	s := newService()
+	w, err := s.Create(1, &WebhookInput{URL: server.URL, EventTypes: []string{"user.registered"}})
+	require.NoError(t, err)
+	require.NoError(t, s.HandleEvent(context.Background(), userRegistered{UserID: 7}))
+
+	for i := 0; i < 10 && r.count() < 3; i++ {
+		_, err := s.Deliver()
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	deliveries, err := s.GetDeliveries(1, w.ID, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, StatusDead, deliveries[0].Status, "delivery should be dead after MaxAttempts")
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[0].ResponseCode)
+	assert.Equal(t, 3, r.count())
+
+	deliveryIDs := map[string]bool{}
+	for _, req := range r.requests {
+		deliveryIDs[req.Header.Get(DeliveryHeader)] = true
+	}
+	assert.Len(t, deliveryIDs, 1, "retries should keep the delivery ID so receivers can dedupe")
+}
+
+func TestSendTest(t *testing.T) {
+	r := &receiver{status: http.StatusNoContent}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	s := newService()
+	w, err := s.Create(1, &WebhookInput{URL: server.URL, EventTypes: []string{"user.registered"}})
+	require.NoError(t, err)
+
+	_, err = s.SendTest(2, w.ID)
+	assertIsError(t, ErrNotAdmin, err)
+
+	d, err := s.SendTest(1, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, d.Status)
+	assert.Equal(t, TestEventType, r.requests[0].Header.Get(EventHeader))
+
+	r.status = http.StatusBadRequest
+	d, err = s.SendTest(1, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusDead, d.Status, "test events are not retried")
+	assert.Equal(t, http.StatusBadRequest, d.ResponseCode)
+}
+
+func mustPayload(t *testing.T, body []byte) *Payload {
+	t.Helper()
+
+	p := &Payload{}
+	require.NoError(t, json.Unmarshal(body, p))
+	return p
+}
+
+func assertIsError(t *testing.T, wanted, got error, msgAndArgs ...interface{}) {
+	t.Helper()
+	if !errors.Is(got, wanted) {
+		t.Errorf("Error %v is not an %v %v", got, wanted, msgAndArgs)
+	}
+}